@@ -1,20 +1,43 @@
 package api
 
 import (
+	"reesource-tracker/api/backups"
+	"reesource-tracker/api/exports"
+	"reesource-tracker/api/imports"
+	"reesource-tracker/api/jobs"
 	"reesource-tracker/api/locations"
+	"reesource-tracker/api/notifications"
+	"reesource-tracker/api/oidc"
+	"reesource-tracker/api/openapi"
 	"reesource-tracker/api/products"
+	"reesource-tracker/api/reports"
+	"reesource-tracker/api/roles"
 	"reesource-tracker/api/samples"
 	"reesource-tracker/api/sync"
+	"reesource-tracker/api/tokens"
 	"reesource-tracker/api/users"
+	"reesource-tracker/api/webhooks"
+	"reesource-tracker/lib/auth"
 
 	"github.com/gin-gonic/gin"
 )
 
 func Routes(route *gin.Engine) {
-	api_routes := route.Group("/api")
+	api_routes := route.Group(openapi.PREFIX, auth.Middleware(), openapi.Validate())
 	samples.Routes(api_routes)
 	products.Routes(api_routes)
 	locations.Routes(api_routes)
 	sync.Routes(api_routes)
 	users.Routes(api_routes)
+	tokens.Routes(api_routes)
+	roles.Routes(api_routes)
+	oidc.Routes(api_routes)
+	webhooks.Routes(api_routes)
+	notifications.Routes(api_routes)
+	jobs.Routes(api_routes)
+	backups.Routes(api_routes)
+	imports.Routes(api_routes)
+	exports.Routes(api_routes)
+	reports.Routes(api_routes)
+	openapi.Routes(api_routes)
 }
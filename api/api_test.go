@@ -0,0 +1,327 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reesource-tracker/lib/testenv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// testServer serves the API routes on a fresh database and returns an admin token for it.
+func testServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	Routes(r)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	token, _ := testenv.AdminToken(t)
+	return srv, token
+}
+
+// call makes a request and decodes the JSON reply into out, if it isn't nil. body is sent as
+// a form if it is url.Values, as CSV if it is a string and as JSON otherwise.
+func call(t *testing.T, srv *httptest.Server, token string, method string, path string, body any, out any) int {
+	t.Helper()
+	var (
+		reader      io.Reader
+		contentType string
+	)
+	switch b := body.(type) {
+	case nil:
+	case url.Values:
+		reader, contentType = strings.NewReader(b.Encode()), "application/x-www-form-urlencoded"
+	case string:
+		reader, contentType = strings.NewReader(b), "text/csv"
+	default:
+		raw, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader, contentType = bytes.NewReader(raw), "application/json"
+	}
+	req, err := http.NewRequest(method, srv.URL+"/api"+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	raw, _ := io.ReadAll(res.Body)
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			t.Fatalf("%s %s: decoding %q: %v", method, path, raw, err)
+		}
+	}
+	return res.StatusCode
+}
+
+// mustCall is call that fails the test unless the reply has status want.
+func mustCall(t *testing.T, srv *httptest.Server, token string, want int, method string, path string, body any, out any) {
+	t.Helper()
+	var raw json.RawMessage
+	status := call(t, srv, token, method, path, body, &raw)
+	if status != want {
+		t.Fatalf("%s %s: got %d %s, want %d", method, path, status, raw, want)
+	}
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			t.Fatalf("%s %s: decoding %s: %v", method, path, raw, err)
+		}
+	}
+}
+
+type created struct {
+	ID string `json:"id"`
+}
+
+func TestAuthentication(t *testing.T) {
+	testenv.Engines(t, func(t *testing.T) {
+		srv, token := testServer(t)
+		if status := call(t, srv, "", http.MethodGet, "/products", nil, nil); status != http.StatusUnauthorized {
+			t.Errorf("anonymous request: got %d, want 401", status)
+		}
+		if status := call(t, srv, "rst_invalid", http.MethodGet, "/products", nil, nil); status != http.StatusUnauthorized {
+			t.Errorf("invalid token: got %d, want 401", status)
+		}
+		var permissions struct {
+			Anonymous   bool     `json:"anonymous"`
+			Permissions []string `json:"permissions"`
+		}
+		mustCall(t, srv, "", http.StatusOK, http.MethodGet, "/permissions", nil, &permissions)
+		if !permissions.Anonymous || len(permissions.Permissions) != 0 {
+			t.Errorf("anonymous permissions: got %+v, want none", permissions)
+		}
+		mustCall(t, srv, token, http.StatusOK, http.MethodGet, "/permissions", nil, &permissions)
+		if permissions.Anonymous || len(permissions.Permissions) == 0 {
+			t.Errorf("admin permissions: got %+v", permissions)
+		}
+	})
+}
+
+func TestTokens(t *testing.T) {
+	testenv.Engines(t, func(t *testing.T) {
+		srv, _ := testServer(t)
+		token, userID := testenv.AdminToken(t)
+		path := "/user/" + userID.String() + "/tokens"
+
+		var scanner created
+		mustCall(t, srv, token, http.StatusOK, http.MethodPost, path, map[string]any{"name": "Scanner", "scopes": []string{"read-only"}}, &scanner)
+		var tokens []struct {
+			ID     string   `json:"id"`
+			UserID string   `json:"user_id"`
+			Name   string   `json:"name"`
+			Scopes []string `json:"scopes"`
+		}
+		mustCall(t, srv, token, http.StatusOK, http.MethodGet, path, nil, &tokens)
+		found := false
+		for _, listed := range tokens {
+			if listed.ID == scanner.ID {
+				found = listed.UserID == userID.String() && listed.Name == "Scanner" && len(listed.Scopes) == 1
+			}
+		}
+		if !found {
+			t.Errorf("tokens: got %+v, want %s listed with string IDs", tokens, scanner.ID)
+		}
+
+		// Another user's token, a missing token and a revoked token are all not found
+		var other created
+		mustCall(t, srv, token, http.StatusOK, http.MethodPost, "/user", map[string]string{"name": "Grace", "email": "grace@example.com"}, &other)
+		mustCall(t, srv, token, http.StatusNotFound, http.MethodDelete, "/user/"+other.ID+"/tokens/"+scanner.ID, nil, nil)
+		mustCall(t, srv, token, http.StatusNotFound, http.MethodDelete, path+"/"+other.ID, nil, nil)
+		mustCall(t, srv, token, http.StatusOK, http.MethodDelete, path+"/"+scanner.ID, nil, nil)
+		mustCall(t, srv, token, http.StatusNotFound, http.MethodDelete, path+"/"+scanner.ID, nil, nil)
+	})
+}
+
+func TestInventory(t *testing.T) {
+	testenv.Engines(t, func(t *testing.T) {
+		srv, token := testServer(t)
+
+		var lab, shelf, family, model, user created
+		mustCall(t, srv, token, http.StatusOK, http.MethodPost, "/location", map[string]string{"name": "Lab", "description": "Ground floor"}, &lab)
+		mustCall(t, srv, token, http.StatusOK, http.MethodPost, "/location", map[string]string{"name": "Shelf"}, &shelf)
+		mustCall(t, srv, token, http.StatusOK, http.MethodPost, "/location/"+shelf.ID, map[string]string{"name": "Shelf 1", "parent_location_id": lab.ID}, nil)
+		mustCall(t, srv, token, http.StatusOK, http.MethodPost, "/product", map[string]string{"name": "Boards"}, &family)
+		mustCall(t, srv, token, http.StatusOK, http.MethodPost, "/product", map[string]string{"name": "Board v2", "parent_product_id": family.ID}, &model)
+		mustCall(t, srv, token, http.StatusOK, http.MethodPost, "/user", map[string]string{"name": "Grace", "email": "grace@example.com"}, &user)
+		mustCall(t, srv, token, http.StatusBadRequest, http.MethodPost, "/user", map[string]string{"name": "Bad", "email": "not an email"}, nil)
+
+		var location struct{ Name string }
+		mustCall(t, srv, token, http.StatusOK, http.MethodGet, "/location/"+shelf.ID, nil, &location)
+		if location.Name != "Shelf 1" {
+			t.Errorf("location name: got %q, want Shelf 1", location.Name)
+		}
+
+		var generated struct {
+			SampleIDs []string `json:"sample_ids"`
+		}
+		mustCall(t, srv, token, http.StatusOK, http.MethodGet, "/generate_samples?num_samples=2", nil, &generated)
+		if len(generated.SampleIDs) != 2 {
+			t.Fatalf("generated %v, want 2 IDs", generated.SampleIDs)
+		}
+		sampleID := generated.SampleIDs[0]
+		form := url.Values{
+			"location_id": {shelf.ID},
+			"product_id":  {model.ID},
+			"owner_id":    {user.ID},
+			"state":       {"in_use"},
+		}
+		mustCall(t, srv, token, http.StatusOK, http.MethodPost, "/sample/"+sampleID, form, nil)
+
+		var samples []struct{ State string }
+		mustCall(t, srv, token, http.StatusOK, http.MethodGet, "/samples?product=any-"+family.ID+"&location=any-"+lab.ID, nil, &samples)
+		if len(samples) != 1 || samples[0].State != "in_use" {
+			t.Errorf("samples below Boards in Lab: got %+v, want the in_use sample", samples)
+		}
+		mustCall(t, srv, token, http.StatusOK, http.MethodGet, "/samples?state=unassigned", nil, &samples)
+		if len(samples) != 1 {
+			t.Errorf("unassigned samples: got %d, want 1", len(samples))
+		}
+
+		mustCall(t, srv, token, http.StatusOK, http.MethodPost, "/sample/"+sampleID+"/mods/", map[string]string{"name": "Bodge wire"}, nil)
+		mustCall(t, srv, token, http.StatusOK, http.MethodPost, "/sample/"+sampleID+"/comments/", map[string]string{"comment": "Works"}, nil)
+		var mods struct {
+			Mods []struct{ ID []byte }
+		}
+		mustCall(t, srv, token, http.StatusOK, http.MethodGet, "/sample/"+sampleID+"/mods/", nil, &mods)
+		if len(mods.Mods) != 1 {
+			t.Fatalf("mods: got %d, want 1", len(mods.Mods))
+		}
+		modID, err := uuid.FromBytes(mods.Mods[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A mod can only be removed through the sample it belongs to
+		mustCall(t, srv, token, http.StatusBadRequest, http.MethodDelete, "/sample/not-a-sample/mods/"+modID.String(), nil, nil)
+		mustCall(t, srv, token, http.StatusNotFound, http.MethodDelete, "/sample/"+generated.SampleIDs[1]+"/mods/"+modID.String(), nil, nil)
+		mustCall(t, srv, token, http.StatusOK, http.MethodDelete, "/sample/"+sampleID+"/mods/"+modID.String(), nil, nil)
+
+		mustCall(t, srv, token, http.StatusOK, http.MethodPost, "/sample/"+sampleID+"/notes/", map[string]string{"contents": "Reflowed U3"}, nil)
+		var notes struct {
+			Notes []struct{ AuthorID []byte }
+		}
+		mustCall(t, srv, token, http.StatusOK, http.MethodGet, "/sample/"+sampleID+"/notes/", nil, &notes)
+		if len(notes.Notes) != 1 || len(notes.Notes[0].AuthorID) != 16 {
+			t.Errorf("notes: got %+v, want one with its author", notes.Notes)
+		}
+
+		due := time.Now().AddDate(0, 0, 7).Format(time.DateOnly)
+		mustCall(t, srv, token, http.StatusOK, http.MethodPost, "/sample/"+sampleID+"/loan", map[string]string{"user_id": user.ID, "due_at": due}, nil)
+		var loans []json.RawMessage
+		mustCall(t, srv, token, http.StatusOK, http.MethodGet, "/loans", nil, &loans)
+		if len(loans) != 1 {
+			t.Errorf("loans: got %d, want 1", len(loans))
+		}
+		mustCall(t, srv, token, http.StatusOK, http.MethodDelete, "/sample/"+sampleID+"/loan", nil, nil)
+
+		var states []struct {
+			State string `json:"state"`
+			Count int    `json:"count"`
+		}
+		mustCall(t, srv, token, http.StatusOK, http.MethodGet, "/reports/states", nil, &states)
+		counts := map[string]int{}
+		for _, s := range states {
+			counts[s.State] = s.Count
+		}
+		if counts["in_use"] != 1 || counts["unassigned"] != 1 {
+			t.Errorf("state report: got %v", counts)
+		}
+
+		// Products and locations in use can't be deleted
+		if status := call(t, srv, token, http.MethodDelete, "/product/"+model.ID, nil, nil); status == http.StatusOK {
+			t.Error("deleted a product a sample refers to")
+		}
+		var spare created
+		mustCall(t, srv, token, http.StatusOK, http.MethodPost, "/location", map[string]string{"name": "Spare"}, &spare)
+		mustCall(t, srv, token, http.StatusOK, http.MethodDelete, "/location/"+spare.ID, nil, nil)
+		mustCall(t, srv, token, http.StatusNotFound, http.MethodGet, "/location/"+spare.ID, nil, nil)
+	})
+}
+
+func TestGenerateSamples(t *testing.T) {
+	testenv.Engines(t, func(t *testing.T) {
+		srv, _ := testServer(t)
+		token, userID := testenv.AdminToken(t)
+
+		var generated struct {
+			SampleIDs []string `json:"sample_ids"`
+		}
+		mustCall(t, srv, token, http.StatusOK, http.MethodGet, "/generate_samples?num_samples=3", nil, &generated)
+		var entries []struct {
+			Action  string `json:"action"`
+			Details struct {
+				SampleIDs []string `json:"sample_ids"`
+			} `json:"details"`
+		}
+		mustCall(t, srv, token, http.StatusOK, http.MethodGet, "/user/"+userID.String()+"/activity", nil, &entries)
+		if len(entries) != 1 || entries[0].Action != "generated" || len(entries[0].Details.SampleIDs) != 3 {
+			t.Errorf("activity: got %+v, want one entry for the three samples", entries)
+		}
+	})
+}
+
+func TestImportExport(t *testing.T) {
+	testenv.Engines(t, func(t *testing.T) {
+		srv, _ := testServer(t)
+		// The importing user's own token, to find the import in their activity
+		token, userID := testenv.AdminToken(t)
+
+		var result struct {
+			Created int `json:"created"`
+		}
+		csv := "name,parent,part_number\nBoards,,\nBoard v2,Boards,BV2\n"
+		mustCall(t, srv, token, http.StatusOK, http.MethodPost, "/import/products", csv, &result)
+		if result.Created != 2 {
+			t.Errorf("imported products: created %d, want 2", result.Created)
+		}
+		var invalid struct {
+			Result struct {
+				Errors []struct {
+					Line int `json:"line"`
+				} `json:"errors"`
+			} `json:"result"`
+		}
+		mustCall(t, srv, token, http.StatusUnprocessableEntity, http.MethodPost, "/import/products", "name,parent\nX,Missing\n", &invalid)
+		if errs := invalid.Result.Errors; len(errs) != 1 || errs[0].Line != 2 {
+			t.Errorf("invalid import: got errors %+v, want one on line 2", errs)
+		}
+		var entries []struct {
+			EntityKind string `json:"entity_kind"`
+			Action     string `json:"action"`
+		}
+		mustCall(t, srv, token, http.StatusOK, http.MethodGet, "/user/"+userID.String()+"/activity", nil, &entries)
+		if len(entries) != 2 || entries[0].EntityKind != "product" || entries[0].Action != "created" {
+			t.Errorf("activity: got %+v, want the two imported products", entries)
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/export/products", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		if res.StatusCode != http.StatusOK || !strings.Contains(string(body), "Boards / Board v2") {
+			t.Errorf("export: got %d %q", res.StatusCode, body)
+		}
+	})
+}
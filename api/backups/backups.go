@@ -0,0 +1,93 @@
+package backups
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/backup"
+	"reesource-tracker/lib/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Backups are restored with the restore command while the server is stopped, so there is
+// no endpoint for it.
+func Routes(route *gin.RouterGroup) {
+	manage := auth.Require(auth.PermBackupsManage)
+	route.GET("/backups", manage, listBackups)
+	route.POST("/backups", manage, createBackup)
+	route.GET("/backup/:backup_name", manage, downloadBackup)
+	route.POST("/backup/:backup_name/verify", manage, verifyBackup)
+}
+
+func listBackups(c *gin.Context) {
+	backups, err := backup.All()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, backups)
+}
+
+// POST /backups
+// Takes a backup now and removes the oldest ones beyond BACKUP_KEEP.
+func createBackup(c *gin.Context) {
+	path, err := backup.Create(c)
+	if errors.Is(err, backup.ErrUnsupported) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	created, err := backup.Get(filepath.Base(path))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	removed, err := backup.Prune(config.Current.BackupKeep)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	names := []string{}
+	for _, path := range removed {
+		names = append(names, filepath.Base(path))
+	}
+	c.JSON(http.StatusCreated, gin.H{"backup": created, "removed": names})
+}
+
+func downloadBackup(c *gin.Context) {
+	found, err := backup.Get(c.Param("backup_name"))
+	if errors.Is(err, backup.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.FileAttachment(found.Path, found.Name)
+}
+
+// POST /backup/:backup_name/verify
+// Runs the checks a restore would, without restoring.
+func verifyBackup(c *gin.Context) {
+	found, err := backup.Get(c.Param("backup_name"))
+	if errors.Is(err, backup.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	report, err := backup.Verify(c, found.Path)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "report": report})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"backup": found, "report": report})
+}
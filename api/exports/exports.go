@@ -0,0 +1,55 @@
+package exports
+
+import (
+	"log/slog"
+	"net/http"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/exporter"
+	samplefilter "reesource-tracker/lib/sample_filter"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Routes(route *gin.RouterGroup) {
+	route.GET("/export/samples", auth.Require(auth.PermSamplesRead), exportTable(exporter.KindSamples))
+	route.GET("/export/products", auth.Require(auth.PermProductsRead), exportTable(exporter.KindProducts))
+	route.GET("/export/locations", auth.Require(auth.PermLocationsRead), exportTable(exporter.KindLocations))
+	route.GET("/export/users", auth.Require(auth.PermUsersRead), exportTable(exporter.KindUsers))
+}
+
+// GET /export/:kind?format=xlsx
+// Downloads a table as CSV (the default) or XLSX. Samples take the same filters as
+// GET /samples. The file is streamed, so an error once it has started can only be logged.
+func exportTable(kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", exporter.FormatCSV)
+		if _, ok := exporter.ContentTypes[format]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": exporter.ErrUnknownFormat.Error()})
+			return
+		}
+		var matcher *samplefilter.Matcher
+		if kind == exporter.KindSamples {
+			var filter samplefilter.Filter
+			if err := c.ShouldBindQuery(&filter); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var err error
+			if matcher, err = filter.Matcher(c); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		c.Header("Content-Type", exporter.ContentTypes[format])
+		c.Header("Content-Disposition", `attachment; filename="`+exporter.Filename(kind, format, time.Now())+`"`)
+		c.Status(http.StatusOK)
+		if err := exporter.Export(c, kind, format, c.Writer, matcher); err != nil {
+			slog.ErrorContext(c, "Export failed", "kind", kind, "format", format, "error", err)
+			if !c.Writer.Written() {
+				c.Header("Content-Disposition", "")
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package imports
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reesource-tracker/api/sync"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/database"
+	"reesource-tracker/lib/importer"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MAX_IMPORT_SIZE is the largest file that can be imported, in bytes.
+const MAX_IMPORT_SIZE = 32 << 20
+
+func Routes(route *gin.RouterGroup) {
+	// The permissions depend on the kind and options, so they are checked in the handler
+	route.POST("/import/:kind", importFile)
+}
+
+// permissions lists what an import needs. Creating missing products and locations from
+// sample rows also needs permission to write those.
+func permissions(kind string, createMissing bool) []string {
+	switch kind {
+	case importer.KindSamples:
+		if createMissing {
+			return []string{auth.PermSamplesWrite, auth.PermProductsWrite, auth.PermLocationsWrite}
+		}
+		return []string{auth.PermSamplesWrite}
+	case importer.KindProducts:
+		return []string{auth.PermProductsWrite}
+	case importer.KindLocations:
+		return []string{auth.PermLocationsWrite}
+	case importer.KindUsers:
+		return []string{auth.PermUsersWrite}
+	}
+	return nil
+}
+
+// POST /import/:kind?dry_run=true&create_missing=true&mapping={"id":"Sample ID"}
+// Takes the CSV file as the request body, or as the "file" field of a multipart form
+// whose other fields can hold the options instead of the query string.
+func importFile(c *gin.Context) {
+	kind := c.Param("kind")
+	if _, ok := importer.Fields[kind]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown import kind, expected samples, products, locations or users"})
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MAX_IMPORT_SIZE)
+	multipart := strings.HasPrefix(c.ContentType(), "multipart/form-data")
+	option := func(name string) string {
+		if multipart {
+			if value, ok := c.GetPostForm(name); ok {
+				return value
+			}
+		}
+		return c.Query(name)
+	}
+
+	opts := importer.Options{
+		DryRun:        option("dry_run") == "true",
+		CreateMissing: option("create_missing") == "true",
+		Record: func(q *database.Queries, change importer.Change) error {
+			var details interface{}
+			if change.Changes != nil {
+				details = change.Changes
+			}
+			return activity.RecordWith(c, q, change.Kind, change.ID, change.Action, details)
+		},
+	}
+	p, ok := auth.CurrentPrincipal(c)
+	for _, perm := range permissions(kind, opts.CreateMissing) {
+		if !ok || !p.Can(perm) {
+			auth.Forbidden(c, perm)
+			return
+		}
+	}
+	if mapping := option("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &opts.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mapping must be a JSON object of field names to column headers"})
+			return
+		}
+	}
+
+	var file io.Reader = c.Request.Body
+	if multipart {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		opened, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer opened.Close()
+		file = opened
+	}
+
+	result, err := importer.Import(c, kind, file, opts)
+	switch {
+	case errors.Is(err, importer.ErrInvalidRows):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "result": result})
+		return
+	case errors.Is(err, importer.ErrInvalidFile):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !result.DryRun && len(result.Changes) > 0 {
+		sync.BroadcastImport(c, kind, result.Changes)
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"errors"
+	"net/http"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/database"
+	"reesource-tracker/lib/scheduler"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Routes(route *gin.RouterGroup) {
+	manage := auth.Require(auth.PermJobsManage)
+	route.GET("/jobs", manage, listJobs)
+	route.GET("/job/:job_name", manage, getJob)
+	route.POST("/job/:job_name", manage, updateJob)
+	route.POST("/job/:job_name/run", manage, runJob)
+	route.GET("/job/:job_name/runs", manage, listRuns)
+}
+
+// Run is the API representation of one run of a job.
+type Run struct {
+	ID         int64      `json:"id"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Status     string     `json:"status"`
+	Output     string     `json:"output"`
+}
+
+func listJobs(c *gin.Context) {
+	statuses, err := scheduler.List(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, statuses)
+}
+
+func getJob(c *gin.Context) {
+	status, err := scheduler.Get(c, c.Param("job_name"))
+	if errors.Is(err, scheduler.ErrUnknownJob) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// POST /job/:job_name
+// Changes the schedule or pauses the job. Omitted fields are left as they are.
+func updateJob(c *gin.Context) {
+	var req struct {
+		Schedule *string `json:"schedule"`
+		Enabled  *bool   `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := c.Param("job_name")
+	status, err := scheduler.Get(c, name)
+	if errors.Is(err, scheduler.ErrUnknownJob) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Schedule != nil {
+		if _, err := scheduler.ParseSchedule(*req.Schedule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		status.Schedule = *req.Schedule
+	}
+	if req.Enabled != nil {
+		status.Enabled = *req.Enabled
+	}
+	if err := scheduler.Update(c, name, status.Schedule, status.Enabled); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	status, err = scheduler.Get(c, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// POST /job/:job_name/run
+// Starts the job now; its progress can be followed in the run history.
+func runJob(c *gin.Context) {
+	err := scheduler.RunNow(c.Param("job_name"))
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownJob):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+	case errors.Is(err, scheduler.ErrAlreadyRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": "Job is already running"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusAccepted, gin.H{"status": "started"})
+	}
+}
+
+// GET /job/:job_name/runs?limit=50
+func listRuns(c *gin.Context) {
+	name := c.Param("job_name")
+	if _, err := scheduler.Get(c, name); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	limit := int64(50)
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = min(parsed, 500)
+	}
+	rows, err := database.Connection.ListJobRuns(c, database.ListJobRunsParams{JobName: name, Limit: limit})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	runs := make([]Run, 0, len(rows))
+	for _, row := range rows {
+		run := Run{ID: row.ID, StartedAt: row.StartedAt, Status: row.Status, Output: row.Output}
+		if row.FinishedAt.Valid {
+			run.FinishedAt = &row.FinishedAt.Time
+		}
+		runs = append(runs, run)
+	}
+	c.JSON(http.StatusOK, runs)
+}
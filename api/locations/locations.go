@@ -2,6 +2,8 @@ package locations
 
 import (
 	"reesource-tracker/api/sync"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/auth"
 	"reesource-tracker/lib/database"
 	id_helper "reesource-tracker/lib/id_helper"
 	"database/sql"
@@ -12,11 +14,11 @@ import (
 )
 
 func Routes(route *gin.RouterGroup) {
-	route.GET("/locations", getLocations)
-	route.POST("/location", createLocation)
-	route.GET("/location/:location_id", getLocation)
-	route.POST("/location/:location_id", updateLocation)
-	route.DELETE("/location/:location_id", deleteLocation)
+	route.GET("/locations", auth.Require(auth.PermLocationsRead), getLocations)
+	route.POST("/location", auth.Require(auth.PermLocationsWrite), createLocation)
+	route.GET("/location/:location_id", auth.Require(auth.PermLocationsRead), getLocation)
+	route.POST("/location/:location_id", auth.Require(auth.PermLocationsWrite), updateLocation)
+	route.DELETE("/location/:location_id", auth.Require(auth.PermLocationsDelete), deleteLocation)
 }
 
 // DELETE /location/:location_id
@@ -36,8 +38,9 @@ func deleteLocation(c *gin.Context) {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	activity.Record(c, activity.KindLocation, locationID, activity.ActionDeleted, nil)
 	c.JSON(200, gin.H{"status": "deleted"})
-	sync.BroadcastEvent("locations_updated", gin.H{})
+	broadcastLocation(c, binary_uuid, sync.OpDeleted)
 }
 
 func createLocation(c *gin.Context) {
@@ -64,8 +67,9 @@ func createLocation(c *gin.Context) {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(200, gin.H{"status": "success"})
-	sync.BroadcastEvent("locations_updated", gin.H{})
+	activity.Record(c, activity.KindLocation, id_helper.BlobToString(new_uid), activity.ActionCreated, req)
+	c.JSON(200, gin.H{"status": "success", "id": id_helper.BlobToString(new_uid)})
+	broadcastLocation(c, new_uid, sync.OpCreated)
 }
 
 func getLocations(c *gin.Context) {
@@ -83,7 +87,12 @@ func getLocation(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "id required"})
 		return
 	}
-	location, err := database.Connection.GetLocation(c, locationID)
+	binary_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(locationID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	location, err := database.Connection.GetLocation(c, binary_uuid)
 	if err != nil {
 		c.JSON(404, gin.H{"error": err.Error()})
 		return
@@ -133,6 +142,20 @@ func updateLocation(c *gin.Context) {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	activity.Record(c, activity.KindLocation, locationID, activity.ActionUpdated, req)
 	c.JSON(200, gin.H{"status": "success"})
-	sync.BroadcastEvent("locations_updated", gin.H{})
+	broadcastLocation(c, binary_uuid, sync.OpUpdated)
+}
+
+// broadcastLocation sends the current state of a location to sync clients.
+func broadcastLocation(c *gin.Context, id []byte, op string) {
+	var data interface{}
+	topics := []string{sync.TopicLocation + id_helper.BlobToString(id)}
+	if op != sync.OpDeleted {
+		if location, err := database.Connection.GetLocation(c, id); err == nil {
+			data = location
+			topics = sync.LocationTopics(c, id)
+		}
+	}
+	sync.BroadcastChange(c, activity.KindLocation, id_helper.BlobToString(id), op, data, nil, topics...)
 }
@@ -0,0 +1,80 @@
+package notifications
+
+import (
+	"net/http"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/auth"
+	id_helper "reesource-tracker/lib/id_helper"
+	"reesource-tracker/lib/notifications"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Routes(route *gin.RouterGroup) {
+	manage := auth.RequireSelfOr(auth.PermNotificationsManageSelf, auth.PermUsersWrite)
+	route.GET("/user/:user_id/notifications", manage, getPreferences)
+	route.POST("/user/:user_id/notifications", manage, updatePreferences)
+}
+
+// GET /user/:user_id/notifications
+func getPreferences(c *gin.Context) {
+	binary_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(c.Param("user_id"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	prefs, err := notifications.LoadPreferences(c, binary_uuid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// POST /user/:user_id/notifications
+// Fields left out of the request keep their current values. An empty email turns emails off.
+func updatePreferences(c *gin.Context) {
+	userID := c.Param("user_id")
+	binary_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(userID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	var req struct {
+		Email  *string         `json:"email"`
+		Digest string          `json:"digest"`
+		Kinds  map[string]bool `json:"kinds"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	prefs, err := notifications.LoadPreferences(c, binary_uuid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if req.Digest != "" {
+		prefs.Digest = req.Digest
+	}
+	for kind, enabled := range req.Kinds {
+		prefs.Kinds[kind] = enabled
+	}
+	if err := prefs.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Email != nil {
+		if err := notifications.SetEmail(c, binary_uuid, *req.Email); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := notifications.SavePreferences(c, binary_uuid, prefs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	activity.Record(c, activity.KindUser, userID, activity.ActionUpdated, req)
+	prefs, _ = notifications.LoadPreferences(c, binary_uuid)
+	c.JSON(http.StatusOK, prefs)
+}
@@ -0,0 +1,79 @@
+package oidc
+
+import (
+	"net/http"
+	"reesource-tracker/lib/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Routes(route *gin.RouterGroup) {
+	route.GET("/auth/login", login)
+	route.GET("/auth/callback", callback)
+	route.POST("/auth/logout", logout)
+}
+
+// GET /auth/login?redirect=/app/...
+func login(c *gin.Context) {
+	client := auth.OIDC()
+	if client == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
+	}
+	flow, url, err := client.NewLoginFlow(auth.SafeRedirect(c.Query("redirect")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := auth.SaveLoginFlow(c, flow); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Redirect(http.StatusFound, url)
+}
+
+// GET /auth/callback
+func callback(c *gin.Context) {
+	client := auth.OIDC()
+	if client == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
+	}
+	if errMsg := c.Query("error"); errMsg != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errMsg, "description": c.Query("error_description")})
+		return
+	}
+	flow, err := auth.TakeLoginFlow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Query("state") != flow.State {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Login state does not match"})
+		return
+	}
+	identity, err := client.Exchange(c, c.Query("code"), flow)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	userID, err := client.Provision(c, identity)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := auth.StartSession(c, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Redirect(http.StatusFound, flow.Redirect)
+}
+
+// POST /auth/logout
+func logout(c *gin.Context) {
+	if err := auth.EndSession(c); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "logged out"})
+}
@@ -0,0 +1,273 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reesource-tracker/api"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/config"
+	"reesource-tracker/lib/database"
+	"reesource-tracker/lib/testenv"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	clientID     = "tracker"
+	clientSecret = "tracker-secret"
+	redirectURL  = "http://tracker.test/api/auth/callback"
+)
+
+// issuer is a minimal OpenID provider. Codes are handed out by authorize, which the test
+// calls in place of the user's browser, and the token endpoint checks their PKCE verifier.
+type issuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]grant
+}
+
+type grant struct {
+	challenge string
+	nonce     string
+}
+
+func newIssuer(t *testing.T) *issuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss := &issuer{key: key, codes: map[string]grant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                                iss.URL,
+			"authorization_endpoint":                iss.URL + "/authorize",
+			"token_endpoint":                        iss.URL + "/token",
+			"jwks_uri":                              iss.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", iss.token)
+	iss.Server = httptest.NewServer(mux)
+	t.Cleanup(iss.Close)
+	return iss
+}
+
+// authorize grants a code for the authorization URL the tracker redirected to.
+func (iss *issuer) authorize(t *testing.T, authURL *url.URL) string {
+	t.Helper()
+	query := authURL.Query()
+	if query.Get("client_id") != clientID || query.Get("redirect_uri") != redirectURL || query.Get("response_type") != "code" {
+		t.Fatalf("authorization request: got %v", query)
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("authorization request without a PKCE challenge: %v", query)
+	}
+	if !slices.Contains(strings.Fields(query.Get("scope")), "openid") {
+		t.Fatalf("authorization request without the openid scope: %v", query)
+	}
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	code := "code-" + query.Get("state")
+	iss.codes[code] = grant{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	return code
+}
+
+func (iss *issuer) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	iss.mu.Lock()
+	grant, found := iss.codes[r.PostForm.Get("code")]
+	delete(iss.codes, r.PostForm.Get("code"))
+	iss.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case id != clientID || secret != clientSecret:
+		w.WriteHeader(http.StatusUnauthorized)
+		writeJSON(w, map[string]string{"error": "invalid_client"})
+		return
+	case !found || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge:
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	now := time.Now()
+	writeJSON(w, map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token": iss.sign(map[string]any{
+			"iss":            iss.URL,
+			"sub":            "grace",
+			"aud":            clientID,
+			"iat":            now.Unix(),
+			"exp":            now.Add(time.Hour).Unix(),
+			"nonce":          grant.nonce,
+			"name":           "Grace Hopper",
+			"email":          "grace@example.com",
+			"email_verified": true,
+			"groups":         []string{"lab-admins"},
+		}),
+	})
+}
+
+// sign encodes claims as an RS256 JWT.
+func (iss *issuer) sign(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, iss.key, crypto.SHA256, digest[:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+// serve sends a request to the router with the given cookies.
+func serve(r *gin.Engine, target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func cookie(t *testing.T, w *httptest.ResponseRecorder, name string) *http.Cookie {
+	t.Helper()
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name && c.Value != "" {
+			return c
+		}
+	}
+	t.Fatalf("no %s cookie in %v", name, w.Result().Header["Set-Cookie"])
+	return nil
+}
+
+// login starts a login and returns the authorization URL and the login flow cookie.
+func login(t *testing.T, r *gin.Engine) (*url.URL, *http.Cookie) {
+	t.Helper()
+	w := serve(r, "/api/auth/login?redirect=/app/samples")
+	if w.Code != http.StatusFound {
+		t.Fatalf("login: got %d %s", w.Code, w.Body)
+	}
+	authURL, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authURL, cookie(t, w, auth.LOGIN_FLOW_COOKIE)
+}
+
+func setup(t *testing.T) (*issuer, *gin.Engine) {
+	t.Helper()
+	testenv.Open(t, config.DRIVER_SQLITE)
+	iss := newIssuer(t)
+	cfg := config.Current
+	cfg.OIDCIssuer = iss.URL
+	cfg.OIDCClientID = clientID
+	cfg.OIDCClientSecret = clientSecret
+	cfg.OIDCRedirectURL = redirectURL
+	cfg.OIDCRoleMapping = "lab-admins=admin"
+	if err := auth.SetupOIDC(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api.Routes(r)
+	return iss, r
+}
+
+func TestLogin(t *testing.T) {
+	iss, r := setup(t)
+	authURL, flow := login(t, r)
+	if !strings.HasPrefix(authURL.String(), iss.URL+"/authorize?") {
+		t.Fatalf("login redirected to %s", authURL)
+	}
+	code := iss.authorize(t, authURL)
+
+	w := serve(r, "/api/auth/callback?code="+code+"&state="+authURL.Query().Get("state"), flow)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/app/samples" {
+		t.Fatalf("callback: got %d to %q %s", w.Code, w.Header().Get("Location"), w.Body)
+	}
+	session := cookie(t, w, auth.SESSION_COOKIE)
+	if !session.HttpOnly {
+		t.Error("session cookie is readable from scripts")
+	}
+
+	w = serve(r, "/api/permissions", session)
+	var permissions struct {
+		Anonymous bool     `json:"anonymous"`
+		Roles     []string `json:"roles"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &permissions); err != nil {
+		t.Fatal(err)
+	}
+	if permissions.Anonymous || !slices.Contains(permissions.Roles, auth.RoleAdmin) {
+		t.Errorf("permissions with the session: got %+v, want the mapped admin role", permissions)
+	}
+	users, err := database.Connection.GetUsers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Name != "Grace Hopper" || users[0].Email.String != "grace@example.com" {
+		t.Errorf("provisioned users: got %+v", users)
+	}
+}
+
+func TestCallbackRejected(t *testing.T) {
+	iss, r := setup(t)
+	first, firstFlow := login(t, r)
+	second, secondFlow := login(t, r)
+	code := iss.authorize(t, first)
+
+	if w := serve(r, "/api/auth/callback?code="+code+"&state="+second.Query().Get("state"), firstFlow); w.Code != http.StatusBadRequest {
+		t.Errorf("state of another login: got %d, want 400", w.Code)
+	}
+	if w := serve(r, "/api/auth/callback?code="+code+"&state="+first.Query().Get("state")); w.Code != http.StatusBadRequest {
+		t.Errorf("without the login flow cookie: got %d, want 400", w.Code)
+	}
+	// The code was issued for the first login's challenge, so the second's verifier fails
+	w := serve(r, "/api/auth/callback?code="+code+"&state="+second.Query().Get("state"), secondFlow)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("verifier of another login: got %d, want 401", w.Code)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == auth.SESSION_COOKIE && c.Value != "" {
+			t.Error("failed login started a session")
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Package openapi serves the OpenAPI document describing the API, and validates requests
+// against it.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PREFIX is where the API is mounted, the server URL of the document.
+const PREFIX = "/api"
+
+//go:embed openapi.json
+var raw []byte
+
+// spec is the parsed document. It is embedded in the binary, so failing to parse it is a bug.
+var spec = mustLoad(raw)
+
+// document is the part of the OpenAPI document needed to validate requests.
+type document struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas    map[string]*schema    `json:"schemas"`
+		Parameters map[string]*parameter `json:"parameters"`
+	} `json:"components"`
+	// operations are keyed by method and gin route, e.g. "GET /sample/:sample_id"
+	operations map[string]*operation
+}
+
+type operation struct {
+	Parameters  []*parameter `json:"parameters"`
+	RequestBody *struct {
+		Required bool `json:"required"`
+		Content  map[string]struct {
+			Schema *schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+type parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+}
+
+func Routes(route *gin.RouterGroup) {
+	route.GET("/openapi.json", getDocument)
+}
+
+// GET /openapi.json
+func getDocument(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", raw)
+}
+
+// Missing lists the routes under PREFIX that the document doesn't describe, as "METHOD path".
+func Missing(routes gin.RoutesInfo) []string {
+	missing := []string{}
+	for _, route := range routes {
+		path, ok := strings.CutPrefix(route.Path, PREFIX+"/")
+		if !ok {
+			continue
+		}
+		if spec.operations[route.Method+" /"+path] == nil {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+	return missing
+}
+
+func mustLoad(raw []byte) *document {
+	doc := &document{operations: map[string]*operation{}}
+	if err := json.Unmarshal(raw, doc); err != nil {
+		panic(fmt.Errorf("openapi.json: %w", err))
+	}
+	for name, s := range doc.Components.Schemas {
+		if err := doc.resolve(s); err != nil {
+			panic(fmt.Errorf("openapi.json: schema %s: %w", name, err))
+		}
+	}
+	for path, methods := range doc.Paths {
+		for method, op := range methods {
+			for i, param := range op.Parameters {
+				if param.Ref != "" {
+					name, _ := strings.CutPrefix(param.Ref, "#/components/parameters/")
+					if op.Parameters[i] = doc.Components.Parameters[name]; op.Parameters[i] == nil {
+						panic(fmt.Errorf("openapi.json: %s %s: unknown parameter %s", method, path, param.Ref))
+					}
+				}
+				if err := doc.resolve(op.Parameters[i].Schema); err != nil {
+					panic(fmt.Errorf("openapi.json: %s %s: %s: %w", method, path, op.Parameters[i].Name, err))
+				}
+			}
+			if op.RequestBody != nil {
+				for mediaType, content := range op.RequestBody.Content {
+					if err := doc.resolve(content.Schema); err != nil {
+						panic(fmt.Errorf("openapi.json: %s %s: %s: %w", method, path, mediaType, err))
+					}
+				}
+			}
+			doc.operations[strings.ToUpper(method)+" "+ginPath(path)] = op
+		}
+	}
+	return doc
+}
+
+var templateParam = regexp.MustCompile(`\{([^}/]+)\}`)
+
+// ginPath turns a path template such as /sample/{sample_id} into the gin route /sample/:sample_id.
+func ginPath(path string) string {
+	return templateParam.ReplaceAllString(path, ":$1")
+}
@@ -0,0 +1,74 @@
+package openapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reesource-tracker/api"
+	"reesource-tracker/api/openapi"
+	"reesource-tracker/lib/config"
+	"reesource-tracker/lib/testenv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEveryRouteDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api.Routes(r)
+	if missing := openapi.Missing(r.Routes()); len(missing) > 0 {
+		t.Errorf("routes missing from openapi.json: %s", strings.Join(missing, ", "))
+	}
+}
+
+// Requests are validated once the caller is authenticated, before their permission check.
+func TestValidation(t *testing.T) {
+	testenv.Open(t, config.DRIVER_SQLITE)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api.Routes(r)
+	token, userID := testenv.AdminToken(t)
+
+	tests := []struct {
+		name          string
+		anonymousRole string
+		token         string
+		method        string
+		path          string
+		body          string
+		want          int
+	}{
+		{"anonymous", "none", "", http.MethodPost, "/api/product", `{"name": "Widget"}`, http.StatusUnauthorized},
+		{"without permission", "viewer", "", http.MethodPost, "/api/product", `{"name": "Widget"}`, http.StatusForbidden},
+		{"invalid, anonymous", "none", "", http.MethodPost, "/api/product", `{"name": 5}`, http.StatusBadRequest},
+		{"invalid, without permission", "viewer", "", http.MethodPost, "/api/product", `{"name": 5}`, http.StatusBadRequest},
+		{"invalid, with permission", "none", token, http.MethodPost, "/api/product", `{"name": 5}`, http.StatusBadRequest},
+		{"self or permission, anonymous", "none", "", http.MethodGet, "/api/user/" + userID.String() + "/tokens", "", http.StatusUnauthorized},
+		{"self or permission, invalid", "none", "", http.MethodGet, "/api/user/not-a-uuid/tokens", "", http.StatusBadRequest},
+		{"import, anonymous", "none", "", http.MethodPost, "/api/import/products?dry_run=true", "name\n", http.StatusUnauthorized},
+		{"import, invalid", "none", "", http.MethodPost, "/api/import/products?dry_run=maybe", "name\n", http.StatusBadRequest},
+		{"import, invalid with permission", "none", token, http.MethodPost, "/api/import/products?dry_run=maybe", "name\n", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Current.AnonymousRole = tt.anonymousRole
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				contentType := "application/json"
+				if strings.HasPrefix(tt.path, "/api/import") {
+					contentType = "text/csv"
+				}
+				req.Header.Set("Content-Type", contentType)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("got %d %s, want %d", w.Code, w.Body, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// schema is the subset of the OpenAPI schema object that requests are checked against.
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	Pattern              string             `json:"pattern"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	Items                *schema            `json:"items"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *schema            `json:"additionalProperties"`
+	AllOf                []*schema          `json:"allOf"`
+
+	target  *schema
+	pattern *regexp.Regexp
+}
+
+// resolve links the references in s to the component schemas and compiles its patterns.
+func (doc *document) resolve(s *schema) error {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		name, _ := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		if s.target = doc.Components.Schemas[name]; s.target == nil {
+			return fmt.Errorf("unknown schema %s", s.Ref)
+		}
+		return nil
+	}
+	if s.Pattern != "" {
+		var err error
+		if s.pattern, err = regexp.Compile(s.Pattern); err != nil {
+			return err
+		}
+	}
+	children := append([]*schema{s.Items, s.AdditionalProperties}, s.AllOf...)
+	for _, child := range s.Properties {
+		children = append(children, child)
+	}
+	for _, child := range children {
+		if err := doc.resolve(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deref follows a reference to the schema it names.
+func (s *schema) deref() *schema {
+	for s.target != nil {
+		s = s.target
+	}
+	return s
+}
+
+// hasBinary reports whether s is an object with a file upload among its properties.
+func (s *schema) hasBinary() bool {
+	for _, prop := range s.deref().Properties {
+		if prop.deref().Format == "binary" {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks a decoded JSON value against s. Objects are allowed properties s doesn't
+// list, since handlers ignore them.
+func (s *schema) validate(value any) error {
+	s = s.deref()
+	for _, sub := range s.AllOf {
+		if err := sub.validate(value); err != nil {
+			return err
+		}
+	}
+	if value == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("must not be null")
+	}
+	if len(s.Enum) > 0 && !s.allows(value) {
+		return fmt.Errorf("must be one of %s", s.enumList())
+	}
+	switch v := value.(type) {
+	case string:
+		if s.Type != "" && s.Type != "string" {
+			return fmt.Errorf("must be %s", article(s.Type))
+		}
+		return s.validateString(v)
+	case float64:
+		if s.Type == "integer" && v != math.Trunc(v) {
+			return fmt.Errorf("must be an integer")
+		}
+		if s.Type != "" && s.Type != "integer" && s.Type != "number" {
+			return fmt.Errorf("must be %s", article(s.Type))
+		}
+		if s.Minimum != nil && v < *s.Minimum {
+			return fmt.Errorf("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return fmt.Errorf("must be at most %v", *s.Maximum)
+		}
+	case bool:
+		if s.Type != "" && s.Type != "boolean" {
+			return fmt.Errorf("must be %s", article(s.Type))
+		}
+	case []any:
+		if s.Type != "" && s.Type != "array" {
+			return fmt.Errorf("must be %s", article(s.Type))
+		}
+		if s.Items == nil {
+			return nil
+		}
+		for i, item := range v {
+			if err := s.Items.validate(item); err != nil {
+				return within(fmt.Sprintf("[%d]", i), err)
+			}
+		}
+	case map[string]any:
+		if s.Type != "" && s.Type != "object" {
+			return fmt.Errorf("must be %s", article(s.Type))
+		}
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return within(name, fmt.Errorf("is required"))
+			}
+		}
+		for name, field := range v {
+			prop := s.Properties[name]
+			if prop == nil {
+				prop = s.AdditionalProperties
+			}
+			if prop == nil {
+				continue
+			}
+			if err := prop.validate(field); err != nil {
+				return within(name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *schema) validateString(v string) error {
+	length := utf8.RuneCountInString(v)
+	if s.MinLength != nil && length < *s.MinLength {
+		if *s.MinLength == 1 {
+			return fmt.Errorf("must not be empty")
+		}
+		return fmt.Errorf("must be at least %d characters long", *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		return fmt.Errorf("must be at most %d characters long", *s.MaxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(v) {
+		if s.Description != "" {
+			return fmt.Errorf("must be %s", strings.ToLower(s.Description[:1])+s.Description[1:])
+		}
+		return fmt.Errorf("must match %s", s.Pattern)
+	}
+	switch s.Format {
+	case "uuid":
+		if _, err := uuid.Parse(v); err != nil {
+			return fmt.Errorf("must be a UUID")
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, v); err != nil {
+			return fmt.Errorf("must be a date such as 2006-01-02")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			return fmt.Errorf("must be an RFC 3339 time such as 2006-01-02T15:04:05Z")
+		}
+	}
+	return nil
+}
+
+// parse converts the string values of a query parameter or form field to the type s expects,
+// so they can be validated like JSON. Only arrays take more than one value.
+func (s *schema) parse(values []string) (any, error) {
+	s = s.deref()
+	if s.Type == "array" {
+		items := make([]any, len(values))
+		for i, value := range values {
+			var err error
+			if s.Items == nil {
+				items[i] = value
+			} else if items[i], err = s.Items.parse([]string{value}); err != nil {
+				return nil, within(fmt.Sprintf("[%d]", i), err)
+			}
+		}
+		return items, nil
+	}
+	value := values[0]
+	switch s.Type {
+	case "integer", "number":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be %s", article(s.Type))
+		}
+		return n, nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("must be true or false")
+		}
+		return b, nil
+	}
+	return value, nil
+}
+
+func (s *schema) allows(value any) bool {
+	for _, allowed := range s.Enum {
+		if value == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *schema) enumList() string {
+	values := make([]string, len(s.Enum))
+	for i, value := range s.Enum {
+		values[i] = fmt.Sprint(value)
+	}
+	return strings.Join(values, ", ")
+}
+
+// fieldError is an error in a field of an object or array, such as scopes[1] or kinds.email.
+type fieldError struct {
+	field string
+	err   error
+}
+
+func (e *fieldError) Error() string {
+	return e.field + ": " + e.err.Error()
+}
+
+// within adds the field an error is in to its path.
+func within(field string, err error) error {
+	inner, ok := err.(*fieldError)
+	if !ok {
+		return &fieldError{field, err}
+	}
+	if strings.HasPrefix(inner.field, "[") {
+		return &fieldError{field + inner.field, inner.err}
+	}
+	return &fieldError{field + "." + inner.field, inner.err}
+}
+
+func article(kind string) string {
+	if kind == "integer" || kind == "object" || kind == "array" {
+		return "an " + kind
+	}
+	return "a " + kind
+}
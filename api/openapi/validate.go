@@ -0,0 +1,171 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Validate rejects requests whose parameters or body don't match their operation in the
+// document, with 400, or 415 for a body of a type the operation doesn't take. Routes missing
+// from the document aren't checked; Missing lists them.
+//
+// It is registered on the API group after auth.Middleware, so it runs before each route's
+// permission check: an invalid request gets 400 whoever makes it.
+func Validate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path, ok := strings.CutPrefix(c.FullPath(), PREFIX)
+		if !ok {
+			return
+		}
+		op := spec.operations[c.Request.Method+" "+path]
+		if op == nil {
+			return
+		}
+		if err := op.validateParameters(c); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if status, err := op.validateBody(c); err != nil {
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+		}
+	}
+}
+
+func (op *operation) validateParameters(c *gin.Context) error {
+	query := c.Request.URL.Query()
+	for _, param := range op.Parameters {
+		var values []string
+		switch param.In {
+		case "path":
+			values = []string{c.Param(param.Name)}
+		case "query":
+			values = query[param.Name]
+		default:
+			continue
+		}
+		// Empty values are treated as missing, as gin's binding does
+		if len(values) == 0 || len(values) == 1 && values[0] == "" {
+			if param.Required {
+				return fmt.Errorf("%s parameter %q is required", param.In, param.Name)
+			}
+			continue
+		}
+		value, err := param.Schema.parse(values)
+		if err == nil {
+			err = param.Schema.validate(value)
+		}
+		if fe, ok := err.(*fieldError); ok {
+			return fmt.Errorf("%s parameter %q %w", param.In, param.Name+fe.field, fe.err)
+		} else if err != nil {
+			return fmt.Errorf("%s parameter %q %w", param.In, param.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateBody checks JSON and form bodies, and that other bodies are of a type the operation
+// takes. Bodies sent to operations without one are ignored, as the handlers do.
+func (op *operation) validateBody(c *gin.Context) (int, error) {
+	body := op.RequestBody
+	if body == nil {
+		return 0, nil
+	}
+	empty := c.Request.ContentLength == 0 && c.GetHeader("Content-Type") == ""
+	if empty {
+		if body.Required {
+			return http.StatusBadRequest, fmt.Errorf("request body is required")
+		}
+		return 0, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil {
+		mediaType = "application/octet-stream"
+	}
+	content, ok := body.Content[mediaType]
+	if !ok {
+		if content, ok = body.Content["*/*"]; !ok {
+			types := make([]string, 0, len(body.Content))
+			for t := range body.Content {
+				types = append(types, t)
+			}
+			slices.Sort(types)
+			return http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type %q, expected %s", mediaType, strings.Join(types, " or "))
+		}
+		// Anything else is passed to the handler as it is
+		return 0, nil
+	}
+	if content.Schema == nil {
+		return 0, nil
+	}
+
+	var value any
+	switch mediaType {
+	case "application/json":
+		raw, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+		if len(bytes.TrimSpace(raw)) == 0 {
+			if body.Required {
+				return http.StatusBadRequest, fmt.Errorf("request body is required")
+			}
+			return 0, nil
+		}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return http.StatusBadRequest, fmt.Errorf("request body is not valid JSON: %w", err)
+		}
+	case "application/x-www-form-urlencoded":
+		if err := c.Request.ParseForm(); err != nil {
+			return http.StatusBadRequest, err
+		}
+		if value, err = formValue(content.Schema, c.Request.PostForm); err != nil {
+			return http.StatusBadRequest, err
+		}
+	case "multipart/form-data":
+		// File uploads are left to the handler, which limits their size
+		if content.Schema.hasBinary() {
+			return 0, nil
+		}
+		form, err := c.MultipartForm()
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		if value, err = formValue(content.Schema, form.Value); err != nil {
+			return http.StatusBadRequest, err
+		}
+	default:
+		return 0, nil
+	}
+	if err := content.Schema.validate(value); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("request body: %w", err)
+	}
+	return 0, nil
+}
+
+// formValue turns the fields of a form that the schema describes into an object, so it can be
+// validated like a JSON body.
+func formValue(s *schema, form url.Values) (any, error) {
+	object := map[string]any{}
+	for name, prop := range s.deref().Properties {
+		values, ok := form[name]
+		if !ok || len(values) == 0 {
+			continue
+		}
+		value, err := prop.parse(values)
+		if err != nil {
+			return nil, fmt.Errorf("request body: %w", within(name, err))
+		}
+		object[name] = value
+	}
+	return object, nil
+}
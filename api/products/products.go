@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"net/http"
 	"reesource-tracker/api/sync"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/auth"
 	"reesource-tracker/lib/database"
 	id_helper "reesource-tracker/lib/id_helper"
 
@@ -12,11 +14,11 @@ import (
 )
 
 func Routes(route *gin.RouterGroup) {
-	route.GET("/products", getProducts)
-	route.POST("/product", createProduct)
-	route.GET("/product/:product_id", getProduct)
-	route.POST("/product/:product_id", updateProduct)
-	route.DELETE("/product/:product_id", deleteProduct)
+	route.GET("/products", auth.Require(auth.PermProductsRead), getProducts)
+	route.POST("/product", auth.Require(auth.PermProductsWrite), createProduct)
+	route.GET("/product/:product_id", auth.Require(auth.PermProductsRead), getProduct)
+	route.POST("/product/:product_id", auth.Require(auth.PermProductsWrite), updateProduct)
+	route.DELETE("/product/:product_id", auth.Require(auth.PermProductsDelete), deleteProduct)
 }
 
 // DELETE /product/:product_id
@@ -36,8 +38,9 @@ func deleteProduct(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	activity.Record(c, activity.KindProduct, productID, activity.ActionDeleted, nil)
 	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
-	sync.BroadcastEvent("products_updated", gin.H{})
+	broadcastProduct(c, binary_uuid, sync.OpDeleted)
 }
 
 func createProduct(c *gin.Context) {
@@ -49,6 +52,15 @@ func createProduct(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	var parentBinaryUUID []byte
+	if req.ParentProductID != nil && *req.ParentProductID != "" {
+		parent, errMsg, ok := id_helper.MustParseAndMarshalUUID(*req.ParentProductID)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+		parentBinaryUUID = parent
+	}
 	new_uid, err := uuid.New().MarshalBinary()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate product ID"})
@@ -58,15 +70,16 @@ func createProduct(c *gin.Context) {
 	params := database.UpsertProductParams{
 		ID:              new_uid,
 		Name:            req.Name,
-		ParentProductID: req.ParentProductID,
+		ParentProductID: parentBinaryUUID,
 	}
 	err = database.Connection.UpsertProduct(c, params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
-	sync.BroadcastEvent("products_updated", gin.H{})
+	activity.Record(c, activity.KindProduct, id_helper.BlobToString(new_uid), activity.ActionCreated, gin.H{"name": req.Name})
+	c.JSON(http.StatusOK, gin.H{"status": "success", "id": id_helper.BlobToString(new_uid)})
+	broadcastProduct(c, new_uid, sync.OpCreated)
 }
 
 func getProduct(c *gin.Context) {
@@ -75,7 +88,12 @@ func getProduct(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "product_id required"})
 		return
 	}
-	product, err := database.Connection.GetProductByID(c, productID)
+	binary_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(productID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	product, err := database.Connection.GetProductByID(c, binary_uuid)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -124,8 +142,22 @@ func updateProduct(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	activity.Record(c, activity.KindProduct, productID, activity.ActionUpdated, req)
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
-	sync.BroadcastEvent("products_updated", gin.H{})
+	broadcastProduct(c, binary_uuid, sync.OpUpdated)
+}
+
+// broadcastProduct sends the current state of a product to sync clients.
+func broadcastProduct(c *gin.Context, id []byte, op string) {
+	var data interface{}
+	topics := []string{sync.TopicProduct + id_helper.BlobToString(id)}
+	if op != sync.OpDeleted {
+		if product, err := database.Connection.GetProductByID(c, id); err == nil {
+			data = product
+			topics = sync.ProductTopics(c, id)
+		}
+	}
+	sync.BroadcastChange(c, activity.KindProduct, id_helper.BlobToString(id), op, data, nil, topics...)
 }
 
 func getProducts(c *gin.Context) {
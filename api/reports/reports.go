@@ -0,0 +1,160 @@
+package reports
+
+import (
+	"encoding/csv"
+	"net/http"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/reports"
+	samplestate "reesource-tracker/lib/sample_state"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Routes(route *gin.RouterGroup) {
+	read := auth.Require(auth.PermSamplesRead)
+	route.GET("/reports", read, listReports)
+	route.GET("/reports/states", read, statesReport)
+	route.GET("/reports/products", read, productsReport)
+	route.GET("/reports/locations", read, locationsReport)
+	route.GET("/reports/owners", read, ownersReport)
+	route.GET("/reports/registrations", read, registrationsReport)
+	route.GET("/reports/state_changes", read, stateChangesReport)
+}
+
+var reportDescriptions = gin.H{
+	"states":        "Samples in each state",
+	"products":      "Samples per product, rolled up the product tree",
+	"locations":     "Samples per location, rolled up the location tree",
+	"owners":        "Samples per owner",
+	"registrations": "Samples registered per day, week or month",
+	"state_changes": "Samples moved into each state per day, week or month",
+}
+
+func listReports(c *gin.Context) {
+	c.JSON(http.StatusOK, reportDescriptions)
+}
+
+// wantsCSV reports whether the client asked for CSV with ?format=csv or an Accept header.
+func wantsCSV(c *gin.Context) bool {
+	if format := c.Query("format"); format != "" {
+		return format == "csv"
+	}
+	return strings.Contains(c.GetHeader("Accept"), "text/csv")
+}
+
+// respond sends data as JSON, or the header and rows as a CSV download.
+func respond(c *gin.Context, name string, data interface{}, header []string, rows [][]string) {
+	if !wantsCSV(c) {
+		c.JSON(http.StatusOK, data)
+		return
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	w.Write(header)
+	w.WriteAll(rows)
+}
+
+func count(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+func statesReport(c *gin.Context) {
+	counts, err := reports.ByState(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	rows := [][]string{}
+	for _, row := range counts {
+		rows = append(rows, []string{row.State, count(row.Count)})
+	}
+	respond(c, "states", counts, []string{"state", "count"}, rows)
+}
+
+func treeRows(counts []reports.TreeCount) [][]string {
+	rows := [][]string{}
+	for _, row := range counts {
+		rows = append(rows, []string{row.ID, row.Name, row.ParentID, row.Path, count(row.Count), count(row.Total)})
+	}
+	return rows
+}
+
+var treeHeader = []string{"id", "name", "parent_id", "path", "count", "total"}
+
+func productsReport(c *gin.Context) {
+	counts, err := reports.ByProduct(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respond(c, "products", counts, treeHeader, treeRows(counts))
+}
+
+func locationsReport(c *gin.Context) {
+	counts, err := reports.ByLocation(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respond(c, "locations", counts, treeHeader, treeRows(counts))
+}
+
+func ownersReport(c *gin.Context) {
+	counts, err := reports.ByOwner(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	rows := [][]string{}
+	for _, row := range counts {
+		rows = append(rows, []string{row.OwnerID, row.OwnerName, count(row.Count)})
+	}
+	respond(c, "owners", counts, []string{"owner_id", "owner_name", "count"}, rows)
+}
+
+// GET /reports/registrations?bucket=week&from=2025-01-01&to=2025-03-31
+func registrationsReport(c *gin.Context) {
+	period, err := reports.ParsePeriod(c.Query("bucket"), c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	points, err := reports.Registrations(c, period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	rows := [][]string{}
+	for _, point := range points {
+		rows = append(rows, []string{point.Period, count(point.Count)})
+	}
+	respond(c, "registrations", points, []string{period.Bucket, "count"}, rows)
+}
+
+// GET /reports/state_changes?bucket=month
+func stateChangesReport(c *gin.Context) {
+	period, err := reports.ParsePeriod(c.Query("bucket"), c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	points, err := reports.StateChanges(c, period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	header := append([]string{period.Bucket, "total"}, samplestate.States...)
+	rows := [][]string{}
+	for _, point := range points {
+		row := []string{point.Period, count(point.Total)}
+		for _, state := range samplestate.States {
+			row = append(row, count(point.States[state]))
+		}
+		rows = append(rows, row)
+	}
+	respond(c, "state_changes", points, header, rows)
+}
@@ -0,0 +1,121 @@
+package roles
+
+import (
+	"net/http"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Routes(route *gin.RouterGroup) {
+	route.GET("/permissions", getPermissions)
+	route.GET("/roles", getRoles)
+	route.GET("/user/:user_id/roles", auth.RequireSelfOr(auth.PermUsersRead, auth.PermRolesManage), getUserRoles)
+	route.POST("/user/:user_id/roles", auth.Require(auth.PermRolesManage), addUserRole)
+	route.DELETE("/user/:user_id/roles/:role", auth.Require(auth.PermRolesManage), removeUserRole)
+}
+
+// GET /permissions
+// Lists what the current principal may do so the client can hide controls it can't use.
+func getPermissions(c *gin.Context) {
+	p, ok := auth.CurrentPrincipal(c)
+	if !ok {
+		auth.Forbidden(c, "")
+		return
+	}
+	res := gin.H{
+		"anonymous":   p.Anonymous,
+		"roles":       p.Roles,
+		"permissions": p.Permissions,
+	}
+	if !p.Anonymous {
+		res["user_id"] = p.UserID.String()
+	}
+	if p.TokenID != nil {
+		res["token_id"] = p.TokenID.String()
+		res["scopes"] = p.Scopes
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GET /roles
+func getRoles(c *gin.Context) {
+	c.JSON(http.StatusOK, auth.RolePermissions)
+}
+
+// GET /user/:user_id/roles
+func getUserRoles(c *gin.Context) {
+	userID := c.Param("user_id")
+	binary_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(userID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	roles, err := database.Connection.ListUserRoles(c, binary_uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": roles, "permissions": auth.PermissionsForRoles(roles)})
+}
+
+// POST /user/:user_id/roles
+func addUserRole(c *gin.Context) {
+	userID := c.Param("user_id")
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !auth.IsRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+		return
+	}
+	binary_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(userID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	if _, err := database.Connection.GetUserByID(c, binary_uuid); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	err := database.Connection.AddUserRole(c, database.AddUserRoleParams{
+		UserID: binary_uuid,
+		Role:   req.Role,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	activity.Record(c, activity.KindUser, userID, activity.ActionRoleAdded, gin.H{"role": req.Role})
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// DELETE /user/:user_id/roles/:role
+func removeUserRole(c *gin.Context) {
+	userID := c.Param("user_id")
+	binary_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(userID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	err := database.Connection.RemoveUserRole(c, database.RemoveUserRoleParams{
+		UserID: binary_uuid,
+		Role:   c.Param("role"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	activity.Record(c, activity.KindUser, userID, activity.ActionRoleRemoved, gin.H{"role": c.Param("role")})
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
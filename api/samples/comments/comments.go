@@ -0,0 +1,137 @@
+package comments
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"reesource-tracker/api/sync"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	"reesource-tracker/lib/notifications"
+	sampleid "reesource-tracker/lib/sample_id"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func Routes(route *gin.RouterGroup) {
+	route.POST("/", auth.Require(auth.PermSamplesWrite), addComment)
+	route.GET("/", auth.Require(auth.PermSamplesRead), listComments)
+}
+
+// POST /sample/:sample_id/comments
+// Users mentioned as @Name in the comment are notified.
+func addComment(c *gin.Context) {
+	var req struct {
+		Comment string `json:"comment" form:"comment"`
+	}
+	if err := c.ShouldBind(&req); err != nil || strings.TrimSpace(req.Comment) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment is required"})
+		return
+	}
+	parts, err := sampleid.ParseSampleID(c.Param("sample_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sample ID format"})
+		return
+	}
+	RawSampleID := parts[:]
+	if _, err := database.Connection.GetSampleById(c, RawSampleID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sample not found"})
+		return
+	}
+	commentID, err := uuid.New().MarshalBinary()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate comment ID"})
+		return
+	}
+	err = database.Connection.AddSampleComment(c, database.AddSampleCommentParams{
+		ID:        commentID,
+		SampleID:  RawSampleID,
+		Comment:   req.Comment,
+		CreatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		AuthorID:  activity.ActorUserID(c),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	display_id, _ := sampleid.FormatSampleID(RawSampleID)
+	changes := gin.H{
+		"comment_id": id_helper.BlobToString(commentID),
+		"comment":    req.Comment,
+	}
+	activity.Record(c, activity.KindSample, display_id, activity.ActionCommented, changes)
+	sync.BroadcastSample(c, RawSampleID, sync.OpUpdated, gin.H{"comment_added": changes})
+	notifyMentions(c, display_id, req.Comment)
+	c.JSON(http.StatusOK, gin.H{"message": "Comment added"})
+}
+
+func listComments(c *gin.Context) {
+	parts, err := sampleid.ParseSampleID(c.Param("sample_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sample ID format"})
+		return
+	}
+	comments, err := database.Connection.ListSampleComments(c, parts[:])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"comments": comments})
+}
+
+func notifyMentions(c *gin.Context, display_id string, comment string) {
+	if !notifications.Enabled() {
+		return
+	}
+	users, err := database.Connection.GetUsers(c)
+	if err != nil {
+		slog.ErrorContext(c, "Failed to load users for mentions", "error", err)
+		return
+	}
+	// Longest names first, so "@Bob Smith" doesn't also mention "Bob"
+	sort.SliceStable(users, func(i, j int) bool { return len(users[i].Name) > len(users[j].Name) })
+	remaining := strings.ToLower(comment)
+	for _, user := range users {
+		var found bool
+		remaining, found = takeMentions(remaining, user.Name)
+		if found {
+			notifications.Notify(c, user.ID, notifications.KindCommentMention, notifications.Message{
+				SampleID: display_id,
+				Comment:  comment,
+			})
+		}
+	}
+}
+
+// takeMentions removes every @name from a lowercased comment, ignoring matches that are the
+// start of a longer word, and reports whether there were any.
+func takeMentions(comment string, name string) (string, bool) {
+	if name == "" {
+		return comment, false
+	}
+	mention := "@" + strings.ToLower(name)
+	found := false
+	for offset := 0; ; {
+		index := strings.Index(comment[offset:], mention)
+		if index == -1 {
+			return comment, found
+		}
+		start, end := offset+index, offset+index+len(mention)
+		next, _ := utf8.DecodeRuneInString(comment[end:])
+		if end < len(comment) && (unicode.IsLetter(next) || unicode.IsDigit(next)) {
+			offset = end
+			continue
+		}
+		comment = comment[:start] + comment[end:]
+		offset = start
+		found = true
+	}
+}
@@ -0,0 +1,199 @@
+package loans
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"reesource-tracker/api/sync"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	sampleid "reesource-tracker/lib/sample_id"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func Routes(route *gin.RouterGroup) {
+	route.GET("/loans", auth.Require(auth.PermSamplesRead), listLoans)
+	route.GET("/sample/:sample_id/loan", auth.Require(auth.PermSamplesRead), getLoan)
+	route.POST("/sample/:sample_id/loan", auth.Require(auth.PermSamplesWrite), createLoan)
+	route.DELETE("/sample/:sample_id/loan", auth.Require(auth.PermSamplesWrite), returnLoan)
+}
+
+// Loan is the API representation of a sample lent to a user.
+type Loan struct {
+	ID       string    `json:"id"`
+	SampleID string    `json:"sample_id"`
+	UserID   string    `json:"user_id"`
+	LoanedAt time.Time `json:"loaned_at"`
+	DueAt    time.Time `json:"due_at"`
+	Overdue  bool      `json:"overdue"`
+}
+
+func loanFromRow(row database.SampleLoan) Loan {
+	raw, _ := row.SampleID.([]byte)
+	display_id, _ := sampleid.FormatSampleID(raw)
+	return Loan{
+		ID:       id_helper.BlobToString(row.ID),
+		SampleID: display_id,
+		UserID:   id_helper.BlobToString(row.UserID),
+		LoanedAt: row.LoanedAt,
+		DueAt:    row.DueAt,
+		Overdue:  row.DueAt.Before(time.Now()),
+	}
+}
+
+// parseDueDate accepts an RFC 3339 time, or a date meaning the end of that day.
+func parseDueDate(value string) (time.Time, error) {
+	if due, err := time.Parse(time.RFC3339, value); err == nil {
+		return due.UTC(), nil
+	}
+	day, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Second).UTC(), nil
+}
+
+func listLoans(c *gin.Context) {
+	rows, err := database.Connection.ListActiveLoans(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	loans := make([]Loan, 0, len(rows))
+	for _, row := range rows {
+		loans = append(loans, loanFromRow(row))
+	}
+	c.JSON(http.StatusOK, loans)
+}
+
+func getLoan(c *gin.Context) {
+	parts, err := sampleid.ParseSampleID(c.Param("sample_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sample ID format"})
+		return
+	}
+	row, err := database.Connection.GetActiveSampleLoan(c, parts[:])
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sample is not on loan"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, loanFromRow(row))
+}
+
+// POST /sample/:sample_id/loan
+// Lends the sample to a user until due_at, e.g. {"user_id": "...", "due_at": "2025-01-31"}.
+// The borrower is reminded daily once the loan is overdue.
+func createLoan(c *gin.Context) {
+	var req struct {
+		UserID string `json:"user_id" form:"user_id"`
+		DueAt  string `json:"due_at" form:"due_at"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	parts, err := sampleid.ParseSampleID(c.Param("sample_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sample ID format"})
+		return
+	}
+	RawSampleID := parts[:]
+	userBinary, errMsg, ok := id_helper.MustParseAndMarshalUUID(req.UserID)
+	if !ok || userBinary == nil {
+		if errMsg == "" {
+			errMsg = "user_id is required"
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	dueAt, err := parseDueDate(req.DueAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "due_at must be a date (YYYY-MM-DD) or an RFC 3339 time"})
+		return
+	}
+	now := time.Now().UTC()
+	if !dueAt.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "due_at must be in the future"})
+		return
+	}
+	if _, err := database.Connection.GetSampleById(c, RawSampleID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sample not found"})
+		return
+	}
+	if _, err := database.Connection.GetUserByID(c, userBinary); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+	if _, err := database.Connection.GetActiveSampleLoan(c, RawSampleID); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Sample is already on loan"})
+		return
+	}
+	loanID, err := uuid.New().MarshalBinary()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate loan ID"})
+		return
+	}
+	err = database.Connection.CreateSampleLoan(c, database.CreateSampleLoanParams{
+		ID:       loanID,
+		SampleID: RawSampleID,
+		UserID:   userBinary,
+		LoanedAt: now,
+		DueAt:    dueAt,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	row, err := database.Connection.GetActiveSampleLoan(c, RawSampleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	loan := loanFromRow(row)
+	changes := gin.H{"loan": gin.H{"from": nil, "to": loan}}
+	activity.Record(c, activity.KindSample, loan.SampleID, activity.ActionUpdated, changes)
+	sync.BroadcastSample(c, RawSampleID, sync.OpUpdated, changes)
+	c.JSON(http.StatusOK, loan)
+}
+
+// DELETE /sample/:sample_id/loan
+// Marks the sample as returned.
+func returnLoan(c *gin.Context) {
+	parts, err := sampleid.ParseSampleID(c.Param("sample_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sample ID format"})
+		return
+	}
+	RawSampleID := parts[:]
+	row, err := database.Connection.GetActiveSampleLoan(c, RawSampleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sample is not on loan"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	err = database.Connection.ReturnSampleLoan(c, database.ReturnSampleLoanParams{
+		ReturnedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		SampleID:   RawSampleID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	loan := loanFromRow(row)
+	changes := gin.H{"loan": gin.H{"from": loan, "to": nil}}
+	activity.Record(c, activity.KindSample, loan.SampleID, activity.ActionUpdated, changes)
+	sync.BroadcastSample(c, RawSampleID, sync.OpUpdated, changes)
+	c.JSON(http.StatusOK, gin.H{"status": "returned"})
+}
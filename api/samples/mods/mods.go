@@ -1,7 +1,10 @@
 package mods
 
 import (
+	"bytes"
 	"reesource-tracker/api/sync"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/auth"
 	"reesource-tracker/lib/database"
 	"reesource-tracker/lib/id_helper"
 	sampleid "reesource-tracker/lib/sample_id"
@@ -14,9 +17,9 @@ import (
 )
 
 func Routes(route *gin.RouterGroup) {
-	route.POST("/", addMod)
-	route.DELETE("/:mod_id", removeMod)
-	route.GET("/", listMods)
+	route.POST("/", auth.Require(auth.PermSamplesWrite), addMod)
+	route.DELETE("/:mod_id", auth.Require(auth.PermSamplesWrite), removeMod)
+	route.GET("/", auth.Require(auth.PermSamplesRead), listMods)
 }
 
 func addMod(c *gin.Context) {
@@ -49,12 +52,19 @@ func addMod(c *gin.Context) {
 		SampleID:  RawSampleID,
 		Name:      req.Name,
 		TimeAdded: timeNow,
+		AddedBy:   activity.ActorUserID(c),
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	sync.BroadcastEvent("samples_updated", gin.H{})
+	display_id, _ := sampleid.FormatSampleID(RawSampleID)
+	changes := gin.H{
+		"mod_id": id_helper.BlobToString(modID),
+		"name":   req.Name,
+	}
+	activity.Record(c, activity.KindSample, display_id, activity.ActionModAdded, changes)
+	sync.BroadcastSample(c, RawSampleID, sync.OpUpdated, gin.H{"mod_added": changes})
 	c.JSON(http.StatusOK, gin.H{"message": "Mod added"})
 }
 
@@ -64,20 +74,39 @@ func removeMod(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Mod ID is required"})
 		return
 	}
+	parts, err := sampleid.ParseSampleID(c.Param("sample_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sample ID format"})
+		return
+	}
+	RawSampleID := parts[:]
 	modUUID, msg, ok := id_helper.MustParseAndMarshalUUID(modID)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
 		return
 	}
-	err := database.Connection.RemoveSampleMod(c, database.RemoveSampleModParams{
+	mod, err := database.Connection.GetSampleMod(c, modUUID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mod not found"})
+		return
+	}
+	if modSampleID, _ := mod.SampleID.([]byte); !bytes.Equal(modSampleID, RawSampleID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mod not found"})
+		return
+	}
+	err = database.Connection.RemoveSampleMod(c, database.RemoveSampleModParams{
 		TimeRemoved: sql.NullTime{Time: time.Now(), Valid: true},
+		RemovedBy:   activity.ActorUserID(c),
 		ID:          modUUID,
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	sync.BroadcastEvent("samples_updated", gin.H{})
+	display_id, _ := sampleid.FormatSampleID(RawSampleID)
+	changes := gin.H{"mod_id": modID}
+	activity.Record(c, activity.KindSample, display_id, activity.ActionModRemoved, changes)
+	sync.BroadcastSample(c, RawSampleID, sync.OpUpdated, gin.H{"mod_removed": changes})
 	c.JSON(http.StatusOK, gin.H{"message": "Mod removed"})
 }
 
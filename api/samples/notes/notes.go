@@ -0,0 +1,81 @@
+package notes
+
+import (
+	"net/http"
+	"reesource-tracker/api/sync"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	sampleid "reesource-tracker/lib/sample_id"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func Routes(route *gin.RouterGroup) {
+	route.POST("/", auth.Require(auth.PermSamplesWrite), addNote)
+	route.GET("/", auth.Require(auth.PermSamplesRead), listNotes)
+}
+
+// POST /sample/:sample_id/notes
+func addNote(c *gin.Context) {
+	var req struct {
+		Contents string `json:"contents" form:"contents"`
+	}
+	if err := c.ShouldBind(&req); err != nil || strings.TrimSpace(req.Contents) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Contents are required"})
+		return
+	}
+	parts, err := sampleid.ParseSampleID(c.Param("sample_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sample ID format"})
+		return
+	}
+	RawSampleID := parts[:]
+	if _, err := database.Connection.GetSampleById(c, RawSampleID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sample not found"})
+		return
+	}
+	noteID, err := uuid.New().MarshalBinary()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate note ID"})
+		return
+	}
+	err = database.Connection.AddSampleNote(c, database.AddSampleNoteParams{
+		ID:       noteID,
+		SampleID: RawSampleID,
+		Contents: req.Contents,
+		TimeMade: time.Now(),
+		AuthorID: activity.ActorUserID(c),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	display_id, _ := sampleid.FormatSampleID(RawSampleID)
+	changes := gin.H{
+		"note_id":  id_helper.BlobToString(noteID),
+		"contents": req.Contents,
+	}
+	activity.Record(c, activity.KindSample, display_id, activity.ActionNoted, changes)
+	sync.BroadcastSample(c, RawSampleID, sync.OpUpdated, gin.H{"note_added": changes})
+	c.JSON(http.StatusOK, gin.H{"message": "Note added"})
+}
+
+// GET /sample/:sample_id/notes
+func listNotes(c *gin.Context) {
+	parts, err := sampleid.ParseSampleID(c.Param("sample_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sample ID format"})
+		return
+	}
+	notes, err := database.Connection.ListSampleNotes(c, parts[:])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"notes": notes})
+}
@@ -2,11 +2,19 @@ package samples
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
+	"reesource-tracker/api/samples/comments"
+	"reesource-tracker/api/samples/loans"
 	"reesource-tracker/api/samples/mods"
+	"reesource-tracker/api/samples/notes"
 	"reesource-tracker/api/sync"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/auth"
 	"reesource-tracker/lib/database"
 	id_helper "reesource-tracker/lib/id_helper"
+	"reesource-tracker/lib/notifications"
+	samplefilter "reesource-tracker/lib/sample_filter"
 	sampleid "reesource-tracker/lib/sample_id"
 	"strconv"
 	"strings"
@@ -15,17 +23,15 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-type SampleData struct {
-	database.ListSamplesRow
-	Mods []database.SampleMod `json:"mods"`
-}
-
 func Routes(route *gin.RouterGroup) {
-	route.GET("/samples", getSamples)
-	route.GET("/sample/:sample_id", getSample)
-	route.POST("/sample/:sample_id", updateSample)
-	route.GET("/generate_samples", generateUniqueSamples)
+	route.GET("/samples", auth.Require(auth.PermSamplesRead), getSamples)
+	route.GET("/sample/:sample_id", auth.Require(auth.PermSamplesRead), getSample)
+	route.POST("/sample/:sample_id", auth.Require(auth.PermSamplesWrite), updateSample)
+	route.GET("/generate_samples", auth.Require(auth.PermSamplesWrite), generateUniqueSamples)
 	mods.Routes(route.Group("/sample/:sample_id/mods"))
+	comments.Routes(route.Group("/sample/:sample_id/comments"))
+	notes.Routes(route.Group("/sample/:sample_id/notes"))
+	loans.Routes(route)
 }
 
 func getSample(c *gin.Context) {
@@ -116,6 +122,13 @@ func updateSample(c *gin.Context) {
 
 	productIssue := c.PostForm("product_issue")
 
+	previous, err := database.Connection.GetSampleById(c, RawSampleID)
+	created := errors.Is(err, sql.ErrNoRows)
+	if err != nil && !created {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	current_time := time.Now()
 
 	res, err := database.Connection.UpdateOrCreateSample(c, database.UpdateOrCreateSampleParams{
@@ -132,27 +145,86 @@ func updateSample(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	sync.BroadcastEvent("samples_updated", gin.H{})
+	display_id, _ := sampleid.FormatSampleID(RawSampleID)
+	action, op := activity.ActionUpdated, sync.OpUpdated
+	if created {
+		action, op = activity.ActionCreated, sync.OpCreated
+	}
+	changes := sampleChanges(previous, res)
+	activity.Record(c, activity.KindSample, display_id, action, changes)
+	previousTopics := sync.SampleTopics(c, display_id, previous.LocationID, previous.ProductID, previous.OwnerID)
+	sync.BroadcastSample(c, RawSampleID, op, changes, previousTopics...)
+	notifySampleChanges(c, display_id, previous, res)
 	c.JSON(http.StatusOK, res)
 }
 
+// sampleChanges lists the fields that differ between two versions of a sample.
+func sampleChanges(before database.Sample, after database.Sample) gin.H {
+	changes := gin.H{}
+	compare := func(field string, from string, to string) {
+		if from != to {
+			changes[field] = gin.H{"from": from, "to": to}
+		}
+	}
+	compare("location_id", id_helper.BlobToString(before.LocationID), id_helper.BlobToString(after.LocationID))
+	compare("product_id", id_helper.BlobToString(before.ProductID), id_helper.BlobToString(after.ProductID))
+	compare("owner_id", id_helper.BlobToString(before.OwnerID), id_helper.BlobToString(after.OwnerID))
+	compare("state", before.State, after.State)
+	compare("product_issue", before.ProductIssue.String, after.ProductIssue.String)
+	return changes
+}
+
+// notifySampleChanges emails the owners affected by an update.
+func notifySampleChanges(c *gin.Context, display_id string, before database.Sample, after database.Sample) {
+	if id_helper.BlobToString(before.OwnerID) != id_helper.BlobToString(after.OwnerID) {
+		notifications.Notify(c, after.OwnerID, notifications.KindOwnerChanged, notifications.Message{
+			SampleID:   display_id,
+			Assigned:   true,
+			OtherOwner: notifications.UserName(c, before.OwnerID),
+		})
+		notifications.Notify(c, before.OwnerID, notifications.KindOwnerChanged, notifications.Message{
+			SampleID:   display_id,
+			OtherOwner: notifications.UserName(c, after.OwnerID),
+		})
+	}
+	if after.State == "broken" && before.State != "broken" {
+		notifications.Notify(c, after.OwnerID, notifications.KindStateBroken, notifications.Message{
+			SampleID:      display_id,
+			PreviousState: before.State,
+		})
+	}
+}
+
+// GET /samples?state=broken&location=any-<uuid>
+// Takes the optional filters in samplefilter.Filter, the same ones as the sample list.
 func getSamples(c *gin.Context) {
-	res, err := database.Connection.ListSamples(c)
+	var filter samplefilter.Filter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	matcher, err := filter.Matcher(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	samples, err := database.Connection.ListSampleData(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	var samples []SampleData = []SampleData{}
-	for _, sample := range res {
-		// Convert time_registered to a string
-		mod_data, err := database.Connection.ListSampleMods(c, sample.ID)
+	selected := []database.SampleData{}
+	for _, sample := range samples {
+		match, err := matcher.Match(c, sample.ListSamplesRow)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		samples = append(samples, SampleData{sample, mod_data})
+		if match {
+			selected = append(selected, sample)
+		}
 	}
-	c.JSON(http.StatusOK, samples)
+	c.JSON(http.StatusOK, selected)
 }
 
 func generateUniqueSamples(c *gin.Context) {
@@ -162,23 +234,25 @@ func generateUniqueSamples(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid number of samples"})
 		return
 	}
-	sample_ids := make([]string, numSamples)
-	for i := 0; i < numSamples; i++ {
-		new_id_string, new_id, err := sampleid.GenerateNewSampleID()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+	var rawIDs [][]byte
+	var sample_ids []string
+	err = database.Transaction(c, func(q *database.Queries) error {
+		var registerErr error
+		rawIDs, registerErr = q.RegisterNewSamples(c, numSamples)
+		if registerErr != nil {
+			return registerErr
 		}
-		_, err = database.Connection.UpdateOrCreateSample(c, database.UpdateOrCreateSampleParams{
-			ID:    new_id[:],
-			State: "unassigned",
-		})
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+		sample_ids = make([]string, len(rawIDs))
+		for i, new_id := range rawIDs {
+			sample_ids[i], _ = sampleid.FormatSampleID(new_id)
 		}
-		sample_ids[i] = new_id_string
+		// One entry for the whole batch, which can be thousands of samples
+		return activity.RecordWith(c, q, activity.KindSample, "", activity.ActionGenerated, gin.H{"sample_ids": sample_ids})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
+	sync.BroadcastGenerated(c, rawIDs)
 	c.JSON(http.StatusOK, gin.H{"message": "Samples generated successfully", "sample_ids": sample_ids})
-	sync.BroadcastEvent("samples_updated", gin.H{})
 }
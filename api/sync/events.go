@@ -0,0 +1,157 @@
+package sync
+
+import (
+	"context"
+	"maps"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	"reesource-tracker/lib/importer"
+	sampleid "reesource-tracker/lib/sample_id"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Operations carried in EntityEvent.Op.
+const (
+	OpCreated = "created"
+	OpUpdated = "updated"
+	OpDeleted = "deleted"
+)
+
+// Event type for each entity kind. These names, and the EntityEvent payload, are a stable
+// contract with clients; see docs/sync-events.md before changing them.
+var EventTypes = map[string]string{
+	activity.KindSample:   "samples_updated",
+	activity.KindProduct:  "products_updated",
+	activity.KindLocation: "locations_updated",
+	activity.KindUser:     "users_updated",
+}
+
+// EntityEvent describes a single change to a sample, product, location or user.
+type EntityEvent struct {
+	Kind string `json:"kind"`
+	// ID is the formatted sample ID (e.g. "0A-1B-2C") for samples and a UUID string otherwise.
+	ID string `json:"id"`
+	Op string `json:"op"`
+	// Data is the entity as returned by its GET endpoint. It is omitted for deletes, and
+	// clients should refetch the collection if it is missing on a create or update.
+	Data    interface{}    `json:"data,omitempty"`
+	Changes interface{}    `json:"changes,omitempty"`
+	Actor   activity.Actor `json:"actor"`
+	Time    time.Time      `json:"time"`
+}
+
+// BroadcastChange notifies clients of a change made by the current request. topics are
+// the subscription topics the change is relevant to, see topics.go.
+func BroadcastChange(c *gin.Context, kind string, id string, op string, data interface{}, changes interface{}, topics ...string) {
+	BroadcastEvent(EventTypes[kind], EntityEvent{
+		Kind:    kind,
+		ID:      id,
+		Op:      op,
+		Data:    data,
+		Changes: changes,
+		Actor:   activity.ActorOf(c),
+		Time:    time.Now(),
+	}, topics...)
+}
+
+// BroadcastSample sends the current state of a sample to clients. extraTopics lets callers
+// include topics the sample has just left, e.g. the location it was moved out of.
+func BroadcastSample(c *gin.Context, rawID []byte, op string, changes interface{}, extraTopics ...string) {
+	display_id, _ := sampleid.FormatSampleID(rawID)
+	var data interface{}
+	topics := []string{TopicSample + display_id}
+	if sample, err := database.Connection.GetSampleData(c, rawID); err == nil {
+		data = sample
+		topics = SampleTopics(c, display_id, sample.LocationID, sample.ProductID, sample.OwnerID)
+	}
+	BroadcastChange(c, activity.KindSample, display_id, op, data, changes, append(topics, extraTopics...)...)
+}
+
+// EventImported is sent once for each import, in place of an event for every entity it
+// created or updated.
+const EventImported = "imported"
+
+// ImportGenerated is the ImportEvent kind for samples registered by /generate_samples.
+const ImportGenerated = "generated"
+
+// ImportEvent describes a completed import. Clients should refetch the affected collections.
+type ImportEvent struct {
+	// Kind is what the file contained: samples, products, locations or users. It is
+	// ImportGenerated for newly generated samples.
+	Kind string `json:"kind"`
+	// Created and Updated list the IDs of the changed entities by their kind
+	Created map[string][]string `json:"created"`
+	Updated map[string][]string `json:"updated"`
+	Actor   activity.Actor      `json:"actor"`
+	Time    time.Time           `json:"time"`
+}
+
+// BroadcastImport sends a single event for the changes made by an import, tagged with the
+// topics of every changed entity.
+func BroadcastImport(c *gin.Context, kind string, changes []importer.Change) {
+	evt := ImportEvent{
+		Kind:    kind,
+		Created: map[string][]string{},
+		Updated: map[string][]string{},
+		Actor:   activity.ActorOf(c),
+		Time:    time.Now(),
+	}
+	topics := map[string]bool{}
+	// Many samples share a location or product, so their trees are only walked once
+	treeTopics := map[string][]string{}
+	cached := func(prefix string, id interface{}, walk func(context.Context, interface{}) []string) []string {
+		key := prefix + id_helper.BlobToString(id)
+		if _, ok := treeTopics[key]; !ok {
+			treeTopics[key] = walk(c, id)
+		}
+		return treeTopics[key]
+	}
+	for _, change := range changes {
+		if change.Action == activity.ActionCreated {
+			evt.Created[change.Kind] = append(evt.Created[change.Kind], change.ID)
+		} else {
+			evt.Updated[change.Kind] = append(evt.Updated[change.Kind], change.ID)
+		}
+		keys := []string{}
+		switch change.Kind {
+		case activity.KindSample:
+			keys = append(keys, TopicSample+change.ID)
+			if sample, err := database.Connection.GetSampleById(c, change.RawID); err == nil {
+				keys = append(keys, cached(TopicLocation, sample.LocationID, LocationTopics)...)
+				keys = append(keys, cached(TopicProduct, sample.ProductID, ProductTopics)...)
+				if owner := id_helper.BlobToString(sample.OwnerID); owner != "" {
+					keys = append(keys, TopicOwner+owner)
+				}
+			}
+		case activity.KindProduct:
+			keys = cached(TopicProduct, change.RawID, ProductTopics)
+		case activity.KindLocation:
+			keys = cached(TopicLocation, change.RawID, LocationTopics)
+		case activity.KindUser:
+			keys = []string{TopicOwner + change.ID}
+		}
+		for _, key := range keys {
+			topics[key] = true
+		}
+	}
+	BroadcastEvent(EventImported, evt, slices.Sorted(maps.Keys(topics))...)
+}
+
+// BroadcastGenerated sends a single event for a batch of newly generated samples.
+func BroadcastGenerated(c *gin.Context, rawIDs [][]byte) {
+	changes := make([]importer.Change, 0, len(rawIDs))
+	for _, rawID := range rawIDs {
+		display_id, _ := sampleid.FormatSampleID(rawID)
+		changes = append(changes, importer.Change{
+			Kind:   activity.KindSample,
+			ID:     display_id,
+			Action: activity.ActionCreated,
+			RawID:  rawID,
+		})
+	}
+	BroadcastImport(c, ImportGenerated, changes)
+}
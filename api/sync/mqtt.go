@@ -0,0 +1,73 @@
+package sync
+
+import (
+	"context"
+	"log/slog"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/database"
+	"reesource-tracker/lib/mqtt"
+	sampleid "reesource-tracker/lib/sample_id"
+	"strings"
+)
+
+// publishMQTT mirrors an event to the MQTT broker, if one is configured. Changes go to
+// <prefix>/<collection>/<id>/<op>, e.g. reesource/samples/0A-1B-2C/updated, and the current
+// state of each sample is kept as a retained message on reesource/samples/0A-1B-2C.
+func publishMQTT(evtType string, data interface{}) {
+	if !mqtt.Enabled() {
+		return
+	}
+	switch evt := data.(type) {
+	case EntityEvent:
+		collection := strings.TrimSuffix(evtType, "_updated")
+		mqtt.Publish(mqtt.Topic(collection, evt.ID, evt.Op), evt, false)
+		if evt.Kind == activity.KindSample && (evt.Data != nil || evt.Op == OpDeleted) {
+			// A nil state clears the retained message for deleted samples
+			mqtt.Publish(mqtt.Topic(collection, evt.ID), evt.Data, true)
+		}
+	case PresenceEvent:
+		mqtt.Publish(mqtt.Topic(EventPresence, evt.Session), evt, false)
+	case ImportEvent:
+		mqtt.Publish(mqtt.Topic(EventImported, evt.Kind), evt, false)
+		publishImportedSamples(append(evt.Created[activity.KindSample], evt.Updated[activity.KindSample]...))
+	}
+}
+
+// publishImportedSamples updates the retained state of samples changed by an import.
+func publishImportedSamples(ids []string) {
+	collection := strings.TrimSuffix(EventTypes[activity.KindSample], "_updated")
+	for _, display_id := range ids {
+		raw, err := sampleid.ParseSampleID(display_id)
+		if err != nil {
+			continue
+		}
+		sample, err := database.Connection.GetSampleData(context.Background(), raw[:])
+		if err != nil {
+			slog.Error("Failed to load sample for MQTT", "sample_id", display_id, "error", err)
+			continue
+		}
+		mqtt.Publish(mqtt.Topic(collection, display_id), sample, true)
+	}
+}
+
+// PublishSampleStates publishes the retained state of every sample. It is run whenever the
+// MQTT client connects, in case the broker has lost its retained messages.
+func PublishSampleStates() {
+	if database.Connection == nil {
+		return
+	}
+	samples, err := database.Connection.ListSampleData(context.Background())
+	if err != nil {
+		slog.Error("Failed to load samples for MQTT", "error", err)
+		return
+	}
+	collection := strings.TrimSuffix(EventTypes[activity.KindSample], "_updated")
+	for _, sample := range samples {
+		raw, _ := sample.ID.([]byte)
+		display_id, err := sampleid.FormatSampleID(raw)
+		if err != nil {
+			continue
+		}
+		mqtt.Publish(mqtt.Topic(collection, display_id), sample, true)
+	}
+}
@@ -0,0 +1,169 @@
+package sync_test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reesource-tracker/api"
+	"reesource-tracker/api/sync"
+	"reesource-tracker/lib/config"
+	"reesource-tracker/lib/mqtt"
+	"reesource-tracker/lib/testenv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type message struct {
+	topic    string
+	payload  []byte
+	retained bool
+}
+
+// newBroker starts a minimal MQTT 3.1.1 broker that accepts any client and passes on the
+// messages it publishes.
+func newBroker(t *testing.T) (string, chan message) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	messages := make(chan message, 100)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveMQTT(conn, messages)
+		}
+	}()
+	return "tcp://" + ln.Addr().String(), messages
+}
+
+func serveMQTT(conn net.Conn, messages chan message) {
+	defer conn.Close()
+	for {
+		header := make([]byte, 1)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		// The remaining length is a varint of up to four bytes
+		length, shift := 0, 0
+		for {
+			b := make([]byte, 1)
+			if _, err := io.ReadFull(conn, b); err != nil {
+				return
+			}
+			length |= int(b[0]&0x7f) << shift
+			shift += 7
+			if b[0]&0x80 == 0 {
+				break
+			}
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		switch header[0] >> 4 {
+		case 1: // CONNECT
+			conn.Write([]byte{0x20, 2, 0, 0})
+		case 3: // PUBLISH
+			qos := header[0] >> 1 & 3
+			topicLength := int(binary.BigEndian.Uint16(body))
+			msg := message{topic: string(body[2 : 2+topicLength]), retained: header[0]&1 == 1}
+			rest := body[2+topicLength:]
+			if qos > 0 {
+				conn.Write([]byte{0x40, 2, rest[0], rest[1]})
+				rest = rest[2:]
+			}
+			msg.payload = rest
+			messages <- msg
+		case 12: // PINGREQ
+			conn.Write([]byte{0xd0, 0})
+		case 14: // DISCONNECT
+			return
+		}
+	}
+}
+
+// wait returns the payload of the next message on topic with the given retain flag,
+// skipping any others.
+func wait(t *testing.T, messages chan message, topic string, retained bool) map[string]any {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-messages:
+			if msg.topic != topic || msg.retained != retained {
+				continue
+			}
+			var payload map[string]any
+			if err := json.Unmarshal(msg.payload, &payload); err != nil {
+				t.Fatalf("%s: %v", topic, err)
+			}
+			return payload
+		case <-timeout:
+			t.Fatalf("timed out waiting for a message on %s with retain %t", topic, retained)
+			return nil
+		}
+	}
+}
+
+func request(t *testing.T, r *gin.Engine, token string, method string, path string, form url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s %s: got %d %s", method, path, w.Code, w.Body)
+	}
+	return w
+}
+
+// Each sample's state is kept as a retained message, published for every sample when the
+// client connects and again whenever the sample changes.
+func TestRetainedSampleStates(t *testing.T) {
+	testenv.Open(t, config.DRIVER_SQLITE)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api.Routes(r)
+	token, _ := testenv.AdminToken(t)
+
+	var generated struct {
+		SampleIDs []string `json:"sample_ids"`
+	}
+	w := request(t, r, token, http.MethodGet, "/api/generate_samples?num_samples=1", nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &generated); err != nil || len(generated.SampleIDs) != 1 {
+		t.Fatalf("generating a sample: got %s", w.Body)
+	}
+	sampleID := generated.SampleIDs[0]
+
+	broker, messages := newBroker(t)
+	config.Current.MQTTBroker = broker
+	if err := mqtt.Setup(sync.PublishSampleStates); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mqtt.Close)
+	topic := "reesource/samples/" + sampleID
+	if state := wait(t, messages, topic, true); state["State"] != "unassigned" {
+		t.Errorf("state on connect: got %v, want unassigned", state)
+	}
+
+	request(t, r, token, http.MethodPost, "/api/sample/"+sampleID, url.Values{"state": {"broken"}})
+	if change := wait(t, messages, topic+"/updated", false); change["op"] != "updated" {
+		t.Errorf("change message: got %v", change)
+	}
+	if state := wait(t, messages, topic, true); state["State"] != "broken" {
+		t.Errorf("state after the update: got %v, want broken", state)
+	}
+}
@@ -0,0 +1,143 @@
+package sync
+
+import (
+	"errors"
+	"net/http"
+	"reesource-tracker/lib/activity"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EventPresence is broadcast when a client reports what its user is doing.
+const EventPresence = "presence"
+
+// Status a WebSocket client is reported with after it disconnects.
+const PresenceOffline = "offline"
+
+// MAX_PRESENCE_FIELD_LENGTH limits the session and status strings clients can send.
+const MAX_PRESENCE_FIELD_LENGTH = 64
+
+// Each user, token or anonymous client address may send PRESENCE_BURST presence updates at
+// once, and one more every PRESENCE_INTERVAL after that.
+const (
+	PRESENCE_BURST    = 10
+	PRESENCE_INTERVAL = time.Second
+)
+
+// ErrPresenceRateLimited is returned when a principal sends presence updates too quickly.
+var ErrPresenceRateLimited = errors.New("too many presence updates, slow down")
+
+// presenceAllowance is a token bucket: the updates left, as of the last update.
+type presenceAllowance struct {
+	left float64
+	at   time.Time
+}
+
+var (
+	presenceAllowances   = make(map[string]*presenceAllowance)
+	presenceAllowancesMu sync.Mutex
+)
+
+// PresenceMessage is sent by clients, over the WebSocket or to POST /api/sync/presence.
+type PresenceMessage struct {
+	// Session identifies the browser tab or device, and is chosen by the client.
+	Session string `json:"session"`
+	// Status is free-form, e.g. "online", "idle" or "editing".
+	Status string `json:"status"`
+	// Topic is optional and limits the event to subscribers of that topic, e.g. the
+	// sample being viewed as "sample:0A-1B-2C".
+	Topic string      `json:"topic,omitempty"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// PresenceEvent is the payload of presence events.
+type PresenceEvent struct {
+	PresenceMessage
+	Actor activity.Actor `json:"actor"`
+	Time  time.Time      `json:"time"`
+}
+
+func broadcastPresence(c *gin.Context, msg PresenceMessage) error {
+	if msg.Session == "" || msg.Status == "" {
+		return errors.New("session and status are required")
+	}
+	if len(msg.Session) > MAX_PRESENCE_FIELD_LENGTH || len(msg.Status) > MAX_PRESENCE_FIELD_LENGTH {
+		return errors.New("session and status must be at most 64 characters")
+	}
+	topics := []string{}
+	if msg.Topic != "" {
+		topic, err := validTopic(c, msg.Topic)
+		if err != nil {
+			return err
+		}
+		msg.Topic = topic
+		topics = append(topics, topic)
+	}
+	actor := activity.ActorOf(c)
+	// Going offline is never limited, so others don't keep seeing a closed session
+	if msg.Status != PresenceOffline && !allowPresence(presenceKey(c, actor), time.Now()) {
+		return ErrPresenceRateLimited
+	}
+	broadcastLive(EventPresence, PresenceEvent{
+		PresenceMessage: msg,
+		Actor:           actor,
+		Time:            time.Now(),
+	}, topics...)
+	return nil
+}
+
+// presenceKey identifies the principal a presence update is limited by.
+func presenceKey(c *gin.Context, actor activity.Actor) string {
+	switch {
+	case actor.TokenID != "":
+		return "token:" + actor.TokenID
+	case actor.UserID != "":
+		return "user:" + actor.UserID
+	default:
+		return "ip:" + c.ClientIP()
+	}
+}
+
+// allowPresence takes one update from key's allowance, and reports whether there was one.
+func allowPresence(key string, now time.Time) bool {
+	presenceAllowancesMu.Lock()
+	defer presenceAllowancesMu.Unlock()
+	allowance, ok := presenceAllowances[key]
+	if !ok {
+		// Forget principals whose allowance has refilled, so the map doesn't keep growing
+		for other, old := range presenceAllowances {
+			if now.Sub(old.at) >= PRESENCE_BURST*PRESENCE_INTERVAL {
+				delete(presenceAllowances, other)
+			}
+		}
+		allowance = &presenceAllowance{left: PRESENCE_BURST, at: now}
+		presenceAllowances[key] = allowance
+	}
+	allowance.left = min(PRESENCE_BURST, allowance.left+float64(now.Sub(allowance.at))/float64(PRESENCE_INTERVAL))
+	allowance.at = now
+	if allowance.left < 1 {
+		return false
+	}
+	allowance.left--
+	return true
+}
+
+// POST /sync/presence lets EventSource clients send presence updates.
+func postPresence(c *gin.Context) {
+	var msg PresenceMessage
+	if err := c.ShouldBindJSON(&msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := broadcastPresence(c, msg); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrPresenceRateLimited) {
+			status = http.StatusTooManyRequests
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
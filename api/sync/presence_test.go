@@ -0,0 +1,77 @@
+package sync
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllowPresence(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name  string
+		after []time.Duration // when each update is sent, from start
+		want  int             // how many are allowed
+	}{
+		{name: "burst", after: repeat(0, PRESENCE_BURST), want: PRESENCE_BURST},
+		{name: "over the burst", after: repeat(0, PRESENCE_BURST+5), want: PRESENCE_BURST},
+		{name: "refills", after: append(repeat(0, PRESENCE_BURST+1), PRESENCE_INTERVAL), want: PRESENCE_BURST + 1},
+		{name: "half refilled", after: append(repeat(0, PRESENCE_BURST), PRESENCE_INTERVAL/2), want: PRESENCE_BURST},
+		{name: "steady rate", after: steady(3*PRESENCE_BURST, PRESENCE_INTERVAL), want: 3 * PRESENCE_BURST},
+		{name: "burst again after idling", after: append(repeat(0, PRESENCE_BURST), repeat(time.Hour, PRESENCE_BURST+1)...), want: 2 * PRESENCE_BURST},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := "test:" + tt.name
+			t.Cleanup(func() {
+				presenceAllowancesMu.Lock()
+				delete(presenceAllowances, key)
+				presenceAllowancesMu.Unlock()
+			})
+			allowed := 0
+			for _, after := range tt.after {
+				if allowPresence(key, start.Add(after)) {
+					allowed++
+				}
+			}
+			if allowed != tt.want {
+				t.Errorf("allowed %d of %d updates, want %d", allowed, len(tt.after), tt.want)
+			}
+		})
+	}
+}
+
+// Limits are per principal, so one busy client doesn't hold up another.
+func TestAllowPresenceSeparateKeys(t *testing.T) {
+	t.Cleanup(func() {
+		presenceAllowancesMu.Lock()
+		delete(presenceAllowances, "test:busy")
+		delete(presenceAllowances, "test:quiet")
+		presenceAllowancesMu.Unlock()
+	})
+	now := time.Now()
+	for range PRESENCE_BURST {
+		allowPresence("test:busy", now)
+	}
+	if allowPresence("test:busy", now) {
+		t.Error("busy client allowed past its burst")
+	}
+	if !allowPresence("test:quiet", now) {
+		t.Error("quiet client limited by the busy one")
+	}
+}
+
+func repeat(after time.Duration, n int) []time.Duration {
+	times := make([]time.Duration, n)
+	for i := range times {
+		times[i] = after
+	}
+	return times
+}
+
+func steady(n int, interval time.Duration) []time.Duration {
+	times := make([]time.Duration, n)
+	for i := range times {
+		times[i] = time.Duration(i) * interval
+	}
+	return times
+}
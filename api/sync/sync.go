@@ -1,75 +1,368 @@
 package sync
 
 import (
+	"context"
 	"io"
+	"log/slog"
+	"net/http"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/database"
+	"reesource-tracker/lib/metrics"
+	"reesource-tracker/lib/webhooks"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type Event struct {
-	Type string      `json:"type"`
-	Data interface{} `json:"data"`
+	ID     uint64      `json:"id"`
+	Type   string      `json:"type"`
+	Data   interface{} `json:"data"`
+	Topics []string    `json:"-"`
+}
+
+// EVENT_BUFFER_SIZE is how many past events are kept for clients that reconnect.
+const EVENT_BUFFER_SIZE = 1024
+
+// MAX_LIVE_EVENTS is how many live events, which aren't buffered, are queued for a client
+// that isn't reading them. Older ones are dropped.
+const MAX_LIVE_EVENTS = 64
+
+const (
+	EventInfo           = "info"
+	EventResyncRequired = "resync_required"
+	// EventShutdown is the last event on a stream closed because the server is stopping.
+	EventShutdown = "shutdown"
+)
+
+// RECONNECT_DELAY is how long clients are asked to wait before reconnecting after a
+// shutdown event, giving the server time to restart.
+const RECONNECT_DELAY = 5 * time.Second
+
+// syncClient is woken up whenever there are new events; it then reads them from the
+// buffer from its own cursor, so a slow client falls behind rather than losing events.
+type syncClient struct {
+	id     string
+	wake   chan struct{}
+	topics map[string]bool // guarded by syncClientsMu
+	live   []Event         // live events not sent yet, guarded by syncClientsMu
+}
+
+func (client *syncClient) wants(evt Event) bool {
+	if client.topics[GLOBAL_TOPIC] {
+		return true
+	}
+	for _, topic := range evt.Topics {
+		if client.topics[topic] {
+			return true
+		}
+	}
+	return false
 }
 
 var (
-	syncClients   = make(map[string]map[string]chan Event)
+	syncClients   = make(map[string]map[string]*syncClient)
 	syncClientsMu sync.Mutex
+
+	// Event IDs start from the server start time so they keep increasing across restarts,
+	// which makes IDs from a previous run show up as too old rather than being replayed.
+	firstEventID = uint64(time.Now().UnixMicro())
+	lastEventID  = firstEventID
+	eventBuffer  [EVENT_BUFFER_SIZE]Event
+
+	shuttingDown = make(chan struct{})
+	shutdownOnce sync.Once
 )
 
-func BroadcastEvent(evtType string, data interface{}) {
+// Shutdown ends every sync stream with a shutdown event, after sending the events the client
+// hasn't received yet. Streams opened afterwards are ended straight away.
+func Shutdown() {
+	shutdownOnce.Do(func() { close(shuttingDown) })
+}
+
+// BroadcastEvent sends an event to global subscribers and to those subscribed to any of its topics.
+// Events are also mirrored to MQTT, and their webhook deliveries are stored before it returns.
+func BroadcastEvent(evtType string, data interface{}, topics ...string) {
+	id := publish(evtType, data, topics)
+	metrics.SyncEvents.WithLabelValues(evtType).Inc()
+	publishMQTT(evtType, data)
+	if err := webhooks.Store(context.Background(), database.Connection, id, evtType, data); err != nil {
+		slog.Error("Failed to store webhook deliveries", "event", evtType, "error", err)
+	}
+}
+
+// broadcastLive sends an event only to the clients connected now. It isn't buffered, so
+// frequent events like presence can't push changes out before clients have resumed.
+func broadcastLive(evtType string, data interface{}, topics ...string) {
+	publishLive(evtType, data, topics)
+	metrics.SyncEvents.WithLabelValues(evtType).Inc()
+	publishMQTT(evtType, data)
+}
+
+// publish adds an event to the buffer, wakes its subscribers and returns its ID.
+func publish(evtType string, data interface{}, topics []string) uint64 {
 	syncClientsMu.Lock()
 	defer syncClientsMu.Unlock()
-	for _, clientMap := range syncClients {
-		for _, ch := range clientMap {
-			select {
-			case ch <- Event{Type: evtType, Data: data}:
-			default:
-				// drop if client is slow
-			}
+	lastEventID++
+	eventBuffer[lastEventID%EVENT_BUFFER_SIZE] = Event{ID: lastEventID, Type: evtType, Data: data, Topics: topics}
+	for _, client := range subscribers(topics) {
+		client.notify()
+	}
+	return lastEventID
+}
+
+// publishLive queues an event without an ID for its current subscribers.
+func publishLive(evtType string, data interface{}, topics []string) {
+	syncClientsMu.Lock()
+	defer syncClientsMu.Unlock()
+	evt := Event{Type: evtType, Data: data, Topics: topics}
+	for _, client := range subscribers(topics) {
+		client.live = append(client.live, evt)
+		if len(client.live) > MAX_LIVE_EVENTS {
+			client.live = client.live[len(client.live)-MAX_LIVE_EVENTS:]
+		}
+		client.notify()
+	}
+}
+
+// subscribers returns the clients subscribed to any of the topics, or globally, once each.
+// Callers must hold syncClientsMu.
+func subscribers(topics []string) map[string]*syncClient {
+	found := make(map[string]*syncClient)
+	for _, key := range append([]string{GLOBAL_TOPIC}, topics...) {
+		for id, client := range syncClients[key] {
+			found[id] = client
+		}
+	}
+	return found
+}
+
+func (client *syncClient) notify() {
+	select {
+	case client.wake <- struct{}{}:
+	default:
+		// already has a pending wake-up
+	}
+}
+
+// eventsSince returns the buffered events after the given ID. ok is false if some of those
+// events are no longer buffered, or the ID is unknown, and the client has to resync.
+// Callers must hold syncClientsMu.
+func eventsSince(id uint64) (events []Event, latest uint64, ok bool) {
+	oldest := firstEventID + 1
+	if lastEventID >= EVENT_BUFFER_SIZE && lastEventID-EVENT_BUFFER_SIZE+1 > oldest {
+		oldest = lastEventID - EVENT_BUFFER_SIZE + 1
+	}
+	if id > lastEventID || id+1 < oldest {
+		return nil, lastEventID, false
+	}
+	for next := id + 1; next <= lastEventID; next++ {
+		events = append(events, eventBuffer[next%EVENT_BUFFER_SIZE])
+	}
+	return events, lastEventID, true
+}
+
+// connect registers a new client for the given topics, or for every event if topics is
+// nil, and returns the ID of the last event it has seen.
+func connect(c *gin.Context, topics []string) (*syncClient, uint64) {
+	client := &syncClient{
+		id:     c.ClientIP() + uuid.New().String(),
+		wake:   make(chan struct{}, 1),
+		topics: make(map[string]bool),
+	}
+	if topics == nil {
+		topics = []string{GLOBAL_TOPIC}
+	}
+	cursor, resuming := resumeFrom(c)
+
+	syncClientsMu.Lock()
+	defer syncClientsMu.Unlock()
+	client.addTopics(topics)
+	if !resuming {
+		cursor = lastEventID
+	}
+	// Replay anything missed while reconnecting
+	client.wake <- struct{}{}
+	return client, cursor
+}
+
+// disconnect removes the client from every topic it is subscribed to.
+func (client *syncClient) disconnect() {
+	syncClientsMu.Lock()
+	defer syncClientsMu.Unlock()
+	client.removeTopics(nil)
+}
+
+// subscribe adds topics to the client's subscription.
+func (client *syncClient) subscribe(topics []string) {
+	syncClientsMu.Lock()
+	defer syncClientsMu.Unlock()
+	client.addTopics(topics)
+}
+
+// unsubscribe removes topics from the client's subscription. Removing GLOBAL_TOPIC leaves
+// only the other topics; removing everything stops all events until the next subscribe.
+func (client *syncClient) unsubscribe(topics []string) {
+	syncClientsMu.Lock()
+	defer syncClientsMu.Unlock()
+	client.removeTopics(topics)
+}
+
+func (client *syncClient) addTopics(topics []string) {
+	for _, topic := range topics {
+		if _, ok := syncClients[topic]; !ok {
+			syncClients[topic] = make(map[string]*syncClient)
 		}
+		syncClients[topic][client.id] = client
+		client.topics[topic] = true
 	}
 }
 
+// removeTopics removes the given topics, or all of them if topics is nil.
+func (client *syncClient) removeTopics(topics []string) {
+	if topics == nil {
+		for topic := range client.topics {
+			topics = append(topics, topic)
+		}
+	}
+	for _, topic := range topics {
+		delete(client.topics, topic)
+		delete(syncClients[topic], client.id)
+		if len(syncClients[topic]) == 0 {
+			delete(syncClients, topic)
+		}
+	}
+}
+
+// subscribed returns the client's current subscription.
+func (client *syncClient) subscribed() []string {
+	syncClientsMu.Lock()
+	defer syncClientsMu.Unlock()
+	topics := make([]string, 0, len(client.topics))
+	for topic := range client.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// pending returns the events after cursor that the client is subscribed to, followed by
+// its live events, and the new cursor. If events were missed, it returns a single
+// resync_required event in place of the buffered ones.
+func (client *syncClient) pending(cursor uint64) ([]Event, uint64) {
+	syncClientsMu.Lock()
+	defer syncClientsMu.Unlock()
+	live := client.live
+	client.live = nil
+	events, latest, ok := eventsSince(cursor)
+	if !ok {
+		metrics.SyncResyncs.Inc()
+		// IDs from before this server started, or garbage, weren't dropped by us
+		if cursor >= firstEventID && cursor < latest {
+			metrics.SyncDroppedEvents.Add(float64(latest - cursor))
+		}
+		return append([]Event{{
+			ID:   latest,
+			Type: EventResyncRequired,
+			Data: gin.H{"reason": "missed events are no longer available", "last_event_id": latest},
+		}}, live...), latest
+	}
+	wanted := []Event{}
+	for _, evt := range events {
+		if client.wants(evt) {
+			wanted = append(wanted, evt)
+		}
+	}
+	return append(wanted, live...), latest
+}
+
+// final returns the client's remaining events followed by the shutdown event, which tells
+// the client where to resume from once the server is back.
+func (client *syncClient) final(cursor uint64) []Event {
+	events, cursor := client.pending(cursor)
+	return append(events, Event{
+		Type: EventShutdown,
+		Data: gin.H{
+			"reason":        "server is shutting down",
+			"last_event_id": cursor,
+			"retry_ms":      RECONNECT_DELAY.Milliseconds(),
+		},
+	})
+}
+
+// sseEvent renders an event for the event stream. Events without an ID, like shutdown,
+// leave the client's Last-Event-ID unchanged.
+func sseEvent(evt Event) sse.Event {
+	rendered := sse.Event{Event: evt.Type, Data: evt.Data}
+	if evt.ID != 0 {
+		rendered.Id = strconv.FormatUint(evt.ID, 10)
+	}
+	if evt.Type == EventShutdown {
+		rendered.Retry = uint(RECONNECT_DELAY.Milliseconds())
+	}
+	return rendered
+}
+
+// resumeFrom reads the ID of the last event the client saw, sent by EventSource as the
+// Last-Event-ID header when it reconnects, or as ?last_event_id for manual reconnects.
+func resumeFrom(c *gin.Context) (uint64, bool) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		// Treat garbage like an ID we don't know, so the client resyncs
+		return 0, true
+	}
+	return id, true
+}
+
 func EventStream(c *gin.Context) {
+	topics, err := subscriptionTopics(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
 	c.Header("Transfer-Encoding", "chunked")
 	c.Header("X-Accel-Buffering", "no")
 
-	// For this app, we use a single global channel key
-	model_id := "global"
-	clientID := c.ClientIP() + uuid.New().String()
-	eventChan := make(chan Event, 10)
-
-	syncClientsMu.Lock()
-	if _, ok := syncClients[model_id]; !ok {
-		syncClients[model_id] = make(map[string]chan Event)
-	}
-	syncClients[model_id][clientID] = eventChan
-	syncClientsMu.Unlock()
+	client, cursor := connect(c, topics)
+	defer client.disconnect()
+	metrics.SyncClients.WithLabelValues("sse").Inc()
+	defer metrics.SyncClients.WithLabelValues("sse").Dec()
 
-	defer func() {
-		syncClientsMu.Lock()
-		delete(syncClients[model_id], clientID)
-		if len(syncClients[model_id]) == 0 {
-			delete(syncClients, model_id)
-		}
-		syncClientsMu.Unlock()
-		close(eventChan)
-	}()
-
-	c.SSEvent("info", "Connected")
+	c.SSEvent(EventInfo, "Connected")
 	c.Writer.Flush()
 
 	c.Stream(func(w io.Writer) bool {
 		select {
-		case evt := <-eventChan:
-			c.SSEvent(evt.Type, evt.Data)
+		case <-client.wake:
+			var events []Event
+			events, cursor = client.pending(cursor)
+			for _, evt := range events {
+				c.Render(-1, sseEvent(evt))
+			}
 			c.Writer.Flush()
 			return true
+		case <-shuttingDown:
+			for _, evt := range client.final(cursor) {
+				c.Render(-1, sseEvent(evt))
+			}
+			c.Writer.Flush()
+			return false
 		case <-c.Request.Context().Done():
 			return false
 		}
@@ -78,5 +371,7 @@ func EventStream(c *gin.Context) {
 
 // RegisterSyncRoutes adds the /eventstream endpoint to the router group
 func Routes(route *gin.RouterGroup) {
-	route.GET("/sync", EventStream)
+	route.GET("/sync", auth.Require(auth.PermSamplesRead), EventStream)
+	route.GET("/sync/ws", auth.Require(auth.PermSamplesRead), WebSocket)
+	route.POST("/sync/presence", auth.Require(auth.PermSamplesRead), postPresence)
 }
@@ -0,0 +1,123 @@
+package sync
+
+import "testing"
+
+// resetBuffer empties the event buffer for a test and restores it afterwards.
+func resetBuffer(t *testing.T) {
+	t.Helper()
+	syncClientsMu.Lock()
+	saved, savedFirst, savedLast := eventBuffer, firstEventID, lastEventID
+	eventBuffer = [EVENT_BUFFER_SIZE]Event{}
+	firstEventID, lastEventID = 1000, 1000
+	syncClientsMu.Unlock()
+	t.Cleanup(func() {
+		syncClientsMu.Lock()
+		eventBuffer, firstEventID, lastEventID = saved, savedFirst, savedLast
+		syncClientsMu.Unlock()
+	})
+}
+
+func TestEventsSince(t *testing.T) {
+	const first = 1000
+	tests := []struct {
+		name      string
+		published int
+		cursor    uint64
+		want      int // number of events returned
+		ok        bool
+	}{
+		{name: "up to date", published: 5, cursor: first + 5, want: 0, ok: true},
+		{name: "from the start", published: 5, cursor: first, want: 5, ok: true},
+		{name: "part way", published: 5, cursor: first + 3, want: 2, ok: true},
+		{name: "future cursor", published: 5, cursor: first + 6, ok: false},
+		{name: "previous run", published: 5, cursor: first - 1, ok: false},
+		{name: "full buffer", published: EVENT_BUFFER_SIZE, cursor: first, want: EVENT_BUFFER_SIZE, ok: true},
+		{name: "wrapped, oldest kept", published: EVENT_BUFFER_SIZE + 10, cursor: first + 10, want: EVENT_BUFFER_SIZE, ok: true},
+		{name: "wrapped, newest", published: EVENT_BUFFER_SIZE + 10, cursor: first + EVENT_BUFFER_SIZE + 5, want: 5, ok: true},
+		{name: "wrapped, evicted", published: EVENT_BUFFER_SIZE + 10, cursor: first + 9, ok: false},
+		{name: "wrapped, from the start", published: EVENT_BUFFER_SIZE + 10, cursor: first, ok: false},
+		{name: "wrapped twice", published: 3*EVENT_BUFFER_SIZE + 1, cursor: first + 2*EVENT_BUFFER_SIZE + 1, want: EVENT_BUFFER_SIZE, ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetBuffer(t)
+			for i := 0; i < tt.published; i++ {
+				publish("test", i, nil)
+			}
+			syncClientsMu.Lock()
+			events, latest, ok := eventsSince(tt.cursor)
+			syncClientsMu.Unlock()
+			if ok != tt.ok || latest != first+uint64(tt.published) {
+				t.Fatalf("eventsSince(%d): got ok %v, latest %d, want ok %v, latest %d", tt.cursor, ok, latest, tt.ok, first+tt.published)
+			}
+			if len(events) != tt.want {
+				t.Fatalf("eventsSince(%d): got %d events, want %d", tt.cursor, len(events), tt.want)
+			}
+			// The replay is in order and carries the events' own IDs and data
+			for i, evt := range events {
+				if id := tt.cursor + uint64(i) + 1; evt.ID != id || evt.Data != int(id-first-1) {
+					t.Fatalf("event %d: got ID %d data %v, want ID %d", i, evt.ID, evt.Data, id)
+				}
+			}
+		})
+	}
+}
+
+// A client resuming across an eviction gets a single resync_required event pointing at the
+// latest event, and then carries on from there.
+func TestResumeAcrossEviction(t *testing.T) {
+	resetBuffer(t)
+	client := &syncClient{topics: map[string]bool{GLOBAL_TOPIC: true}}
+	cursor := lastEventID
+	publish("test", nil, nil)
+	for i := 0; i < EVENT_BUFFER_SIZE; i++ {
+		publish("test", nil, nil)
+	}
+
+	events, next := client.pending(cursor)
+	if len(events) != 1 || events[0].Type != EventResyncRequired || events[0].ID != lastEventID || next != lastEventID {
+		t.Fatalf("after an eviction: got %+v, cursor %d, want resync_required at %d", events, next, lastEventID)
+	}
+	publish("test", "after", nil)
+	events, next = client.pending(next)
+	if len(events) != 1 || events[0].Data != "after" || next != lastEventID {
+		t.Errorf("after resyncing: got %+v, cursor %d", events, next)
+	}
+}
+
+// Presence goes to the clients connected now, and doesn't take the place of buffered events.
+func TestLiveEventsNotBuffered(t *testing.T) {
+	resetBuffer(t)
+	viewer := &syncClient{id: "viewer", wake: make(chan struct{}, 1), topics: map[string]bool{}}
+	viewer.subscribe([]string{"sample:0A-1B-2C"})
+	t.Cleanup(viewer.disconnect)
+	other := &syncClient{id: "other", wake: make(chan struct{}, 1), topics: map[string]bool{}}
+	other.subscribe([]string{"sample:01-02-03"})
+	t.Cleanup(other.disconnect)
+
+	cursor := lastEventID
+	publish("test", "change", nil)
+	for i := 0; i < 2*EVENT_BUFFER_SIZE; i++ {
+		publishLive(EventPresence, i, []string{"sample:0A-1B-2C"})
+	}
+	if lastEventID != cursor+1 {
+		t.Fatalf("live events used event IDs: last ID %d, want %d", lastEventID, cursor+1)
+	}
+	syncClientsMu.Lock()
+	events, _, ok := eventsSince(cursor)
+	syncClientsMu.Unlock()
+	if !ok || len(events) != 1 || events[0].Data != "change" {
+		t.Fatalf("resuming after presence: got %+v, %v, want the change", events, ok)
+	}
+
+	events, _ = viewer.pending(lastEventID)
+	if len(events) != MAX_LIVE_EVENTS || events[0].ID != 0 || events[len(events)-1].Data != 2*EVENT_BUFFER_SIZE-1 {
+		t.Errorf("viewer: got %d live events, want the last %d", len(events), MAX_LIVE_EVENTS)
+	}
+	if events, _ = viewer.pending(lastEventID); len(events) != 0 {
+		t.Errorf("viewer: got %d live events again", len(events))
+	}
+	if events, _ = other.pending(lastEventID); len(events) != 0 {
+		t.Errorf("other topic: got %d live events, want none", len(events))
+	}
+}
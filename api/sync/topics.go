@@ -0,0 +1,161 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	sampleid "reesource-tracker/lib/sample_id"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Clients that don't ask for any topics receive every event.
+const GLOBAL_TOPIC = "global"
+
+// Topic prefixes. Events are tagged with every topic they are relevant to, and clients
+// subscribe with the matching query parameter on /api/sync.
+const (
+	TopicSample   = "sample:"   // ?sample=0A-1B-2C
+	TopicLocation = "location:" // ?location=<uuid>, includes everything below it
+	TopicProduct  = "product:"  // ?product=<uuid>, includes child products
+	TopicOwner    = "owner:"    // ?owner=<uuid> or ?mine=true
+)
+
+// MAX_TREE_DEPTH guards the parent walk against cycles in the location and product trees.
+const MAX_TREE_DEPTH = 32
+
+// TopicRequest is a set of topics as sent by clients, either as /api/sync query
+// parameters or in WebSocket subscribe messages.
+type TopicRequest struct {
+	Sample   []string `form:"sample" json:"sample"`
+	Location []string `form:"location" json:"location"`
+	Product  []string `form:"product" json:"product"`
+	Owner    []string `form:"owner" json:"owner"`
+	Mine     bool     `form:"mine" json:"mine"`
+	// All selects every event, which is the default when no other topics are given.
+	All bool `form:"all" json:"all"`
+}
+
+// Topics validates the request and returns the topic keys it selects.
+func (req TopicRequest) Topics(c *gin.Context) ([]string, error) {
+	topics := []string{}
+	if req.All {
+		topics = append(topics, GLOBAL_TOPIC)
+	}
+	for _, value := range req.Sample {
+		raw, err := sampleid.ParseSampleID(value)
+		if err != nil {
+			return nil, errors.New("Invalid sample ID format")
+		}
+		formatted, _ := sampleid.FormatSampleID(raw[:])
+		topics = append(topics, TopicSample+formatted)
+	}
+	for prefix, values := range map[string][]string{TopicLocation: req.Location, TopicProduct: req.Product, TopicOwner: req.Owner} {
+		for _, value := range values {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				return nil, errors.New("Invalid UUID format")
+			}
+			topics = append(topics, prefix+id.String())
+		}
+	}
+	if req.Mine {
+		p, ok := auth.CurrentPrincipal(c)
+		if !ok || p.Anonymous {
+			return nil, errors.New("mine=true requires a logged in user")
+		}
+		topics = append(topics, TopicOwner+p.UserID.String())
+	}
+	return topics, nil
+}
+
+// subscriptionTopics reads the topics a client asked for in the query string. It returns
+// nil for a global subscription.
+func subscriptionTopics(c *gin.Context) ([]string, error) {
+	var req TopicRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		return nil, err
+	}
+	topics, err := req.Topics(c)
+	if err != nil || len(topics) == 0 {
+		return nil, err
+	}
+	return topics, nil
+}
+
+// validTopic checks a single topic key, such as "sample:0A-1B-2C", and returns it in
+// canonical form.
+func validTopic(c *gin.Context, topic string) (string, error) {
+	if topic == GLOBAL_TOPIC {
+		return topic, nil
+	}
+	prefix, value, _ := strings.Cut(topic, ":")
+	var req TopicRequest
+	switch prefix + ":" {
+	case TopicSample:
+		req.Sample = []string{value}
+	case TopicLocation:
+		req.Location = []string{value}
+	case TopicProduct:
+		req.Product = []string{value}
+	case TopicOwner:
+		req.Owner = []string{value}
+	default:
+		return "", errors.New("Unknown topic")
+	}
+	topics, err := req.Topics(c)
+	if err != nil {
+		return "", err
+	}
+	return topics[0], nil
+}
+
+// SampleTopics lists the topics for a sample with the given location, product and owner.
+func SampleTopics(ctx context.Context, displayID string, locationID interface{}, productID interface{}, ownerID interface{}) []string {
+	topics := []string{TopicSample + displayID}
+	topics = append(topics, LocationTopics(ctx, locationID)...)
+	topics = append(topics, ProductTopics(ctx, productID)...)
+	if owner := id_helper.BlobToString(ownerID); owner != "" {
+		topics = append(topics, TopicOwner+owner)
+	}
+	return topics
+}
+
+// LocationTopics lists the topics for a location and every location above it.
+func LocationTopics(ctx context.Context, id interface{}) []string {
+	return treeTopics(TopicLocation, id, func(id interface{}) (interface{}, error) {
+		location, err := database.Connection.GetLocation(ctx, id)
+		return location.ParentLocationID, err
+	})
+}
+
+// ProductTopics lists the topics for a product and every product above it.
+func ProductTopics(ctx context.Context, id interface{}) []string {
+	return treeTopics(TopicProduct, id, func(id interface{}) (interface{}, error) {
+		product, err := database.Connection.GetProductByID(ctx, id)
+		return product.ParentProductID, err
+	})
+}
+
+func treeTopics(prefix string, id interface{}, parentOf func(interface{}) (interface{}, error)) []string {
+	topics := []string{}
+	seen := map[string]bool{}
+	for len(topics) < MAX_TREE_DEPTH {
+		key := id_helper.BlobToString(id)
+		if key == "" || seen[key] {
+			break
+		}
+		seen[key] = true
+		topics = append(topics, prefix+key)
+		parent, err := parentOf(id)
+		if err != nil {
+			break
+		}
+		id = parent
+	}
+	return topics
+}
@@ -0,0 +1,187 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"reesource-tracker/lib/auth"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestTopicRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	user := uuid.New()
+	location := uuid.New()
+	tests := []struct {
+		name      string
+		req       TopicRequest
+		principal *auth.Principal
+		want      []string
+		err       string
+	}{
+		{name: "nothing", req: TopicRequest{}, want: []string{}},
+		{name: "all", req: TopicRequest{All: true}, want: []string{GLOBAL_TOPIC}},
+		{name: "sample is canonicalised", req: TopicRequest{Sample: []string{"0a-1b-2c"}}, want: []string{"sample:0A-1B-2C"}},
+		{name: "location", req: TopicRequest{Location: []string{location.String()}}, want: []string{TopicLocation + location.String()}},
+		{name: "uppercase UUID", req: TopicRequest{Owner: []string{"3F0C" + user.String()[4:]}}, want: []string{TopicOwner + "3f0c" + user.String()[4:]}},
+		{name: "mine", req: TopicRequest{Mine: true}, principal: &auth.Principal{UserID: user}, want: []string{TopicOwner + user.String()}},
+		{name: "mine anonymous", req: TopicRequest{Mine: true}, principal: &auth.Principal{Anonymous: true}, err: "mine=true requires a logged in user"},
+		{name: "bad sample", req: TopicRequest{Sample: []string{"nope"}}, err: "Invalid sample ID format"},
+		{name: "bad UUID", req: TopicRequest{Product: []string{"nope"}}, err: "Invalid UUID format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			if tt.principal != nil {
+				auth.SetPrincipal(c, tt.principal)
+			}
+			got, err := tt.req.Topics(c)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("got %v, %v, want error %q", got, err, tt.err)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidTopic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	id := uuid.New()
+	tests := []struct {
+		topic string
+		want  string
+		ok    bool
+	}{
+		{topic: GLOBAL_TOPIC, want: GLOBAL_TOPIC, ok: true},
+		{topic: "sample:0a-1b-2c", want: "sample:0A-1B-2C", ok: true},
+		{topic: "location:" + id.String(), want: "location:" + id.String(), ok: true},
+		{topic: "product:" + id.String(), want: "product:" + id.String(), ok: true},
+		{topic: "owner:" + id.String(), want: "owner:" + id.String(), ok: true},
+		{topic: "owner:nope"},
+		{topic: "sample:"},
+		{topic: "shelf:" + id.String()},
+		{topic: "sample"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.topic, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			got, err := validTopic(c, tt.topic)
+			if (err == nil) != tt.ok || got != tt.want {
+				t.Errorf("validTopic(%q): got %q, %v, want %q", tt.topic, got, err, tt.want)
+			}
+		})
+	}
+}
+
+// Events reach global subscribers and the subscribers of any one of their topics, and
+// nobody else.
+func TestRouting(t *testing.T) {
+	resetBuffer(t)
+	clients := map[string][]string{
+		"global":   {GLOBAL_TOPIC},
+		"sample":   {"sample:0A-1B-2C"},
+		"location": {"location:lab"},
+		"both":     {"sample:01-02-03", "owner:grace"},
+		"none":     {},
+	}
+	tests := []struct {
+		name   string
+		topics []string
+		want   []string
+	}{
+		{name: "untagged", topics: nil, want: []string{"global"}},
+		{name: "one sample", topics: []string{"sample:0A-1B-2C"}, want: []string{"global", "sample"}},
+		{name: "sample in the lab", topics: []string{"sample:0A-1B-2C", "location:shelf", "location:lab"}, want: []string{"global", "location", "sample"}},
+		{name: "owner", topics: []string{"owner:grace"}, want: []string{"both", "global"}},
+		{name: "unrelated", topics: []string{"sample:FF-FF-FF"}, want: []string{"global"}},
+	}
+
+	registered := map[string]*syncClient{}
+	for name, topics := range clients {
+		client := &syncClient{id: name, wake: make(chan struct{}, 1), topics: map[string]bool{}}
+		client.subscribe(topics)
+		t.Cleanup(client.disconnect)
+		registered[name] = client
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := lastEventID
+			publish("test", tt.name, tt.topics)
+			got := []string{}
+			for name, client := range registered {
+				woken := false
+				select {
+				case <-client.wake:
+					woken = true
+				default:
+				}
+				events, _ := client.pending(cursor)
+				if woken != (len(events) == 1) {
+					t.Errorf("%s: woken %v but got %d events", name, woken, len(events))
+				}
+				if len(events) == 1 {
+					got = append(got, name)
+				}
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("delivered to %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTreeTopics(t *testing.T) {
+	tests := []struct {
+		name    string
+		parents map[string]string // child to parent; missing means the lookup fails
+		want    int
+	}{
+		{name: "root", parents: map[string]string{"0": ""}, want: 1},
+		{name: "chain", parents: map[string]string{"0": "1", "1": "2", "2": ""}, want: 3},
+		{name: "missing parent", parents: map[string]string{"0": "1"}, want: 2},
+		{name: "cycle", parents: map[string]string{"0": "1", "1": "2", "2": "0"}, want: 3},
+		{name: "too deep", parents: deepTree(MAX_TREE_DEPTH + 5), want: MAX_TREE_DEPTH},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := treeTopics(TopicLocation, blob("0"), func(id interface{}) (interface{}, error) {
+				for child, parent := range tt.parents {
+					if slices.Equal(id.([]byte), blob(child)) {
+						if parent == "" {
+							return nil, nil
+						}
+						return blob(parent), nil
+					}
+				}
+				return nil, errors.New("not found")
+			})
+			if len(got) != tt.want || got[0] != TopicLocation+uuid.UUID(blob("0")).String() {
+				t.Errorf("got %v, want %d topics starting with the location itself", got, tt.want)
+			}
+		})
+	}
+}
+
+// blob returns a stable UUID blob for a short name.
+func blob(name string) []byte {
+	id := uuid.NewSHA1(uuid.Nil, []byte(name))
+	return id[:]
+}
+
+func deepTree(depth int) map[string]string {
+	parents := map[string]string{}
+	for i := 0; i < depth; i++ {
+		parents[fmt.Sprint(i)] = fmt.Sprint(i + 1)
+	}
+	return parents
+}
@@ -0,0 +1,192 @@
+package sync
+
+import (
+	"encoding/json"
+	"net/http"
+	"reesource-tracker/lib/metrics"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// PING_INTERVAL is how often the server sends WebSocket pings.
+	PING_INTERVAL = 30 * time.Second
+	// PONG_WAIT is how long the server waits for any message or pong before giving up.
+	PONG_WAIT = 2 * PING_INTERVAL
+	// WRITE_WAIT is how long a single write may take.
+	WRITE_WAIT = 10 * time.Second
+	// MAX_MESSAGE_SIZE limits messages sent by clients.
+	MAX_MESSAGE_SIZE = 64 * 1024
+)
+
+// Messages clients can send over the WebSocket.
+const (
+	MessageSubscribe   = "subscribe"
+	MessageUnsubscribe = "unsubscribe"
+	MessagePing        = "ping"
+	MessagePresence    = "presence"
+)
+
+// Replies sent by the server, alongside the usual events.
+const (
+	EventSubscribed = "subscribed"
+	EventPong       = "pong"
+	EventError      = "error"
+)
+
+// wsMessage is a message from the client. Subscribe messages embed the same fields as the
+// /api/sync query parameters, presence messages use Presence.
+type wsMessage struct {
+	Type string `json:"type"`
+	TopicRequest
+	Presence PresenceMessage `json:"presence"`
+}
+
+// wsEvent is a message to the client. ID is only set for events that can be resumed from.
+type wsEvent struct {
+	ID    uint64      `json:"id,omitempty"`
+	Event string      `json:"event"`
+	Data  interface{} `json:"data"`
+}
+
+// Browsers can't set headers on WebSocket requests, so cookies are the only credentials they
+// send; the default origin check keeps other sites from using them.
+var upgrader = websocket.Upgrader{}
+
+// GET /sync/ws carries the same events as /api/sync over a WebSocket, for networks whose
+// proxies buffer event streams. It accepts the same query parameters.
+func WebSocket(c *gin.Context) {
+	topics, err := subscriptionTopics(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade has already written an error response
+		return
+	}
+
+	client, cursor := connect(c, topics)
+	defer client.disconnect()
+	metrics.SyncClients.WithLabelValues("websocket").Inc()
+	defer metrics.SyncClients.WithLabelValues("websocket").Dec()
+
+	replies := make(chan wsEvent, 16)
+	closed := make(chan struct{})
+	go readWebSocket(c, conn, client, replies, closed)
+	defer func() {
+		// The reader still uses c, so wait for it before gin reuses the context
+		conn.Close()
+		<-closed
+	}()
+
+	ticker := time.NewTicker(PING_INTERVAL)
+	defer ticker.Stop()
+
+	write := func(msg wsEvent) bool {
+		conn.SetWriteDeadline(time.Now().Add(WRITE_WAIT))
+		return conn.WriteJSON(msg) == nil
+	}
+	if !write(wsEvent{Event: EventInfo, Data: "Connected"}) {
+		return
+	}
+	for {
+		select {
+		case <-client.wake:
+			var events []Event
+			events, cursor = client.pending(cursor)
+			for _, evt := range events {
+				if !write(wsEvent{ID: evt.ID, Event: evt.Type, Data: evt.Data}) {
+					return
+				}
+			}
+		case reply := <-replies:
+			if !write(reply) {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(WRITE_WAIT))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-shuttingDown:
+			for _, evt := range client.final(cursor) {
+				if !write(wsEvent{ID: evt.ID, Event: evt.Type, Data: evt.Data}) {
+					return
+				}
+			}
+			conn.SetWriteDeadline(time.Now().Add(WRITE_WAIT))
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down"))
+			return
+		case <-closed:
+			return
+		}
+	}
+}
+
+// readWebSocket handles messages from the client until the connection is closed. Replies
+// go through the replies channel, since only WebSocket writes one message at a time.
+func readWebSocket(c *gin.Context, conn *websocket.Conn, client *syncClient, replies chan<- wsEvent, closed chan<- struct{}) {
+	defer close(closed)
+
+	// The session the client last reported presence for, to mark it offline when it goes
+	var presence *PresenceMessage
+	defer func() {
+		if presence != nil {
+			broadcastPresence(c, PresenceMessage{Session: presence.Session, Status: PresenceOffline, Topic: presence.Topic})
+		}
+	}()
+
+	conn.SetReadLimit(MAX_MESSAGE_SIZE)
+	conn.SetReadDeadline(time.Now().Add(PONG_WAIT))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(PONG_WAIT))
+	})
+	reply := func(evt string, data interface{}) {
+		select {
+		case replies <- wsEvent{Event: evt, Data: data}:
+		default:
+			// The client isn't reading its replies, drop them rather than block
+		}
+	}
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(PONG_WAIT))
+
+		var msg wsMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			reply(EventError, gin.H{"error": "Invalid message"})
+			continue
+		}
+		switch msg.Type {
+		case MessagePing:
+			reply(EventPong, nil)
+		case MessageSubscribe, MessageUnsubscribe:
+			topics, err := msg.TopicRequest.Topics(c)
+			if err != nil {
+				reply(EventError, gin.H{"error": err.Error(), "type": msg.Type})
+				continue
+			}
+			if msg.Type == MessageSubscribe {
+				client.subscribe(topics)
+			} else {
+				client.unsubscribe(topics)
+			}
+			reply(EventSubscribed, gin.H{"topics": client.subscribed()})
+		case MessagePresence:
+			if err := broadcastPresence(c, msg.Presence); err != nil {
+				reply(EventError, gin.H{"error": err.Error(), "type": msg.Type})
+				continue
+			}
+			presence = &msg.Presence
+		default:
+			reply(EventError, gin.H{"error": "Unknown message type", "type": msg.Type})
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package tokens
+
+import (
+	"database/sql"
+	"net/http"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func Routes(route *gin.RouterGroup) {
+	manage := auth.RequireSelfOr(auth.PermTokensManageSelf, auth.PermUsersWrite)
+	route.GET("/user/:user_id/tokens", manage, listTokens)
+	route.POST("/user/:user_id/tokens", manage, createToken)
+	route.DELETE("/user/:user_id/tokens/:token_id", manage, revokeToken)
+}
+
+// Token is the API representation of a token. The token itself is never included.
+type Token struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func tokenFromRow(row database.ListAPITokensByUserRow) Token {
+	token := Token{
+		ID:        id_helper.BlobToString(row.ID),
+		UserID:    id_helper.BlobToString(row.UserID),
+		Name:      row.Name,
+		Scopes:    auth.SplitScopes(row.Scopes),
+		CreatedAt: row.CreatedAt,
+	}
+	if row.ExpiresAt.Valid {
+		token.ExpiresAt = &row.ExpiresAt.Time
+	}
+	if row.LastUsedAt.Valid {
+		token.LastUsedAt = &row.LastUsedAt.Time
+	}
+	if row.RevokedAt.Valid {
+		token.RevokedAt = &row.RevokedAt.Time
+	}
+	return token
+}
+
+// POST /user/:user_id/tokens
+// The plaintext token is only returned in this response; only its hash is stored.
+func createToken(c *gin.Context) {
+	userID := c.Param("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id required"})
+		return
+	}
+	var req struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token name is required"})
+		return
+	}
+	scopes, err := auth.ParseScopes(req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	now := time.Now()
+	var expiresAt sql.NullTime
+	if req.ExpiresAt != nil {
+		if req.ExpiresAt.Before(now) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+			return
+		}
+		expiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+	binary_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(userID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	if _, err := database.Connection.GetUserByID(c, binary_uuid); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	token, hash, err := auth.GenerateToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+	token_uuid := uuid.New()
+	new_uid, err := token_uuid.MarshalBinary()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token ID"})
+		return
+	}
+	err = database.Connection.CreateAPIToken(c, database.CreateAPITokenParams{
+		ID:        new_uid,
+		UserID:    binary_uuid,
+		Name:      req.Name,
+		TokenHash: hash,
+		Scopes:    scopes,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	activity.Record(c, activity.KindToken, token_uuid.String(), activity.ActionCreated, gin.H{
+		"user_id": userID,
+		"name":    req.Name,
+		"scopes":  auth.SplitScopes(scopes),
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"id":         token_uuid.String(),
+		"name":       req.Name,
+		"scopes":     auth.SplitScopes(scopes),
+		"expires_at": req.ExpiresAt,
+		"token":      token,
+	})
+}
+
+// GET /user/:user_id/tokens
+func listTokens(c *gin.Context) {
+	userID := c.Param("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id required"})
+		return
+	}
+	binary_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(userID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	rows, err := database.Connection.ListAPITokensByUser(c, binary_uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	res := make([]Token, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, tokenFromRow(row))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// DELETE /user/:user_id/tokens/:token_id
+func revokeToken(c *gin.Context) {
+	userID := c.Param("user_id")
+	tokenID := c.Param("token_id")
+	if userID == "" || tokenID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id and token_id required"})
+		return
+	}
+	user_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(userID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	token_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(tokenID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	// Revoking only matches an active token of this user, so a missing token, another
+	// user's token and an already revoked token all come back as not found.
+	revoked, err := database.Connection.RevokeAPIToken(c, database.RevokeAPITokenParams{
+		RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		ID:        token_uuid,
+		UserID:    user_uuid,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if revoked == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+	activity.Record(c, activity.KindToken, tokenID, activity.ActionRevoked, gin.H{"user_id": userID})
+	c.JSON(http.StatusOK, gin.H{"status": "revoked"})
+}
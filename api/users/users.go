@@ -1,21 +1,60 @@
 package users
 
 import (
+	"database/sql"
 	"net/http"
 	"reesource-tracker/api/sync"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/auth"
 	"reesource-tracker/lib/database"
 	id_helper "reesource-tracker/lib/id_helper"
+	"reesource-tracker/lib/notifications"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 func Routes(route *gin.RouterGroup) {
-	route.GET("/users", getUsers)
-	route.POST("/user", createUser)
-	route.GET("/user/:user_id", getUser)
-	route.POST("/user/:user_id", updateUser)
-	route.DELETE("/user/:user_id", deleteUser)
+	route.GET("/users", auth.Require(auth.PermUsersRead), getUsers)
+	route.POST("/user", auth.Require(auth.PermUsersWrite), createUser)
+	route.GET("/user/:user_id", auth.Require(auth.PermUsersRead), getUser)
+	route.POST("/user/:user_id", auth.Require(auth.PermUsersWrite), updateUser)
+	route.DELETE("/user/:user_id", auth.Require(auth.PermUsersDelete), deleteUser)
+	route.GET("/user/:user_id/activity", auth.Require(auth.PermUsersRead), getUserActivity)
+}
+
+// GET /user/:user_id/activity?limit=50
+// Lists the most recent changes made by the user, newest first.
+func getUserActivity(c *gin.Context) {
+	userID := c.Param("user_id")
+	binary_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(userID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = parsed
+	}
+	rows, err := database.Connection.ListUserActivity(c, database.ListUserActivityParams{
+		UserID: binary_uuid,
+		Limit:  int64(limit),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	entries := make([]activity.Entry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, activity.EntryFromRow(row))
+	}
+	c.JSON(http.StatusOK, entries)
 }
 
 // DELETE /user/:user_id
@@ -35,18 +74,24 @@ func deleteUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	activity.Record(c, activity.KindUser, userID, activity.ActionDeleted, nil)
 	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
-	sync.BroadcastEvent("users_updated", gin.H{})
+	broadcastUser(c, binary_uuid, sync.OpDeleted)
 }
 
 func createUser(c *gin.Context) {
 	var req struct {
-		Name string `json:"name"`
+		Name  string  `json:"name"`
+		Email *string `json:"email"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	email, ok := parseEmail(c, req.Email)
+	if !ok {
+		return
+	}
 	new_uid, err := uuid.New().MarshalBinary()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate user ID"})
@@ -61,8 +106,12 @@ func createUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
-	sync.BroadcastEvent("users_updated", gin.H{})
+	if !saveEmail(c, new_uid, req.Email, email) {
+		return
+	}
+	activity.Record(c, activity.KindUser, id_helper.BlobToString(new_uid), activity.ActionCreated, req)
+	c.JSON(http.StatusOK, gin.H{"status": "success", "id": id_helper.BlobToString(new_uid)})
+	broadcastUser(c, new_uid, sync.OpCreated)
 }
 
 func getUser(c *gin.Context) {
@@ -71,7 +120,12 @@ func getUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id required"})
 		return
 	}
-	user, err := database.Connection.GetUserByID(c, userID)
+	binary_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(userID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	user, err := database.Connection.GetUserByID(c, binary_uuid)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -86,12 +140,17 @@ func updateUser(c *gin.Context) {
 		return
 	}
 	var req struct {
-		Name string `json:"name"`
+		Name  string  `json:"name"`
+		Email *string `json:"email"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	email, ok := parseEmail(c, req.Email)
+	if !ok {
+		return
+	}
 	binary_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(userID)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
@@ -106,8 +165,51 @@ func updateUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if !saveEmail(c, binary_uuid, req.Email, email) {
+		return
+	}
+	activity.Record(c, activity.KindUser, userID, activity.ActionUpdated, req)
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
-	sync.BroadcastEvent("users_updated", gin.H{})
+	broadcastUser(c, binary_uuid, sync.OpUpdated)
+}
+
+// parseEmail validates the optional email field of a user request, writing an error
+// response and returning false if it is invalid.
+func parseEmail(c *gin.Context, email *string) (sql.NullString, bool) {
+	if email == nil {
+		return sql.NullString{}, true
+	}
+	value, err := notifications.ParseEmail(*email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return value, false
+	}
+	return value, true
+}
+
+// saveEmail stores the email from a user request, if one was given. Leaving it out of the
+// request keeps the current address.
+func saveEmail(c *gin.Context, id []byte, requested *string, email sql.NullString) bool {
+	if requested == nil {
+		return true
+	}
+	err := database.Connection.UpdateUserEmail(c, database.UpdateUserEmailParams{Email: email, ID: id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// broadcastUser sends the current state of a user to sync clients.
+func broadcastUser(c *gin.Context, id []byte, op string) {
+	var data interface{}
+	if op != sync.OpDeleted {
+		if user, err := database.Connection.GetUserByID(c, id); err == nil {
+			data = user
+		}
+	}
+	sync.BroadcastChange(c, activity.KindUser, id_helper.BlobToString(id), op, data, nil, sync.TopicOwner+id_helper.BlobToString(id))
 }
 
 func getUsers(c *gin.Context) {
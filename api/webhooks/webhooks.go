@@ -0,0 +1,378 @@
+package webhooks
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"reesource-tracker/api/sync"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	"reesource-tracker/lib/webhooks"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func Routes(route *gin.RouterGroup) {
+	manage := auth.Require(auth.PermWebhooksManage)
+	route.GET("/webhooks", manage, listWebhooks)
+	route.POST("/webhook", manage, createWebhook)
+	route.GET("/webhook/:webhook_id", manage, getWebhook)
+	route.POST("/webhook/:webhook_id", manage, updateWebhook)
+	route.DELETE("/webhook/:webhook_id", manage, deleteWebhook)
+	route.GET("/webhook/:webhook_id/deliveries", manage, listDeliveries)
+	route.GET("/webhook/:webhook_id/delivery/:delivery_id", manage, getDelivery)
+}
+
+// Webhook is the API representation of a webhook. The secret is only included when the
+// webhook is created or the secret is changed.
+type Webhook struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedBy  string    `json:"created_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	Secret     string    `json:"secret,omitempty"`
+}
+
+func webhookFromRow(row database.Webhook) Webhook {
+	return Webhook{
+		ID:         id_helper.BlobToString(row.ID),
+		URL:        row.Url,
+		EventTypes: webhooks.SplitEventTypes(row.EventTypes),
+		Active:     row.Active,
+		CreatedBy:  id_helper.BlobToString(row.CreatedBy),
+		CreatedAt:  row.CreatedAt,
+	}
+}
+
+type webhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+	Active     *bool    `json:"active"`
+}
+
+func validURL(value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// eventTypeFilter checks the requested event types and joins them for storage.
+func eventTypeFilter(eventTypes []string) (string, error) {
+	for _, eventType := range eventTypes {
+		known := eventType == sync.EventImported
+		for _, name := range sync.EventTypes {
+			known = known || name == eventType
+		}
+		if !known {
+			return "", errors.New("Unknown event type: " + eventType)
+		}
+	}
+	eventTypes = slices.Clone(eventTypes)
+	slices.Sort(eventTypes)
+	return strings.Join(slices.Compact(eventTypes), ","), nil
+}
+
+// POST /webhook
+// If no secret is given one is generated; either way it is returned in the response.
+func createWebhook(c *gin.Context) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	eventTypes, err := eventTypeFilter(req.EventTypes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Secret == "" {
+		req.Secret, err = webhooks.GenerateSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate secret"})
+			return
+		}
+	}
+	active := req.Active == nil || *req.Active
+	webhook_uuid := uuid.New()
+	new_uid, err := webhook_uuid.MarshalBinary()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate webhook ID"})
+		return
+	}
+	row := database.Webhook{
+		ID:         new_uid,
+		Url:        req.URL,
+		EventTypes: eventTypes,
+		Secret:     req.Secret,
+		Active:     active,
+		CreatedBy:  activity.ActorUserID(c),
+		CreatedAt:  time.Now(),
+	}
+	err = database.Connection.CreateWebhook(c, database.CreateWebhookParams(row))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	res := webhookFromRow(row)
+	activity.Record(c, activity.KindWebhook, res.ID, activity.ActionCreated, gin.H{
+		"url":         res.URL,
+		"event_types": res.EventTypes,
+		"active":      res.Active,
+	})
+	res.Secret = req.Secret
+	c.JSON(http.StatusOK, res)
+}
+
+// GET /webhooks
+func listWebhooks(c *gin.Context) {
+	rows, err := database.Connection.ListWebhooks(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	res := make([]Webhook, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, webhookFromRow(row))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// loadWebhook fetches the webhook named by the :webhook_id parameter, writing an error
+// response and returning false if it can't.
+func loadWebhook(c *gin.Context) (database.Webhook, bool) {
+	binary_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(c.Param("webhook_id"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return database.Webhook{}, false
+	}
+	row, err := database.Connection.GetWebhook(c, binary_uuid)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return row, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return row, false
+	}
+	return row, true
+}
+
+// GET /webhook/:webhook_id
+func getWebhook(c *gin.Context) {
+	row, ok := loadWebhook(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, webhookFromRow(row))
+}
+
+// POST /webhook/:webhook_id
+// Fields left out of the request keep their current values.
+func updateWebhook(c *gin.Context) {
+	row, ok := loadWebhook(c)
+	if !ok {
+		return
+	}
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.URL != "" {
+		if err := validURL(req.URL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		row.Url = req.URL
+	}
+	if req.EventTypes != nil {
+		eventTypes, err := eventTypeFilter(req.EventTypes)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		row.EventTypes = eventTypes
+	}
+	if req.Secret != "" {
+		row.Secret = req.Secret
+	}
+	if req.Active != nil {
+		row.Active = *req.Active
+	}
+	err := database.Connection.UpdateWebhook(c, database.UpdateWebhookParams{
+		Url:        row.Url,
+		EventTypes: row.EventTypes,
+		Secret:     row.Secret,
+		Active:     row.Active,
+		ID:         row.ID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	res := webhookFromRow(row)
+	activity.Record(c, activity.KindWebhook, res.ID, activity.ActionUpdated, gin.H{
+		"url":            res.URL,
+		"event_types":    res.EventTypes,
+		"active":         res.Active,
+		"secret_changed": req.Secret != "",
+	})
+	c.JSON(http.StatusOK, res)
+}
+
+// DELETE /webhook/:webhook_id
+// Deleting a webhook also deletes its queued deliveries and their log.
+func deleteWebhook(c *gin.Context) {
+	row, ok := loadWebhook(c)
+	if !ok {
+		return
+	}
+	if err := database.Connection.DeleteWebhook(c, row.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	activity.Record(c, activity.KindWebhook, id_helper.BlobToString(row.ID), activity.ActionDeleted, nil)
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// Delivery is the API representation of a queued or sent webhook delivery.
+type Delivery struct {
+	ID            string          `json:"id"`
+	EventID       int64           `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Status        string          `json:"status"`
+	Attempts      int64           `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt *time.Time      `json:"last_attempt_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	Log           []Attempt       `json:"log,omitempty"`
+}
+
+// Attempt is one try at sending a delivery.
+type Attempt struct {
+	AttemptedAt    time.Time `json:"attempted_at"`
+	DurationMs     int64     `json:"duration_ms"`
+	ResponseStatus *int64    `json:"response_status,omitempty"`
+	ResponseBody   *string   `json:"response_body,omitempty"`
+	Error          *string   `json:"error,omitempty"`
+}
+
+func deliveryFromRow(row database.WebhookDelivery) Delivery {
+	delivery := Delivery{
+		ID:        id_helper.BlobToString(row.ID),
+		EventID:   row.EventID,
+		EventType: row.EventType,
+		Status:    row.Status,
+		Attempts:  row.Attempts,
+		CreatedAt: row.CreatedAt,
+	}
+	if row.Status == webhooks.StatusPending {
+		delivery.NextAttemptAt = &row.NextAttemptAt
+	}
+	if row.LastAttemptAt.Valid {
+		delivery.LastAttemptAt = &row.LastAttemptAt.Time
+	}
+	return delivery
+}
+
+func attemptFromRow(row database.WebhookAttempt) Attempt {
+	attempt := Attempt{AttemptedAt: row.AttemptedAt, DurationMs: row.DurationMs}
+	if row.ResponseStatus.Valid {
+		attempt.ResponseStatus = &row.ResponseStatus.Int64
+	}
+	if row.ResponseBody.Valid {
+		attempt.ResponseBody = &row.ResponseBody.String
+	}
+	if row.Error.Valid {
+		attempt.Error = &row.Error.String
+	}
+	return attempt
+}
+
+// GET /webhook/:webhook_id/deliveries?limit=50
+// Lists the most recent deliveries, newest first.
+func listDeliveries(c *gin.Context) {
+	row, ok := loadWebhook(c)
+	if !ok {
+		return
+	}
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = parsed
+	}
+	rows, err := database.Connection.ListWebhookDeliveries(c, database.ListWebhookDeliveriesParams{
+		WebhookID: row.ID,
+		Limit:     int64(limit),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	res := make([]Delivery, 0, len(rows))
+	for _, delivery := range rows {
+		res = append(res, deliveryFromRow(delivery))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GET /webhook/:webhook_id/delivery/:delivery_id
+// Returns the delivery with its payload and the log of every attempt.
+func getDelivery(c *gin.Context) {
+	row, ok := loadWebhook(c)
+	if !ok {
+		return
+	}
+	delivery_uuid, errMsg, ok := id_helper.MustParseAndMarshalUUID(c.Param("delivery_id"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	deliveryRow, err := database.Connection.GetWebhookDelivery(c, database.GetWebhookDeliveryParams{
+		ID:        delivery_uuid,
+		WebhookID: row.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	attempts, err := database.Connection.ListWebhookAttempts(c, delivery_uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	res := deliveryFromRow(deliveryRow)
+	res.Payload = json.RawMessage(deliveryRow.Payload)
+	res.Log = make([]Attempt, 0, len(attempts))
+	for _, attempt := range attempts {
+		res.Log = append(res.Log, attemptFromRow(attempt))
+	}
+	c.JSON(http.StatusOK, res)
+}
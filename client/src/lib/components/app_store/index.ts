@@ -98,30 +98,192 @@ export async function UpdateAppStore() {
   ]);
 }
 
-// Connect to sync eventstream API and update store on events
-function connectSyncEvents() {
-  const evtSource = new EventSource("/api/sync");
-  evtSource.addEventListener("samples_updated", () => {
-    console.log("Samples updated event received");
-    UpdateSamples();
-  });
-  evtSource.addEventListener("products_updated", () => {
-    console.log("Products updated event received");
-    UpdateProducts();
-  });
-  evtSource.addEventListener("locations_updated", () => {
-    console.log("Locations updated event received");
-    UpdateLocations();
+// Payload of the *_updated sync events, see docs/sync-events.md
+type EntityEvent = {
+  kind: string;
+  id: string;
+  op: "created" | "updated" | "deleted";
+  data?: { [key: string]: any };
+};
+
+function parseEntityEvent(e: MessageEvent): EntityEvent | null {
+  try {
+    const evt = JSON.parse(e.data);
+    return evt && typeof evt.id === "string" ? evt : null;
+  } catch {
+    return null;
+  }
+}
+
+// Replaces, adds or removes the entity an event refers to.
+function applyEntityEvent<T>(
+  items: T[],
+  evt: EntityEvent,
+  getId: (item: T) => string,
+  build: (data: { [key: string]: any }) => T
+): T[] {
+  if (evt.op === "deleted") {
+    return items.filter((item) => getId(item) !== evt.id);
+  }
+  const updated = build(evt.data!);
+  const index = items.findIndex((item) => getId(item) === evt.id);
+  if (index === -1) {
+    return [...items, updated];
+  }
+  const copy = [...items];
+  copy[index] = updated;
+  return copy;
+}
+
+// Applies a sync event to one collection, falling back to a full refetch when the
+// event doesn't carry the new representation.
+function handleEntityEvent<K extends "samples" | "products" | "locations" | "users">(
+  e: MessageEvent,
+  key: K,
+  getId: (item: AppData[K][number]) => string,
+  build: (data: { [key: string]: any }) => AppData[K][number],
+  refetch: () => Promise<void>
+) {
+  const evt = parseEntityEvent(e);
+  if (!evt || (evt.op !== "deleted" && !evt.data)) {
+    refetch();
+    return;
+  }
+  AppStore.update((data) => ({
+    ...data,
+    [key]: applyEntityEvent(data[key] as AppData[K][number][], evt, getId, build),
+  }));
+}
+
+// Handlers for sync events, shared by the WebSocket and EventSource transports
+const syncHandlers: { [event: string]: (e: MessageEvent) => void } = {
+  samples_updated: (e) => {
+    handleEntityEvent(
+      e,
+      "samples",
+      (sample) => sample.DisplayId,
+      (data) => new Sample(data, AppStore),
+      UpdateSamples
+    );
+  },
+  products_updated: (e) => {
+    handleEntityEvent(
+      e,
+      "products",
+      (product) => product.id,
+      (data) => new SampleProduct(data, AppStore),
+      UpdateProducts
+    );
+    AppStore.update((data) => ({
+      ...data,
+      products: [...data.products].sort(
+        (a, b) => a.name.localeCompare(b.name) || a.id.localeCompare(b.id)
+      ),
+    }));
+  },
+  locations_updated: (e) => {
+    handleEntityEvent(
+      e,
+      "locations",
+      (location) => location.id,
+      (data) => new SampleLocation(data, AppStore),
+      UpdateLocations
+    );
+  },
+  users_updated: (e) => {
+    handleEntityEvent(
+      e,
+      "users",
+      (user) => user.id,
+      (data) => new User(data, AppStore),
+      UpdateUsers
+    );
+  },
+  // The server couldn't replay everything we missed while disconnected
+  resync_required: () => {
+    console.log("Sync resync required, refetching everything");
+    UpdateAppStore();
+  },
+};
+
+// Presence reported by other clients, keyed by session
+export const Presence: Writable<{ [session: string]: { [key: string]: any } }> =
+  writable({});
+syncHandlers.presence = (e) => {
+  const evt = JSON.parse(e.data);
+  Presence.update((sessions) => {
+    const copy = { ...sessions };
+    if (evt.status === "offline") {
+      delete copy[evt.session];
+    } else {
+      copy[evt.session] = evt;
+    }
+    return copy;
   });
-  evtSource.addEventListener("users_updated", () => {
-    console.log("Users updated event received");
-    UpdateUsers();
+};
+
+const presenceSession = crypto.randomUUID();
+let syncSocket: WebSocket | null = null;
+
+// Tells other clients what this tab is doing, e.g. SendPresence("viewing", "sample:0A-1B-2C")
+export function SendPresence(status: string, topic?: string, data?: any) {
+  const presence = { session: presenceSession, status, topic, data };
+  if (syncSocket && syncSocket.readyState === WebSocket.OPEN) {
+    syncSocket.send(JSON.stringify({ type: "presence", presence }));
+    return;
+  }
+  fetch("/api/sync/presence", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(presence),
   });
+}
+
+// Connect to the sync eventstream API and update store on events
+function connectEventSource() {
+  const evtSource = new EventSource("/api/sync");
+  for (const [event, handler] of Object.entries(syncHandlers)) {
+    evtSource.addEventListener(event, handler);
+  }
   evtSource.onerror = (err) => {
+    // EventSource reconnects by itself and sends Last-Event-ID, so missed events are replayed
     console.error("Sync eventstream error", err);
-    // Optionally, try to reconnect after a delay
+  };
+}
+
+// Connect to the sync WebSocket, which some proxies handle better than event streams. Falls
+// back to EventSource if the WebSocket never opens.
+function connectWebSocket(lastEventId?: number, retryDelay = 1000) {
+  const protocol = location.protocol === "https:" ? "wss:" : "ws:";
+  const query = lastEventId ? `?last_event_id=${lastEventId}` : "";
+  const socket = new WebSocket(`${protocol}//${location.host}/api/sync/ws${query}`);
+  let opened = false;
+  let heartbeat: ReturnType<typeof setInterval>;
+  socket.onopen = () => {
+    opened = true;
+    retryDelay = 1000;
+    syncSocket = socket;
+    heartbeat = setInterval(() => socket.send(JSON.stringify({ type: "ping" })), 30000);
+  };
+  socket.onmessage = (msg) => {
+    const { id, event, data } = JSON.parse(msg.data);
+    if (id) {
+      lastEventId = id;
+    }
+    syncHandlers[event]?.(new MessageEvent(event, { data: JSON.stringify(data) }));
+  };
+  socket.onclose = () => {
+    clearInterval(heartbeat);
+    syncSocket = null;
+    if (!opened && !lastEventId) {
+      console.log("Sync WebSocket unavailable, using EventSource");
+      connectEventSource();
+      return;
+    }
+    // Reconnect with the last event ID so missed events are replayed
+    setTimeout(() => connectWebSocket(lastEventId, Math.min(retryDelay * 2, 30000)), retryDelay);
   };
 }
 
 // Start sync connection immediately
-connectSyncEvents();
+connectWebSocket();
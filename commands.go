@@ -0,0 +1,527 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/backup"
+	"reesource-tracker/lib/config"
+	"reesource-tracker/lib/database"
+	"reesource-tracker/lib/dump"
+	"reesource-tracker/lib/exporter"
+	id_helper "reesource-tracker/lib/id_helper"
+	"reesource-tracker/lib/importer"
+	"reesource-tracker/lib/notifications"
+	sampleid "reesource-tracker/lib/sample_id"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// commands are the subcommands other than serve, called with the arguments after their name
+// and returning the exit status: 2 if they are used wrongly and 1 if they fail. They work on
+// the database directly, so the changes they make aren't sent to sync clients or recorded in
+// the activity history. Stop the server before running the ones that write to it. Only load
+// and create-admin apply pending migrations, so they work on a new database; the others use
+// the database as it is, since they may run next to a server of another version.
+var commands = map[string]func(args []string) int{
+	"backup":       backupCommand,
+	"restore":      restoreCommand,
+	"dump":         dumpCommand,
+	"load":         loadCommand,
+	"migrate":      migrateCommand,
+	"generate":     generateCommand,
+	"import":       importCommand,
+	"export":       exportCommand,
+	"create-admin": createAdminCommand,
+	"check":        checkCommand,
+}
+
+// backupCommand takes a backup and prunes old ones, like the database_backup job. It is
+// safe to run while the server is running.
+func backupCommand(args []string) int {
+	if len(args) != 0 {
+		slog.Error("Usage: reesource-tracker backup")
+		return 2
+	}
+	ctx := context.Background()
+	if err := database.Open(ctx); err != nil {
+		slog.Error("Failed to open the database", "error", err)
+		return 1
+	}
+	defer database.Close(ctx)
+	path, err := backup.Create(ctx)
+	if err != nil {
+		slog.Error("Backup failed", "error", err)
+		return 1
+	}
+	removed, err := backup.Prune(config.Current.BackupKeep)
+	if err != nil {
+		slog.Error("Failed to remove old backups", "error", err)
+		return 1
+	}
+	fmt.Println(path)
+	for _, path := range removed {
+		slog.Info("Removed old backup", "path", path)
+	}
+	return 0
+}
+
+// restoreCommand replaces the database with a backup after checking it. The server must be
+// stopped; it migrates the restored database when it next starts.
+func restoreCommand(args []string) int {
+	if len(args) != 1 {
+		slog.Error("Usage: reesource-tracker restore <backup file>")
+		return 2
+	}
+	report, previous, err := backup.Restore(context.Background(), args[0])
+	if err != nil {
+		slog.Error("Restore failed", "error", err, "integrity", report.Integrity, "version", report.Version, "latest_version", report.LatestVersion)
+		return 1
+	}
+	slog.Info("Restored backup", "file", args[0], "version", report.Version, "latest_version", report.LatestVersion)
+	if previous != "" {
+		fmt.Printf("The previous database was saved as %s\n", previous)
+	}
+	return 0
+}
+
+// dumpCommand writes every table to a JSON or NDJSON archive, or to stdout when the file is
+// "-". It is safe to run while the server is running.
+func dumpCommand(args []string) int {
+	fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+	format := fs.String("format", "", "json or ndjson, by default from the file extension")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		slog.Error("Usage: reesource-tracker dump [-format json|ndjson] <file>")
+		return 2
+	}
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = dump.FormatFor(path)
+	}
+	ctx := context.Background()
+	if err := database.Open(ctx); err != nil {
+		slog.Error("Failed to open the database", "error", err)
+		return 1
+	}
+	defer database.Close(ctx)
+
+	out := os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			slog.Error("Dump failed", "error", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := dump.Dump(ctx, out, *format); err != nil {
+		slog.Error("Dump failed", "error", err)
+		if path != "-" {
+			os.Remove(path)
+		}
+		return 1
+	}
+	if path != "-" {
+		if err := out.Close(); err != nil {
+			slog.Error("Dump failed", "error", err)
+			return 1
+		}
+		slog.Info("Dumped the database", "file", path, "format", *format)
+	}
+	return 0
+}
+
+// loadCommand adds the rows of an archive written by dump to the database, reading stdin when
+// the file is "-". Nothing is loaded if any row fails.
+func loadCommand(args []string) int {
+	fs := flag.NewFlagSet("load", flag.ContinueOnError)
+	policy := fs.String("on-conflict", dump.ConflictFail, "what to do with rows that already exist: skip, overwrite or fail")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		slog.Error("Usage: reesource-tracker load [-on-conflict skip|overwrite|fail] <file>")
+		return 2
+	}
+	path := fs.Arg(0)
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			slog.Error("Load failed", "error", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+	ctx := context.Background()
+	if err := database.Connect(ctx); err != nil {
+		slog.Error("Failed to open the database", "error", err)
+		return 1
+	}
+	defer database.Close(ctx)
+	result, err := dump.Load(ctx, in, *policy)
+	if err != nil {
+		slog.Error("Load failed, nothing was loaded", "error", err)
+		return 1
+	}
+	for _, t := range result.Tables {
+		fmt.Printf("%-26s %6d inserted %6d updated %6d skipped\n", t.Table, t.Inserted, t.Updated, t.Skipped)
+	}
+	slog.Info("Loaded dump", "file", path, "created_at", result.Header.CreatedAt)
+	return 0
+}
+
+// migrateCommand applies or reverts migrations. The server applies every migration when it
+// starts, so it must be stopped before migrating down.
+func migrateCommand(args []string) int {
+	usage := "Usage: reesource-tracker migrate up | down [-steps n] | version"
+	if len(args) == 0 || (args[0] != "up" && args[0] != "down" && args[0] != "version") {
+		slog.Error(usage)
+		return 2
+	}
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	steps := fs.Int("steps", 1, "how many migrations to revert")
+	if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 0 || *steps < 1 {
+		slog.Error(usage)
+		return 2
+	}
+	ctx := context.Background()
+	if err := database.Open(ctx); err != nil {
+		slog.Error("Failed to open the database", "error", err)
+		return 1
+	}
+	defer database.Close(ctx)
+
+	var err error
+	switch args[0] {
+	case "up":
+		err = database.MigrateUp()
+	case "down":
+		err = database.MigrateDown(*steps)
+	}
+	if err != nil {
+		slog.Error("Migration failed", "error", err)
+		return 1
+	}
+	version, dirty, err := database.MigrationVersion()
+	if err != nil {
+		slog.Error("Failed to read the migration version", "error", err)
+		return 1
+	}
+	fmt.Printf("version %d", version)
+	if dirty {
+		fmt.Print(" (dirty, a migration failed part way)")
+	}
+	fmt.Println()
+	return 0
+}
+
+// generateCommand registers a batch of unassigned samples with random IDs, like the
+// Generate Samples page, and prints the IDs one per line.
+func generateCommand(args []string) int {
+	if len(args) != 1 {
+		slog.Error("Usage: reesource-tracker generate <count>")
+		return 2
+	}
+	count, err := strconv.Atoi(args[0])
+	if err != nil || count < 1 {
+		slog.Error("Usage: reesource-tracker generate <count>", "error", "count must be a positive number")
+		return 2
+	}
+	ctx := context.Background()
+	if err := database.Open(ctx); err != nil {
+		slog.Error("Failed to open the database", "error", err)
+		return 1
+	}
+	defer database.Close(ctx)
+
+	var rawIDs [][]byte
+	err = database.Transaction(ctx, func(q *database.Queries) error {
+		var registerErr error
+		rawIDs, registerErr = q.RegisterNewSamples(ctx, count)
+		return registerErr
+	})
+	if err != nil {
+		slog.Error("Failed to generate samples", "error", err)
+		return 1
+	}
+	for _, raw := range rawIDs {
+		id, err := sampleid.FormatSampleID(raw)
+		if err != nil {
+			slog.Error("Failed to format sample ID", "error", err)
+			return 1
+		}
+		fmt.Println(id)
+	}
+	return 0
+}
+
+// importCommand imports a CSV file of samples, products, locations or users like the import
+// page, reading stdin when the file is "-". Nothing is imported if any row is invalid.
+func importCommand(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "check the file without importing it")
+	createMissing := fs.Bool("create-missing", false, "create products and locations that rows refer to but don't exist")
+	mappingJSON := fs.String("mapping", "", `JSON object mapping fields to column headers, e.g. {"id": "Sample"}`)
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		slog.Error("Usage: reesource-tracker import [-dry-run] [-create-missing] [-mapping json] <kind> <file>")
+		return 2
+	}
+	kind, path := fs.Arg(0), fs.Arg(1)
+	var mapping map[string]string
+	if *mappingJSON != "" {
+		if err := json.Unmarshal([]byte(*mappingJSON), &mapping); err != nil {
+			slog.Error("Invalid mapping", "error", err)
+			return 2
+		}
+	}
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			slog.Error("Import failed", "error", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+	ctx := context.Background()
+	if err := database.Open(ctx); err != nil {
+		slog.Error("Failed to open the database", "error", err)
+		return 1
+	}
+	defer database.Close(ctx)
+
+	result, err := importer.Import(ctx, kind, in, importer.Options{
+		Mapping:       mapping,
+		CreateMissing: *createMissing,
+		DryRun:        *dryRun,
+	})
+	for _, rowErr := range result.Errors {
+		if rowErr.Field != "" {
+			fmt.Printf("line %d: %s: %s\n", rowErr.Line, rowErr.Field, rowErr.Error)
+		} else {
+			fmt.Printf("line %d: %s\n", rowErr.Line, rowErr.Error)
+		}
+	}
+	if err != nil {
+		slog.Error("Import failed", "error", err)
+		if errors.Is(err, importer.ErrUnknownKind) {
+			return 2
+		}
+		return 1
+	}
+	fmt.Printf("%d rows: %d created, %d updated, %d unchanged\n", result.Rows, result.Created, result.Updated, result.Unchanged)
+	if *dryRun {
+		fmt.Println("Dry run, nothing was imported")
+	}
+	return 0
+}
+
+// exportCommand writes samples, products, locations or users to a CSV or XLSX file like the
+// export page, or to stdout when the file is "-". It is safe to run while the server is
+// running.
+func exportCommand(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "", "csv or xlsx, by default from the file extension")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		slog.Error("Usage: reesource-tracker export [-format csv|xlsx] <kind> <file>")
+		return 2
+	}
+	kind, path := fs.Arg(0), fs.Arg(1)
+	if *format == "" {
+		*format = exporter.FormatCSV
+		if strings.EqualFold(filepath.Ext(path), "."+exporter.FormatXLSX) {
+			*format = exporter.FormatXLSX
+		}
+	}
+	if _, ok := exporter.Headers[kind]; !ok {
+		slog.Error("Export failed", "error", exporter.ErrUnknownKind)
+		return 2
+	}
+	ctx := context.Background()
+	if err := database.Open(ctx); err != nil {
+		slog.Error("Failed to open the database", "error", err)
+		return 1
+	}
+	defer database.Close(ctx)
+
+	out := os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			slog.Error("Export failed", "error", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := exporter.Export(ctx, kind, *format, out, nil); err != nil {
+		slog.Error("Export failed", "error", err)
+		if path != "-" {
+			os.Remove(path)
+		}
+		return 1
+	}
+	if path != "-" {
+		if err := out.Close(); err != nil {
+			slog.Error("Export failed", "error", err)
+			return 1
+		}
+		slog.Info("Exported", "kind", kind, "file", path, "format", *format)
+	}
+	return 0
+}
+
+// createAdminCommand gives a user the admin role, e.g. to set up a new instance. With
+// -oidc-subject the user signing in with that subject is promoted, and created if they
+// haven't signed in yet; otherwise a new user is created. With -token an admin API token is
+// created for them and printed, which is the only time it is shown.
+func createAdminCommand(args []string) int {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	emailFlag := fs.String("email", "", "the user's email address")
+	subject := fs.String("oidc-subject", "", "the subject the user signs in with through SSO")
+	tokenName := fs.String("token", "", "also create an admin API token with this name")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 || strings.TrimSpace(fs.Arg(0)) == "" {
+		slog.Error("Usage: reesource-tracker create-admin [-email address] [-oidc-subject subject] [-token name] <name>")
+		return 2
+	}
+	name := strings.TrimSpace(fs.Arg(0))
+	var email sql.NullString
+	if *emailFlag != "" {
+		var err error
+		if email, err = notifications.ParseEmail(*emailFlag); err != nil {
+			slog.Error("Invalid email", "error", err)
+			return 2
+		}
+	}
+	ctx := context.Background()
+	if err := database.Connect(ctx); err != nil {
+		slog.Error("Failed to open the database", "error", err)
+		return 1
+	}
+	defer database.Close(ctx)
+
+	var (
+		userID  []byte
+		created bool
+		token   string
+	)
+	err := database.Transaction(ctx, func(q *database.Queries) error {
+		var err error
+		userID, created, err = adminUser(ctx, q, name, *subject)
+		if err != nil {
+			return err
+		}
+		if email.Valid {
+			if err := q.UpdateUserEmail(ctx, database.UpdateUserEmailParams{Email: email, ID: userID}); err != nil {
+				return err
+			}
+		}
+		if err := q.AddUserRole(ctx, database.AddUserRoleParams{UserID: userID, Role: auth.RoleAdmin}); err != nil {
+			return err
+		}
+		if *tokenName == "" {
+			return nil
+		}
+		var hash []byte
+		if token, hash, err = auth.GenerateToken(); err != nil {
+			return err
+		}
+		tokenID, err := uuid.New().MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return q.CreateAPIToken(ctx, database.CreateAPITokenParams{
+			ID:        tokenID,
+			UserID:    userID,
+			Name:      *tokenName,
+			TokenHash: hash,
+			Scopes:    auth.ScopeAdmin,
+			CreatedAt: time.Now(),
+		})
+	})
+	if err != nil {
+		slog.Error("Failed to create the admin", "error", err)
+		return 1
+	}
+	if created {
+		slog.Info("Created admin user", "id", id_helper.BlobToString(userID), "name", name)
+	} else {
+		slog.Info("Made existing user an admin", "id", id_helper.BlobToString(userID))
+	}
+	if token != "" {
+		fmt.Println(token)
+	}
+	return 0
+}
+
+// adminUser returns the user create-admin should promote and whether it created them.
+func adminUser(ctx context.Context, q *database.Queries, name string, subject string) ([]byte, bool, error) {
+	userID, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return nil, false, err
+	}
+	if subject == "" {
+		err = q.UpsertUser(ctx, database.UpsertUserParams{ID: userID, Name: name})
+		return userID, true, err
+	}
+	oidcSubject := sql.NullString{String: subject, Valid: true}
+	user, err := q.GetUserByOIDCSubject(ctx, oidcSubject)
+	if err == nil {
+		userID, ok := user.ID.([]byte)
+		if !ok {
+			return nil, false, errors.New("user has an invalid ID")
+		}
+		return userID, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+	err = q.CreateOIDCUser(ctx, database.CreateOIDCUserParams{ID: userID, Name: name, OidcSubject: oidcSubject})
+	return userID, true, err
+}
+
+// checkCommand checks the database for corruption, rows referring to missing rows and failed
+// migrations. It exits with 1 if it finds any problems.
+func checkCommand(args []string) int {
+	if len(args) != 0 {
+		slog.Error("Usage: reesource-tracker check")
+		return 2
+	}
+	ctx := context.Background()
+	if err := database.Open(ctx); err != nil {
+		slog.Error("Failed to open the database", "error", err)
+		return 1
+	}
+	defer database.Close(ctx)
+	result, err := database.CheckIntegrity(ctx)
+	if err != nil {
+		slog.Error("Integrity check failed", "error", err)
+		return 1
+	}
+	for _, problem := range result.Problems {
+		fmt.Println(problem)
+	}
+	for _, problem := range result.ForeignKeys {
+		fmt.Println(problem)
+	}
+	if result.Dirty {
+		fmt.Printf("migration %d failed part way\n", result.Version)
+	}
+	if !result.OK() {
+		slog.Error("The database has problems", "problems", len(result.Problems), "foreign_keys", len(result.ForeignKeys), "dirty", result.Dirty)
+		return 1
+	}
+	slog.Info("The database is OK", "version", result.Version)
+	return 0
+}
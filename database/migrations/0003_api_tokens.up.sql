@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id BLOB(16) PRIMARY KEY NOT NULL,
+    user_id BLOB(16) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name VARCHAR(128) NOT NULL,
+    token_hash BLOB(32) NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME,
+    last_used_at DATETIME,
+    revoked_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS api_tokens_user_id ON api_tokens (user_id);
@@ -0,0 +1,10 @@
+ALTER TABLE users ADD COLUMN oidc_subject VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_oidc_subject ON users (oidc_subject);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id BLOB(32) PRIMARY KEY NOT NULL,
+    user_id BLOB(16) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL
+);
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id BLOB(16) PRIMARY KEY NOT NULL,
+    url TEXT NOT NULL,
+    event_types TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT 1,
+    created_by BLOB(16) REFERENCES users (id) ON DELETE SET NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BLOB(16) PRIMARY KEY NOT NULL,
+    webhook_id BLOB(16) NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id INTEGER NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    last_attempt_at DATETIME,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    delivery_id BLOB(16) NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+    attempted_at DATETIME NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    response_status INTEGER,
+    response_body TEXT,
+    error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS webhook_attempts_delivery ON webhook_attempts (delivery_id);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS notifications;
+
+DROP TABLE IF EXISTS notification_preferences;
+
+ALTER TABLE sample_comments DROP COLUMN author_id;
+
+ALTER TABLE users DROP COLUMN email;
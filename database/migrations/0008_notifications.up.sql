@@ -0,0 +1,23 @@
+ALTER TABLE users
+ADD COLUMN email VARCHAR(254);
+
+ALTER TABLE sample_comments
+ADD COLUMN author_id BLOB(16) REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id BLOB(16) PRIMARY KEY NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    digest TEXT NOT NULL DEFAULT 'immediate' CHECK (digest IN ('immediate', 'hourly', 'daily')),
+    disabled_kinds TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id BLOB(16) PRIMARY KEY NOT NULL,
+    user_id BLOB(16) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    sent_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS notifications_pending ON notifications (sent_at, user_id, created_at);
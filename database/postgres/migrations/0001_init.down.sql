@@ -0,0 +1,14 @@
+-- Drop sample_notes table
+DROP TABLE IF EXISTS sample_notes;
+
+-- Drop sample_mods table
+DROP TABLE IF EXISTS sample_mods;
+
+-- Drop samples table
+DROP TABLE IF EXISTS samples;
+
+-- Drop products table
+DROP TABLE IF EXISTS products;
+
+-- Drop locations table
+DROP TABLE IF EXISTS locations;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id BYTEA PRIMARY KEY NOT NULL,
+    user_id BYTEA NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name VARCHAR(128) NOT NULL,
+    token_hash BYTEA NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS api_tokens_user_id ON api_tokens (user_id);
@@ -0,0 +1,2 @@
+-- Drop user_roles table
+DROP TABLE IF EXISTS user_roles;
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id BYTEA NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role TEXT CHECK (role IN ('viewer', 'technician', 'admin')) NOT NULL,
+    PRIMARY KEY (user_id, role)
+);
@@ -0,0 +1,7 @@
+-- Drop sessions table
+DROP TABLE IF EXISTS sessions;
+
+-- Remove oidc_subject column from users
+DROP INDEX IF EXISTS users_oidc_subject;
+
+ALTER TABLE users DROP COLUMN oidc_subject;
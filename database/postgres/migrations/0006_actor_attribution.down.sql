@@ -0,0 +1,10 @@
+-- Drop activity_log table
+DROP TABLE IF EXISTS activity_log;
+
+-- Remove author_id column from sample_notes
+ALTER TABLE sample_notes DROP COLUMN author_id;
+
+-- Remove actor columns from sample_mods
+ALTER TABLE sample_mods DROP COLUMN removed_by;
+
+ALTER TABLE sample_mods DROP COLUMN added_by;
@@ -0,0 +1,21 @@
+ALTER TABLE sample_mods
+ADD COLUMN added_by BYTEA REFERENCES users (id) ON DELETE SET NULL;
+
+ALTER TABLE sample_mods
+ADD COLUMN removed_by BYTEA REFERENCES users (id) ON DELETE SET NULL;
+
+ALTER TABLE sample_notes
+ADD COLUMN author_id BYTEA REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS activity_log (
+    id BYTEA PRIMARY KEY NOT NULL,
+    user_id BYTEA REFERENCES users (id) ON DELETE SET NULL,
+    token_id BYTEA REFERENCES api_tokens (id) ON DELETE SET NULL,
+    entity_kind VARCHAR(32) NOT NULL,
+    entity_id VARCHAR(64) NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    details TEXT,
+    time TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS activity_log_user_time ON activity_log (user_id, time);
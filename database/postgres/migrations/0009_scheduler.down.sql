@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS job_runs;
+
+DROP TABLE IF EXISTS scheduled_jobs;
+
+DROP TABLE IF EXISTS sample_loans;
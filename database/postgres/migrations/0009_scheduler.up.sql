@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS sample_loans (
+    id BYTEA PRIMARY KEY NOT NULL,
+    sample_id BYTEA NOT NULL REFERENCES samples (id),
+    user_id BYTEA NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    loaned_at TIMESTAMPTZ NOT NULL,
+    due_at TIMESTAMPTZ NOT NULL,
+    returned_at TIMESTAMPTZ,
+    reminded_at TIMESTAMPTZ
+);
+
+-- A sample can only be out on one loan at a time
+CREATE UNIQUE INDEX IF NOT EXISTS sample_loans_active ON sample_loans (sample_id)
+WHERE
+    returned_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS sample_loans_due ON sample_loans (returned_at, due_at);
+
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    name TEXT PRIMARY KEY NOT NULL,
+    schedule TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_run_at TIMESTAMPTZ,
+    last_status TEXT
+);
+
+CREATE TABLE IF NOT EXISTS job_runs (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    job_name TEXT NOT NULL REFERENCES scheduled_jobs (name) ON DELETE CASCADE,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ,
+    status TEXT NOT NULL CHECK (status IN ('running', 'succeeded', 'failed')),
+    output TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS job_runs_job ON job_runs (job_name, started_at);
@@ -0,0 +1,15 @@
+// Package postgres holds the PostgreSQL schema and queries. They are built into the binary,
+// since the queries have to match the Go code generated from the SQLite ones.
+package postgres
+
+import "embed"
+
+// Migrations mirror database/migrations, with the same version numbers.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// Queries have the same names, parameters and result columns as database/queries.
+//
+//go:embed queries/*.sql
+var Queries embed.FS
@@ -0,0 +1,25 @@
+-- name: RecordActivity :exec
+INSERT INTO
+    activity_log (
+        id,
+        user_id,
+        token_id,
+        entity_kind,
+        entity_id,
+        action,
+        details,
+        time
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ListUserActivity :many
+SELECT *
+FROM
+    activity_log
+WHERE
+    user_id = $1
+ORDER BY
+    time DESC
+LIMIT
+    $2;
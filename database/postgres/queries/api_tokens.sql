@@ -0,0 +1,45 @@
+-- name: CreateAPIToken :exec
+INSERT INTO
+    api_tokens (id, user_id, name, token_hash, scopes, created_at, expires_at)
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListAPITokensByUser :many
+SELECT
+    id,
+    user_id,
+    name,
+    scopes,
+    created_at,
+    expires_at,
+    last_used_at,
+    revoked_at
+FROM
+    api_tokens
+WHERE
+    user_id = $1
+ORDER BY
+    created_at;
+
+-- name: GetAPITokenByHash :one
+SELECT *
+FROM
+    api_tokens
+WHERE
+    token_hash = $1;
+
+-- name: TouchAPIToken :exec
+UPDATE api_tokens
+SET
+    last_used_at = $1
+WHERE
+    id = $2;
+
+-- name: RevokeAPIToken :execrows
+UPDATE api_tokens
+SET
+    revoked_at = $1
+WHERE
+    id = $2
+    AND user_id = $3
+    AND revoked_at IS NULL;
@@ -0,0 +1,118 @@
+-- PostgreSQL enforces foreign keys, so apart from expiry these rarely find anything. They
+-- mirror the SQLite queries so the cleanup job runs the same on both engines.
+-- name: DeleteOrphanSampleMods :execrows
+DELETE FROM sample_mods
+WHERE
+    sample_id NOT IN (
+        SELECT
+            id
+        FROM
+            samples
+    );
+
+-- name: DeleteOrphanSampleNotes :execrows
+DELETE FROM sample_notes
+WHERE
+    sample_id NOT IN (
+        SELECT
+            id
+        FROM
+            samples
+    );
+
+-- name: DeleteOrphanSampleComments :execrows
+DELETE FROM sample_comments
+WHERE
+    sample_id NOT IN (
+        SELECT
+            id
+        FROM
+            samples
+    );
+
+-- name: DeleteOrphanSampleLoans :execrows
+DELETE FROM sample_loans
+WHERE
+    sample_id NOT IN (
+        SELECT
+            id
+        FROM
+            samples
+    )
+    OR user_id NOT IN (
+        SELECT
+            id
+        FROM
+            users
+    );
+
+-- name: DeleteOrphanUserRoles :execrows
+DELETE FROM user_roles
+WHERE
+    user_id NOT IN (
+        SELECT
+            id
+        FROM
+            users
+    );
+
+-- name: DeleteOrphanAPITokens :execrows
+DELETE FROM api_tokens
+WHERE
+    user_id NOT IN (
+        SELECT
+            id
+        FROM
+            users
+    );
+
+-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions
+WHERE
+    expires_at < $1
+    OR user_id NOT IN (
+        SELECT
+            id
+        FROM
+            users
+    );
+
+-- name: DeleteOldNotifications :execrows
+DELETE FROM notifications
+WHERE
+    sent_at < $1
+    OR user_id NOT IN (
+        SELECT
+            id
+        FROM
+            users
+    );
+
+-- name: DeleteOldWebhookDeliveries :execrows
+DELETE FROM webhook_deliveries
+WHERE
+    (
+        webhook_deliveries.status != 'pending'
+        AND webhook_deliveries.created_at < $1
+    )
+    OR webhook_id NOT IN (
+        SELECT
+            id
+        FROM
+            webhooks
+    );
+
+-- name: DeleteOrphanWebhookAttempts :execrows
+DELETE FROM webhook_attempts
+WHERE
+    delivery_id NOT IN (
+        SELECT
+            id
+        FROM
+            webhook_deliveries
+    );
+
+-- name: DeleteOldJobRuns :execrows
+DELETE FROM job_runs
+WHERE
+    finished_at < $1;
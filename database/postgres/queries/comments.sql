@@ -0,0 +1,14 @@
+-- name: AddSampleComment :exec
+INSERT INTO
+    sample_comments (id, sample_id, comment, created_at, author_id)
+VALUES
+    ($1, $2, $3, $4, $5);
+
+-- name: ListSampleComments :many
+SELECT *
+FROM
+    sample_comments
+WHERE
+    sample_id = $1
+ORDER BY
+    created_at;
@@ -0,0 +1,70 @@
+-- name: EnsureScheduledJob :exec
+INSERT INTO
+    scheduled_jobs (name, schedule)
+VALUES
+    ($1, $2) ON CONFLICT (name) DO NOTHING;
+
+-- name: ListScheduledJobs :many
+SELECT *
+FROM
+    scheduled_jobs
+ORDER BY
+    name;
+
+-- name: GetScheduledJob :one
+SELECT *
+FROM
+    scheduled_jobs
+WHERE
+    name = $1;
+
+-- name: UpdateScheduledJob :exec
+UPDATE scheduled_jobs
+SET
+    schedule = $1,
+    enabled = $2
+WHERE
+    name = $3;
+
+-- name: SetJobLastRun :exec
+UPDATE scheduled_jobs
+SET
+    last_run_at = $1,
+    last_status = $2
+WHERE
+    name = $3;
+
+-- name: StartJobRun :one
+INSERT INTO
+    job_runs (job_name, started_at, status)
+VALUES
+    ($1, $2, 'running') RETURNING id;
+
+-- name: FinishJobRun :exec
+UPDATE job_runs
+SET
+    finished_at = $1,
+    status = $2,
+    output = $3
+WHERE
+    id = $4;
+
+-- name: FailInterruptedJobRuns :exec
+UPDATE job_runs
+SET
+    finished_at = $1,
+    status = 'failed',
+    output = 'interrupted by a server restart'
+WHERE
+    status = 'running';
+
+-- name: ListJobRuns :many
+SELECT *
+FROM
+    job_runs
+WHERE
+    job_name = $1
+ORDER BY
+    started_at DESC
+LIMIT
+    $2;
@@ -0,0 +1,51 @@
+-- name: CreateSampleLoan :exec
+INSERT INTO
+    sample_loans (id, sample_id, user_id, loaned_at, due_at)
+VALUES
+    ($1, $2, $3, $4, $5);
+
+-- name: ReturnSampleLoan :exec
+UPDATE sample_loans
+SET
+    returned_at = $1
+WHERE
+    sample_id = $2
+    AND returned_at IS NULL;
+
+-- name: GetActiveSampleLoan :one
+SELECT *
+FROM
+    sample_loans
+WHERE
+    sample_id = $1
+    AND returned_at IS NULL;
+
+-- name: ListActiveLoans :many
+SELECT *
+FROM
+    sample_loans
+WHERE
+    returned_at IS NULL
+ORDER BY
+    due_at;
+
+-- name: ListOverdueLoans :many
+SELECT *
+FROM
+    sample_loans
+WHERE
+    returned_at IS NULL
+    AND due_at < $1
+    AND (
+        reminded_at IS NULL
+        OR reminded_at < $2
+    )
+ORDER BY
+    due_at;
+
+-- name: MarkLoanReminded :exec
+UPDATE sample_loans
+SET
+    reminded_at = $1
+WHERE
+    id = $2;
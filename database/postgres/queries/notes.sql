@@ -0,0 +1,14 @@
+-- name: AddSampleNote :exec
+INSERT INTO
+    sample_notes (id, sample_id, contents, time_made, author_id)
+VALUES
+    ($1, $2, $3, $4, $5);
+
+-- name: ListSampleNotes :many
+SELECT *
+FROM
+    sample_notes
+WHERE
+    sample_id = $1
+ORDER BY
+    time_made;
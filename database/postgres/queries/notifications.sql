@@ -0,0 +1,52 @@
+-- name: GetNotificationPreferences :one
+SELECT *
+FROM
+    notification_preferences
+WHERE
+    user_id = $1;
+
+-- name: UpsertNotificationPreferences :exec
+INSERT INTO
+    notification_preferences (user_id, digest, disabled_kinds)
+VALUES
+    ($1, $2, $3) ON CONFLICT (user_id) DO
+UPDATE
+SET
+    digest = EXCLUDED.digest,
+    disabled_kinds = EXCLUDED.disabled_kinds;
+
+-- name: QueueNotification :exec
+INSERT INTO
+    notifications (id, user_id, kind, subject, body, created_at)
+VALUES
+    ($1, $2, $3, $4, $5, $6);
+
+-- name: ListPendingNotifications :many
+SELECT
+    notifications.id,
+    notifications.user_id,
+    notifications.kind,
+    notifications.subject,
+    notifications.body,
+    notifications.created_at,
+    users.name,
+    users.email,
+    notification_preferences.digest
+FROM
+    notifications
+    JOIN users ON users.id = notifications.user_id
+    LEFT JOIN notification_preferences ON notification_preferences.user_id = notifications.user_id
+WHERE
+    notifications.sent_at IS NULL
+ORDER BY
+    notifications.user_id,
+    notifications.created_at;
+
+-- name: MarkNotificationsSent :exec
+UPDATE notifications
+SET
+    sent_at = $1
+WHERE
+    user_id = $2
+    AND sent_at IS NULL
+    AND created_at <= $3;
@@ -0,0 +1,30 @@
+-- name: CreateSession :exec
+INSERT INTO
+    sessions (id, user_id, created_at, expires_at)
+VALUES
+    ($1, $2, $3, $4);
+
+-- name: GetSession :one
+SELECT *
+FROM
+    sessions
+WHERE
+    id = $1;
+
+-- name: DeleteSession :exec
+DELETE FROM sessions
+WHERE
+    id = $1;
+
+-- name: GetUserByOIDCSubject :one
+SELECT *
+FROM
+    users
+WHERE
+    oidc_subject = $1;
+
+-- name: CreateOIDCUser :exec
+INSERT INTO
+    users (id, name, oidc_subject)
+VALUES
+    ($1, $2, $3);
@@ -0,0 +1,26 @@
+-- name: ListUserRoles :many
+SELECT
+    role
+FROM
+    user_roles
+WHERE
+    user_id = $1
+ORDER BY
+    role;
+
+-- name: AddUserRole :exec
+INSERT INTO
+    user_roles (user_id, role)
+VALUES
+    ($1, $2) ON CONFLICT (user_id, role) DO NOTHING;
+
+-- name: RemoveUserRole :exec
+DELETE FROM user_roles
+WHERE
+    user_id = $1
+    AND role = $2;
+
+-- name: ClearUserRoles :exec
+DELETE FROM user_roles
+WHERE
+    user_id = $1;
@@ -0,0 +1,136 @@
+-- name: CreateWebhook :exec
+INSERT INTO
+    webhooks (
+        id,
+        url,
+        event_types,
+        secret,
+        active,
+        created_by,
+        created_at
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: UpdateWebhook :exec
+UPDATE webhooks
+SET
+    url = $1,
+    event_types = $2,
+    secret = $3,
+    active = $4
+WHERE
+    id = $5;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+WHERE
+    id = $1;
+
+-- name: GetWebhook :one
+SELECT *
+FROM
+    webhooks
+WHERE
+    id = $1;
+
+-- name: ListWebhooks :many
+SELECT *
+FROM
+    webhooks
+ORDER BY
+    created_at;
+
+-- name: ListActiveWebhooks :many
+SELECT *
+FROM
+    webhooks
+WHERE
+    active;
+
+-- name: CreateWebhookDelivery :exec
+INSERT INTO
+    webhook_deliveries (
+        id,
+        webhook_id,
+        event_id,
+        event_type,
+        payload,
+        status,
+        next_attempt_at,
+        created_at
+    )
+VALUES
+    ($1, $2, $3, $4, $5, 'pending', $6, $7);
+
+-- name: ListDueWebhookDeliveries :many
+SELECT
+    webhook_deliveries.id,
+    webhook_deliveries.webhook_id,
+    webhook_deliveries.event_type,
+    webhook_deliveries.payload,
+    webhook_deliveries.attempts,
+    webhooks.url,
+    webhooks.secret
+FROM
+    webhook_deliveries
+    JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+WHERE
+    webhook_deliveries.status = 'pending'
+    AND webhook_deliveries.next_attempt_at <= $1
+    AND webhooks.active
+ORDER BY
+    webhook_deliveries.next_attempt_at
+LIMIT
+    $2;
+
+-- name: UpdateWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET
+    status = $1,
+    attempts = $2,
+    next_attempt_at = $3,
+    last_attempt_at = $4
+WHERE
+    id = $5;
+
+-- name: RecordWebhookAttempt :exec
+INSERT INTO
+    webhook_attempts (
+        delivery_id,
+        attempted_at,
+        duration_ms,
+        response_status,
+        response_body,
+        error
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6);
+
+-- name: ListWebhookDeliveries :many
+SELECT *
+FROM
+    webhook_deliveries
+WHERE
+    webhook_id = $1
+ORDER BY
+    created_at DESC
+LIMIT
+    $2;
+
+-- name: GetWebhookDelivery :one
+SELECT *
+FROM
+    webhook_deliveries
+WHERE
+    id = $1
+    AND webhook_id = $2;
+
+-- name: ListWebhookAttempts :many
+SELECT *
+FROM
+    webhook_attempts
+WHERE
+    delivery_id = $1
+ORDER BY
+    id;
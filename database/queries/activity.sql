@@ -0,0 +1,25 @@
+-- name: RecordActivity :exec
+INSERT INTO
+    activity_log (
+        id,
+        user_id,
+        token_id,
+        entity_kind,
+        entity_id,
+        action,
+        details,
+        time
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: ListUserActivity :many
+SELECT *
+FROM
+    activity_log
+WHERE
+    user_id = ?
+ORDER BY
+    time DESC
+LIMIT
+    ?;
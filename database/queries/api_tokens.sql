@@ -0,0 +1,45 @@
+-- name: CreateAPIToken :exec
+INSERT INTO
+    api_tokens (id, user_id, name, token_hash, scopes, created_at, expires_at)
+VALUES
+    (?, ?, ?, ?, ?, ?, ?);
+
+-- name: ListAPITokensByUser :many
+SELECT
+    id,
+    user_id,
+    name,
+    scopes,
+    created_at,
+    expires_at,
+    last_used_at,
+    revoked_at
+FROM
+    api_tokens
+WHERE
+    user_id = ?
+ORDER BY
+    created_at;
+
+-- name: GetAPITokenByHash :one
+SELECT *
+FROM
+    api_tokens
+WHERE
+    token_hash = ?;
+
+-- name: TouchAPIToken :exec
+UPDATE api_tokens
+SET
+    last_used_at = ?
+WHERE
+    id = ?;
+
+-- name: RevokeAPIToken :execrows
+UPDATE api_tokens
+SET
+    revoked_at = ?
+WHERE
+    id = ?
+    AND user_id = ?
+    AND revoked_at IS NULL;
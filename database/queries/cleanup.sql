@@ -0,0 +1,118 @@
+-- Foreign keys are only enforced on the connection that enabled them, so rows can be left
+-- behind when their parent is deleted. These queries are used by the cleanup job.
+-- name: DeleteOrphanSampleMods :execrows
+DELETE FROM sample_mods
+WHERE
+    sample_id NOT IN (
+        SELECT
+            id
+        FROM
+            samples
+    );
+
+-- name: DeleteOrphanSampleNotes :execrows
+DELETE FROM sample_notes
+WHERE
+    sample_id NOT IN (
+        SELECT
+            id
+        FROM
+            samples
+    );
+
+-- name: DeleteOrphanSampleComments :execrows
+DELETE FROM sample_comments
+WHERE
+    sample_id NOT IN (
+        SELECT
+            id
+        FROM
+            samples
+    );
+
+-- name: DeleteOrphanSampleLoans :execrows
+DELETE FROM sample_loans
+WHERE
+    sample_id NOT IN (
+        SELECT
+            id
+        FROM
+            samples
+    )
+    OR user_id NOT IN (
+        SELECT
+            id
+        FROM
+            users
+    );
+
+-- name: DeleteOrphanUserRoles :execrows
+DELETE FROM user_roles
+WHERE
+    user_id NOT IN (
+        SELECT
+            id
+        FROM
+            users
+    );
+
+-- name: DeleteOrphanAPITokens :execrows
+DELETE FROM api_tokens
+WHERE
+    user_id NOT IN (
+        SELECT
+            id
+        FROM
+            users
+    );
+
+-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions
+WHERE
+    expires_at < ?
+    OR user_id NOT IN (
+        SELECT
+            id
+        FROM
+            users
+    );
+
+-- name: DeleteOldNotifications :execrows
+DELETE FROM notifications
+WHERE
+    sent_at < ?
+    OR user_id NOT IN (
+        SELECT
+            id
+        FROM
+            users
+    );
+
+-- name: DeleteOldWebhookDeliveries :execrows
+DELETE FROM webhook_deliveries
+WHERE
+    (
+        webhook_deliveries.status != 'pending'
+        AND webhook_deliveries.created_at < ?
+    )
+    OR webhook_id NOT IN (
+        SELECT
+            id
+        FROM
+            webhooks
+    );
+
+-- name: DeleteOrphanWebhookAttempts :execrows
+DELETE FROM webhook_attempts
+WHERE
+    delivery_id NOT IN (
+        SELECT
+            id
+        FROM
+            webhook_deliveries
+    );
+
+-- name: DeleteOldJobRuns :execrows
+DELETE FROM job_runs
+WHERE
+    finished_at < ?;
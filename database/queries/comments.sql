@@ -0,0 +1,14 @@
+-- name: AddSampleComment :exec
+INSERT INTO
+    sample_comments (id, sample_id, comment, created_at, author_id)
+VALUES
+    (?, ?, ?, ?, ?);
+
+-- name: ListSampleComments :many
+SELECT *
+FROM
+    sample_comments
+WHERE
+    sample_id = ?
+ORDER BY
+    created_at;
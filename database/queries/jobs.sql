@@ -0,0 +1,70 @@
+-- name: EnsureScheduledJob :exec
+INSERT INTO
+    scheduled_jobs (name, schedule)
+VALUES
+    (?, ?) ON CONFLICT (name) DO NOTHING;
+
+-- name: ListScheduledJobs :many
+SELECT *
+FROM
+    scheduled_jobs
+ORDER BY
+    name;
+
+-- name: GetScheduledJob :one
+SELECT *
+FROM
+    scheduled_jobs
+WHERE
+    name = ?;
+
+-- name: UpdateScheduledJob :exec
+UPDATE scheduled_jobs
+SET
+    schedule = ?,
+    enabled = ?
+WHERE
+    name = ?;
+
+-- name: SetJobLastRun :exec
+UPDATE scheduled_jobs
+SET
+    last_run_at = ?,
+    last_status = ?
+WHERE
+    name = ?;
+
+-- name: StartJobRun :one
+INSERT INTO
+    job_runs (job_name, started_at, status)
+VALUES
+    (?, ?, 'running') RETURNING id;
+
+-- name: FinishJobRun :exec
+UPDATE job_runs
+SET
+    finished_at = ?,
+    status = ?,
+    output = ?
+WHERE
+    id = ?;
+
+-- name: FailInterruptedJobRuns :exec
+UPDATE job_runs
+SET
+    finished_at = ?,
+    status = 'failed',
+    output = 'interrupted by a server restart'
+WHERE
+    status = 'running';
+
+-- name: ListJobRuns :many
+SELECT *
+FROM
+    job_runs
+WHERE
+    job_name = ?
+ORDER BY
+    started_at DESC
+LIMIT
+    ?;
@@ -0,0 +1,51 @@
+-- name: CreateSampleLoan :exec
+INSERT INTO
+    sample_loans (id, sample_id, user_id, loaned_at, due_at)
+VALUES
+    (?, ?, ?, ?, ?);
+
+-- name: ReturnSampleLoan :exec
+UPDATE sample_loans
+SET
+    returned_at = ?
+WHERE
+    sample_id = ?
+    AND returned_at IS NULL;
+
+-- name: GetActiveSampleLoan :one
+SELECT *
+FROM
+    sample_loans
+WHERE
+    sample_id = ?
+    AND returned_at IS NULL;
+
+-- name: ListActiveLoans :many
+SELECT *
+FROM
+    sample_loans
+WHERE
+    returned_at IS NULL
+ORDER BY
+    due_at;
+
+-- name: ListOverdueLoans :many
+SELECT *
+FROM
+    sample_loans
+WHERE
+    returned_at IS NULL
+    AND due_at < ?
+    AND (
+        reminded_at IS NULL
+        OR reminded_at < ?
+    )
+ORDER BY
+    due_at;
+
+-- name: MarkLoanReminded :exec
+UPDATE sample_loans
+SET
+    reminded_at = ?
+WHERE
+    id = ?;
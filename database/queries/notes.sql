@@ -0,0 +1,14 @@
+-- name: AddSampleNote :exec
+INSERT INTO
+    sample_notes (id, sample_id, contents, time_made, author_id)
+VALUES
+    (?, ?, ?, ?, ?);
+
+-- name: ListSampleNotes :many
+SELECT *
+FROM
+    sample_notes
+WHERE
+    sample_id = ?
+ORDER BY
+    time_made;
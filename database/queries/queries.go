@@ -0,0 +1,8 @@
+// Package queries holds the SQLite queries that the Go code in lib/database is generated
+// from. They are built into the binary so the PostgreSQL versions can be checked against them.
+package queries
+
+import "embed"
+
+//go:embed *.sql
+var Files embed.FS
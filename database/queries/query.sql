@@ -45,6 +45,28 @@ LEFT JOIN users ON samples.owner_id = users.id
 ORDER BY
     time_registered;
 
+-- name: GetSampleSummary :one
+SELECT
+    samples.*,
+    COALESCE(
+        (
+            SELECT
+                GROUP_CONCAT (sample_mods.name, ', ')
+            FROM
+                sample_mods
+            WHERE
+                sample_mods.sample_id = samples.id
+                AND sample_mods.time_removed IS NULL
+        ),
+        ''
+    ) AS current_mods_summary,
+    users.name AS owner_name
+FROM
+    samples
+LEFT JOIN users ON samples.owner_id = users.id
+WHERE
+    samples.id = ?;
+
 -- name: ListSampleMods :many
 SELECT
     *
@@ -55,16 +77,25 @@ WHERE
 ORDER BY
     time_added;
 
+-- name: GetSampleMod :one
+SELECT
+    *
+FROM
+    sample_mods
+WHERE
+    id = ?;
+
 -- name: AddSampleMod :exec
 INSERT INTO
-    sample_mods (id, sample_id, name, time_added, time_removed)
+    sample_mods (id, sample_id, name, time_added, time_removed, added_by)
 VALUES
-    (?, ?, ?, ?, NULL);
+    (?, ?, ?, ?, NULL, ?);
 
 -- name: RemoveSampleMod :exec
 UPDATE sample_mods
 SET
-    time_removed = ?
+    time_removed = ?,
+    removed_by = ?
 WHERE
     id = ?;
 
@@ -170,4 +201,20 @@ SET
 -- name: DeleteUserByID :exec
 DELETE FROM users
 WHERE
-    id = ?;
\ No newline at end of file
+    id = ?;
+-- name: UpdateUserEmail :exec
+UPDATE users
+SET
+    email = ?
+WHERE
+    id = ?;
+
+-- name: ListStaleSamples :many
+SELECT *
+FROM
+    samples
+WHERE
+    last_update < ?
+    AND state NOT IN ('archived', 'unassigned')
+ORDER BY
+    last_update;
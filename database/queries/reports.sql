@@ -0,0 +1,62 @@
+-- name: CountSamplesByState :many
+SELECT
+    state,
+    COUNT(*) AS count
+FROM
+    samples
+GROUP BY
+    state
+ORDER BY
+    state;
+
+-- name: CountSamplesByProduct :many
+SELECT
+    product_id,
+    COUNT(*) AS count
+FROM
+    samples
+GROUP BY
+    product_id;
+
+-- name: CountSamplesByLocation :many
+SELECT
+    location_id,
+    COUNT(*) AS count
+FROM
+    samples
+GROUP BY
+    location_id;
+
+-- name: CountSamplesByOwner :many
+SELECT
+    samples.owner_id,
+    users.name AS owner_name,
+    COUNT(*) AS count
+FROM
+    samples
+LEFT JOIN users ON samples.owner_id = users.id
+GROUP BY
+    samples.owner_id
+ORDER BY
+    count DESC;
+
+-- name: ListSampleRegistrations :many
+SELECT
+    id,
+    time_registered
+FROM
+    samples;
+
+-- name: ListSampleStateChanges :many
+SELECT
+    entity_id,
+    details,
+    time
+FROM
+    activity_log
+WHERE
+    entity_kind = 'sample'
+    AND action IN ('created', 'updated')
+    AND details LIKE '%"state":%'
+ORDER BY
+    time;
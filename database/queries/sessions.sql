@@ -0,0 +1,30 @@
+-- name: CreateSession :exec
+INSERT INTO
+    sessions (id, user_id, created_at, expires_at)
+VALUES
+    (?, ?, ?, ?);
+
+-- name: GetSession :one
+SELECT *
+FROM
+    sessions
+WHERE
+    id = ?;
+
+-- name: DeleteSession :exec
+DELETE FROM sessions
+WHERE
+    id = ?;
+
+-- name: GetUserByOIDCSubject :one
+SELECT *
+FROM
+    users
+WHERE
+    oidc_subject = ?;
+
+-- name: CreateOIDCUser :exec
+INSERT INTO
+    users (id, name, oidc_subject)
+VALUES
+    (?, ?, ?);
@@ -0,0 +1,136 @@
+-- name: CreateWebhook :exec
+INSERT INTO
+    webhooks (
+        id,
+        url,
+        event_types,
+        secret,
+        active,
+        created_by,
+        created_at
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?);
+
+-- name: UpdateWebhook :exec
+UPDATE webhooks
+SET
+    url = ?,
+    event_types = ?,
+    secret = ?,
+    active = ?
+WHERE
+    id = ?;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+WHERE
+    id = ?;
+
+-- name: GetWebhook :one
+SELECT *
+FROM
+    webhooks
+WHERE
+    id = ?;
+
+-- name: ListWebhooks :many
+SELECT *
+FROM
+    webhooks
+ORDER BY
+    created_at;
+
+-- name: ListActiveWebhooks :many
+SELECT *
+FROM
+    webhooks
+WHERE
+    active = 1;
+
+-- name: CreateWebhookDelivery :exec
+INSERT INTO
+    webhook_deliveries (
+        id,
+        webhook_id,
+        event_id,
+        event_type,
+        payload,
+        status,
+        next_attempt_at,
+        created_at
+    )
+VALUES
+    (?, ?, ?, ?, ?, 'pending', ?, ?);
+
+-- name: ListDueWebhookDeliveries :many
+SELECT
+    webhook_deliveries.id,
+    webhook_deliveries.webhook_id,
+    webhook_deliveries.event_type,
+    webhook_deliveries.payload,
+    webhook_deliveries.attempts,
+    webhooks.url,
+    webhooks.secret
+FROM
+    webhook_deliveries
+    JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+WHERE
+    webhook_deliveries.status = 'pending'
+    AND webhook_deliveries.next_attempt_at <= ?
+    AND webhooks.active = 1
+ORDER BY
+    webhook_deliveries.next_attempt_at
+LIMIT
+    ?;
+
+-- name: UpdateWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET
+    status = ?,
+    attempts = ?,
+    next_attempt_at = ?,
+    last_attempt_at = ?
+WHERE
+    id = ?;
+
+-- name: RecordWebhookAttempt :exec
+INSERT INTO
+    webhook_attempts (
+        delivery_id,
+        attempted_at,
+        duration_ms,
+        response_status,
+        response_body,
+        error
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?);
+
+-- name: ListWebhookDeliveries :many
+SELECT *
+FROM
+    webhook_deliveries
+WHERE
+    webhook_id = ?
+ORDER BY
+    created_at DESC
+LIMIT
+    ?;
+
+-- name: GetWebhookDelivery :one
+SELECT *
+FROM
+    webhook_deliveries
+WHERE
+    id = ?
+    AND webhook_id = ?;
+
+-- name: ListWebhookAttempts :many
+SELECT *
+FROM
+    webhook_attempts
+WHERE
+    delivery_id = ?
+ORDER BY
+    id;
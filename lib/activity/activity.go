@@ -0,0 +1,139 @@
+package activity
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	KindSample   = "sample"
+	KindProduct  = "product"
+	KindLocation = "location"
+	KindUser     = "user"
+	KindToken    = "token"
+	KindWebhook  = "webhook"
+)
+
+const (
+	ActionCreated     = "created"
+	ActionUpdated     = "updated"
+	ActionDeleted     = "deleted"
+	ActionModAdded    = "mod_added"
+	ActionModRemoved  = "mod_removed"
+	ActionRevoked     = "revoked"
+	ActionRoleAdded   = "role_added"
+	ActionRoleRemoved = "role_removed"
+	ActionCommented   = "commented"
+	ActionNoted       = "noted"
+	ActionGenerated   = "generated"
+)
+
+// Actor identifies who made a change. Both fields are empty for anonymous requests.
+type Actor struct {
+	UserID  string `json:"user_id,omitempty"`
+	TokenID string `json:"token_id,omitempty"`
+}
+
+// ActorOf returns the actor for the current request.
+func ActorOf(c *gin.Context) Actor {
+	p, ok := auth.CurrentPrincipal(c)
+	if !ok || p.Anonymous {
+		return Actor{}
+	}
+	actor := Actor{UserID: p.UserID.String()}
+	if p.TokenID != nil {
+		actor.TokenID = p.TokenID.String()
+	}
+	return actor
+}
+
+// ActorUserID returns the acting user's binary ID for storing on rows, or nil if anonymous.
+func ActorUserID(c *gin.Context) interface{} {
+	p, ok := auth.CurrentPrincipal(c)
+	if !ok || p.Anonymous {
+		return nil
+	}
+	b, _ := p.UserID.MarshalBinary()
+	return b
+}
+
+func actorTokenID(c *gin.Context) interface{} {
+	p, ok := auth.CurrentPrincipal(c)
+	if !ok || p.TokenID == nil {
+		return nil
+	}
+	b, _ := p.TokenID.MarshalBinary()
+	return b
+}
+
+// Record adds an entry to the activity log for the current request's actor. details is
+// stored as JSON and may be nil. Failing to record history does not fail the request.
+func Record(c *gin.Context, kind string, entityID string, action string, details interface{}) {
+	if err := RecordWith(c, database.Connection, kind, entityID, action, details); err != nil {
+		slog.ErrorContext(c, "Could not record activity", "error", err)
+	}
+}
+
+// RecordWith is Record through q, so the entry can be written in the same transaction as
+// the change. Unlike Record it returns the error, so the caller can roll the change back.
+func RecordWith(c *gin.Context, q *database.Queries, kind string, entityID string, action string, details interface{}) error {
+	var detailsJSON sql.NullString
+	if details != nil {
+		data, err := json.Marshal(details)
+		if err != nil {
+			slog.ErrorContext(c, "Could not encode activity details", "error", err)
+		} else {
+			detailsJSON = sql.NullString{String: string(data), Valid: true}
+		}
+	}
+	id, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return q.RecordActivity(c, database.RecordActivityParams{
+		ID:         id,
+		UserID:     ActorUserID(c),
+		TokenID:    actorTokenID(c),
+		EntityKind: kind,
+		EntityID:   entityID,
+		Action:     action,
+		Details:    detailsJSON,
+		Time:       time.Now(),
+	})
+}
+
+// Entry is an activity log row as returned by the API.
+type Entry struct {
+	ID         string          `json:"id"`
+	UserID     string          `json:"user_id,omitempty"`
+	TokenID    string          `json:"token_id,omitempty"`
+	EntityKind string          `json:"entity_kind"`
+	EntityID   string          `json:"entity_id"`
+	Action     string          `json:"action"`
+	Details    json.RawMessage `json:"details,omitempty"`
+	Time       time.Time       `json:"time"`
+}
+
+func EntryFromRow(row database.ActivityLog) Entry {
+	entry := Entry{
+		ID:         id_helper.BlobToString(row.ID),
+		UserID:     id_helper.BlobToString(row.UserID),
+		TokenID:    id_helper.BlobToString(row.TokenID),
+		EntityKind: row.EntityKind,
+		EntityID:   row.EntityID,
+		Action:     row.Action,
+		Time:       row.Time,
+	}
+	if row.Details.Valid {
+		entry.Details = json.RawMessage(row.Details.String)
+	}
+	return entry
+}
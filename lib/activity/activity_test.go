@@ -0,0 +1,91 @@
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/database"
+	"reesource-tracker/lib/testenv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestRecord(t *testing.T) {
+	testenv.Engines(t, func(t *testing.T) {
+		ctx := context.Background()
+		token, userID := testenv.AdminToken(t)
+		row, err := database.Connection.GetAPITokenByHash(ctx, auth.HashToken(token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _ := row.ID.([]byte)
+		tokenID, err := uuid.FromBytes(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name      string
+			principal *auth.Principal
+			details   interface{}
+			want      Entry
+		}{
+			{
+				name:      "session",
+				principal: &auth.Principal{UserID: userID},
+				details:   gin.H{"name": "Lab"},
+				want:      Entry{UserID: userID.String(), Details: json.RawMessage(`{"name":"Lab"}`)},
+			},
+			{
+				name:      "token",
+				principal: &auth.Principal{UserID: userID, TokenID: &tokenID},
+				want:      Entry{UserID: userID.String(), TokenID: tokenID.String()},
+			},
+			{
+				name:      "anonymous",
+				principal: &auth.Principal{UserID: userID, Anonymous: true},
+				want:      Entry{},
+			},
+			{
+				name: "no principal",
+				want: Entry{},
+			},
+		}
+		rawUserID, _ := userID.MarshalBinary()
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c, _ := gin.CreateTestContext(httptest.NewRecorder())
+				if tt.principal != nil {
+					auth.SetPrincipal(c, tt.principal)
+				}
+				entityID := uuid.NewString()
+				if err := RecordWith(c, database.Connection, KindLocation, entityID, ActionCreated, tt.details); err != nil {
+					t.Fatal(err)
+				}
+				if got := ActorOf(c); got.UserID != tt.want.UserID || got.TokenID != tt.want.TokenID {
+					t.Errorf("ActorOf: got %+v, want user %q token %q", got, tt.want.UserID, tt.want.TokenID)
+				}
+				if tt.want.UserID == "" {
+					return
+				}
+				rows, err := database.Connection.ListUserActivity(ctx, database.ListUserActivityParams{UserID: rawUserID, Limit: 1})
+				if err != nil || len(rows) != 1 {
+					t.Fatalf("listing activity: %v, %d rows", err, len(rows))
+				}
+				got := EntryFromRow(rows[0])
+				if got.UserID != tt.want.UserID || got.TokenID != tt.want.TokenID {
+					t.Errorf("actor: got user %q token %q, want %q %q", got.UserID, got.TokenID, tt.want.UserID, tt.want.TokenID)
+				}
+				if got.EntityKind != KindLocation || got.EntityID != entityID || got.Action != ActionCreated {
+					t.Errorf("entry: got %+v", got)
+				}
+				if string(got.Details) != string(tt.want.Details) {
+					t.Errorf("details: got %s, want %s", got.Details, tt.want.Details)
+				}
+			})
+		}
+	})
+}
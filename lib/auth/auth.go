@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const principalKey = "auth_principal"
+
+// Principal is the identity a request is acting as.
+type Principal struct {
+	UserID      uuid.UUID
+	TokenID     *uuid.UUID // set when the request authenticated with an API token
+	Anonymous   bool
+	Scopes      []string
+	Roles       []string
+	Permissions []string
+}
+
+func (p *Principal) Can(perm string) bool {
+	return slices.Contains(p.Permissions, perm)
+}
+
+func SetPrincipal(c *gin.Context, p *Principal) {
+	c.Set(principalKey, p)
+}
+
+// CurrentPrincipal returns the principal for the request. Requests without credentials get
+// an anonymous principal from Middleware.
+func CurrentPrincipal(c *gin.Context) (*Principal, bool) {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return nil, false
+	}
+	p, ok := value.(*Principal)
+	return p, ok
+}
+
+// LogUser identifies who made a request for the access log: the user ID, with the token ID
+// for API tokens, or "anonymous".
+func LogUser(c *gin.Context) string {
+	p, ok := CurrentPrincipal(c)
+	if !ok || p.Anonymous {
+		return "anonymous"
+	}
+	if p.TokenID != nil {
+		return p.UserID.String() + " (token " + p.TokenID.String() + ")"
+	}
+	return p.UserID.String()
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware authenticates requests carrying an "Authorization: Bearer" API token or a
+// session cookie. Requests without either continue as the anonymous principal.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			authenticateSession(c)
+			return
+		}
+		raw, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || raw == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header"})
+			return
+		}
+		token, err := database.Connection.GetAPITokenByHash(c, HashToken(raw))
+		if errors.Is(err, sql.ErrNoRows) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API token"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		now := time.Now()
+		if token.RevokedAt.Valid {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API token has been revoked"})
+			return
+		}
+		if token.ExpiresAt.Valid && now.After(token.ExpiresAt.Time) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API token has expired"})
+			return
+		}
+
+		userID, err := id_helper.UUIDFromBlob(token.UserID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		tokenID, err := id_helper.UUIDFromBlob(token.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		roles, err := database.Connection.ListUserRoles(c, token.UserID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if roles == nil {
+			roles = []string{}
+		}
+		err = database.Connection.TouchAPIToken(c, database.TouchAPITokenParams{
+			LastUsedAt: sql.NullTime{Time: now, Valid: true},
+			ID:         token.ID,
+		})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		scopes := SplitScopes(token.Scopes)
+		SetPrincipal(c, &Principal{
+			UserID:      userID,
+			TokenID:     &tokenID,
+			Scopes:      scopes,
+			Roles:       roles,
+			Permissions: PermissionsForToken(roles, scopes),
+		})
+		c.Next()
+	}
+}
+
+func authenticateSession(c *gin.Context) {
+	secret, err := c.Cookie(SESSION_COOKIE)
+	if err != nil || secret == "" {
+		SetPrincipal(c, anonymousPrincipal())
+		c.Next()
+		return
+	}
+	p, err := sessionPrincipal(c, secret)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if p == nil {
+		// Stale cookie, e.g. after logging out elsewhere
+		setCookie(c, SESSION_COOKIE, "", "/", -1)
+		p = anonymousPrincipal()
+	}
+	SetPrincipal(c, p)
+	c.Next()
+}
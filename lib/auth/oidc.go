@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reesource-tracker/lib/config"
+	"reesource-tracker/lib/database"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+// OIDCClient handles the OpenID Connect authorization-code flow (with PKCE) against the
+// identity provider configured by the OIDC settings.
+type OIDCClient struct {
+	provider    *oidc.Provider
+	verifier    *oidc.IDTokenVerifier
+	config      oauth2.Config
+	groupsClaim string
+	roleMapping map[string]string
+	defaultRole string
+}
+
+// LoginFlow is the state kept in a short-lived cookie between the login redirect and the callback.
+type LoginFlow struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Redirect string `json:"redirect"`
+}
+
+// Identity is what we take from a verified ID token.
+type Identity struct {
+	Subject string
+	Name    string
+	Email   string
+	Groups  []string
+}
+
+var oidcClient *OIDCClient
+
+// OIDC returns the configured client, or nil if single sign-on is disabled.
+func OIDC() *OIDCClient {
+	return oidcClient
+}
+
+// SetupOIDC configures single sign-on from the OIDC settings in config.Current. It is a no-op
+// when no issuer is set. The client secret is optional for public clients.
+func SetupOIDC(ctx context.Context) error {
+	cfg := config.Current
+	issuer := cfg.OIDCIssuer
+	if issuer == "" {
+		return nil
+	}
+	clientID := cfg.OIDCClientID
+	redirectURL := cfg.OIDCRedirectURL
+	if clientID == "" || redirectURL == "" {
+		return errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC_ISSUER is set")
+	}
+	roleMapping, err := parseRoleMapping(cfg.OIDCRoleMapping)
+	if err != nil {
+		return err
+	}
+	defaultRole := cfg.OIDCDefaultRole
+	if defaultRole != "" && !IsRole(defaultRole) {
+		return fmt.Errorf("OIDC_DEFAULT_ROLE: unknown role %q", defaultRole)
+	}
+	groupsClaim := cfg.OIDCGroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	scopes := strings.Fields(cfg.OIDCScopes)
+	if len(scopes) == 0 {
+		scopes = []string{"profile", "email", "groups"}
+	}
+
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	oidcClient = &OIDCClient{
+		provider: provider,
+		verifier: provider.Verifier(&oidc.Config{ClientID: clientID}),
+		config: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  redirectURL,
+			Scopes:       append([]string{oidc.ScopeOpenID}, scopes...),
+		},
+		groupsClaim: groupsClaim,
+		roleMapping: roleMapping,
+		defaultRole: defaultRole,
+	}
+	return nil
+}
+
+func parseRoleMapping(value string) (map[string]string, error) {
+	mapping := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		if !ok || group == "" || !IsRole(role) {
+			return nil, fmt.Errorf("OIDC_ROLE_MAPPING: invalid entry %q", pair)
+		}
+		mapping[group] = role
+	}
+	return mapping, nil
+}
+
+// NewLoginFlow starts a login, returning the flow state to keep and the URL to send the user to.
+func (o *OIDCClient) NewLoginFlow(redirect string) (LoginFlow, string, error) {
+	state, err := randomSecret()
+	if err != nil {
+		return LoginFlow{}, "", err
+	}
+	nonce, err := randomSecret()
+	if err != nil {
+		return LoginFlow{}, "", err
+	}
+	flow := LoginFlow{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: oauth2.GenerateVerifier(),
+		Redirect: redirect,
+	}
+	url := o.config.AuthCodeURL(flow.State, oauth2.S256ChallengeOption(flow.Verifier), oidc.Nonce(flow.Nonce))
+	return flow, url, nil
+}
+
+// Exchange swaps an authorization code for tokens and returns the verified identity.
+func (o *OIDCClient) Exchange(ctx context.Context, code string, flow LoginFlow) (Identity, error) {
+	token, err := o.config.Exchange(ctx, code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return Identity{}, fmt.Errorf("code exchange failed: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, errors.New("no id_token in token response")
+	}
+	idToken, err := o.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if idToken.Nonce != flow.Nonce {
+		return Identity{}, errors.New("ID token nonce does not match")
+	}
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, err
+	}
+	identity := Identity{Subject: idToken.Subject, Groups: claimStrings(claims[o.groupsClaim])}
+	if verified, _ := claims["email_verified"].(bool); verified {
+		identity.Email, _ = claims["email"].(string)
+	}
+	for _, key := range []string{"name", "preferred_username", "email"} {
+		if name, ok := claims[key].(string); ok && name != "" {
+			identity.Name = name
+			break
+		}
+	}
+	if identity.Name == "" {
+		identity.Name = identity.Subject
+	}
+	return identity, nil
+}
+
+// Provision finds the user for an identity, creating them on first login, and updates
+// their roles from the group mapping. It returns the user's binary ID.
+func (o *OIDCClient) Provision(ctx context.Context, identity Identity) ([]byte, error) {
+	subject := sql.NullString{String: identity.Subject, Valid: true}
+	created := false
+	var userID []byte
+	user, err := database.Connection.GetUserByOIDCSubject(ctx, subject)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		userID, err = uuid.New().MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		err = database.Connection.CreateOIDCUser(ctx, database.CreateOIDCUserParams{
+			ID:          userID,
+			Name:        identity.Name,
+			OidcSubject: subject,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if identity.Email != "" {
+			err = database.Connection.UpdateUserEmail(ctx, database.UpdateUserEmailParams{
+				Email: sql.NullString{String: identity.Email, Valid: true},
+				ID:    userID,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		created = true
+	case err != nil:
+		return nil, err
+	default:
+		var ok bool
+		if userID, ok = user.ID.([]byte); !ok {
+			return nil, errors.New("user has an invalid ID")
+		}
+	}
+
+	roles := o.mapRoles(identity.Groups)
+	if len(o.roleMapping) == 0 && !created {
+		// Without a mapping, roles are managed in the app and left alone after first login
+		return userID, nil
+	}
+	if !created {
+		if err := database.Connection.ClearUserRoles(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	for _, role := range roles {
+		err := database.Connection.AddUserRole(ctx, database.AddUserRoleParams{UserID: userID, Role: role})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return userID, nil
+}
+
+func (o *OIDCClient) mapRoles(groups []string) []string {
+	roles := []string{}
+	for _, group := range groups {
+		if role, ok := o.roleMapping[group]; ok {
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) == 0 && o.defaultRole != "" {
+		roles = append(roles, o.defaultRole)
+	}
+	return roles
+}
+
+// claimStrings reads a claim that may be a single string or a list of strings.
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+const LOGIN_FLOW_COOKIE = "reesource_login_flow"
+
+// SaveLoginFlow keeps the flow state in a cookie scoped to the auth routes for ten minutes.
+func SaveLoginFlow(c *gin.Context, flow LoginFlow) error {
+	data, err := json.Marshal(flow)
+	if err != nil {
+		return err
+	}
+	setCookie(c, LOGIN_FLOW_COOKIE, base64.RawURLEncoding.EncodeToString(data), "/api/auth", 600)
+	return nil
+}
+
+// TakeLoginFlow reads and clears the flow state saved by SaveLoginFlow.
+func TakeLoginFlow(c *gin.Context) (LoginFlow, error) {
+	var flow LoginFlow
+	value, err := c.Cookie(LOGIN_FLOW_COOKIE)
+	if err != nil {
+		return flow, errors.New("login flow expired, please try again")
+	}
+	setCookie(c, LOGIN_FLOW_COOKIE, "", "/api/auth", -1)
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return flow, err
+	}
+	err = json.Unmarshal(data, &flow)
+	return flow, err
+}
+
+// SafeRedirect only allows local paths as post-login redirects.
+func SafeRedirect(redirect string) string {
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.HasPrefix(redirect, "/\\") {
+		return "/app"
+	}
+	return redirect
+}
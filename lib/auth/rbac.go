@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"net/http"
+	"reesource-tracker/lib/config"
+	"slices"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RoleViewer     = "viewer"
+	RoleTechnician = "technician"
+	RoleAdmin      = "admin"
+)
+
+var Roles = []string{RoleViewer, RoleTechnician, RoleAdmin}
+
+const (
+	PermSamplesRead             = "samples:read"
+	PermSamplesWrite            = "samples:write"
+	PermProductsRead            = "products:read"
+	PermProductsWrite           = "products:write"
+	PermProductsDelete          = "products:delete"
+	PermLocationsRead           = "locations:read"
+	PermLocationsWrite          = "locations:write"
+	PermLocationsDelete         = "locations:delete"
+	PermUsersRead               = "users:read"
+	PermUsersWrite              = "users:write"
+	PermUsersDelete             = "users:delete"
+	PermRolesManage             = "roles:manage"
+	PermTokensManageSelf        = "tokens:manage_self"
+	PermWebhooksManage          = "webhooks:manage"
+	PermNotificationsManageSelf = "notifications:manage_self"
+	PermJobsManage              = "jobs:manage"
+	PermBackupsManage           = "backups:manage"
+	PermMetricsRead             = "metrics:read"
+)
+
+var readPermissions = []string{
+	PermSamplesRead,
+	PermProductsRead,
+	PermLocationsRead,
+	PermUsersRead,
+	PermMetricsRead,
+}
+
+// RolePermissions lists what each role is allowed to do. Roles are not hierarchical in storage,
+// so each entry spells out the full set.
+var RolePermissions = map[string][]string{
+	RoleViewer: append(slices.Clone(readPermissions),
+		PermNotificationsManageSelf,
+	),
+	RoleTechnician: append(slices.Clone(readPermissions),
+		PermNotificationsManageSelf,
+		PermSamplesWrite,
+		PermTokensManageSelf,
+	),
+	RoleAdmin: append(slices.Clone(readPermissions),
+		PermNotificationsManageSelf,
+		PermSamplesWrite,
+		PermTokensManageSelf,
+		PermProductsWrite,
+		PermProductsDelete,
+		PermLocationsWrite,
+		PermLocationsDelete,
+		PermUsersWrite,
+		PermUsersDelete,
+		PermRolesManage,
+		PermWebhooksManage,
+		PermJobsManage,
+		PermBackupsManage,
+	),
+}
+
+// scopePermissions limits what an API token can do, on top of its owner's roles.
+var scopePermissions = map[string][]string{
+	ScopeReadOnly:     readPermissions,
+	ScopeSamplesWrite: append(slices.Clone(readPermissions), PermSamplesWrite),
+	ScopeAdmin:        RolePermissions[RoleAdmin],
+}
+
+func IsRole(role string) bool {
+	return slices.Contains(Roles, role)
+}
+
+// PermissionsForRoles returns the sorted union of the permissions granted by roles.
+func PermissionsForRoles(roles []string) []string {
+	set := map[string]bool{}
+	for _, role := range roles {
+		for _, perm := range RolePermissions[role] {
+			set[perm] = true
+		}
+	}
+	return sortedKeys(set)
+}
+
+// PermissionsForToken returns the permissions of a token: the user's role permissions
+// narrowed down to what the token's scopes allow.
+func PermissionsForToken(roles []string, scopes []string) []string {
+	allowed := map[string]bool{}
+	for _, scope := range scopes {
+		for _, perm := range scopePermissions[scope] {
+			allowed[perm] = true
+		}
+	}
+	set := map[string]bool{}
+	for _, perm := range PermissionsForRoles(roles) {
+		if allowed[perm] {
+			set[perm] = true
+		}
+	}
+	return sortedKeys(set)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AnonymousRole is the configured role given to requests without credentials. By default,
+// or if it is "none", they get no role and must authenticate; giving them admin has to be
+// asked for explicitly.
+func AnonymousRole() string {
+	role := config.Current.AnonymousRole
+	if !IsRole(role) {
+		return ""
+	}
+	return role
+}
+
+func anonymousPrincipal() *Principal {
+	roles := []string{}
+	if role := AnonymousRole(); role != "" {
+		roles = append(roles, role)
+	}
+	return &Principal{Anonymous: true, Roles: roles, Permissions: PermissionsForRoles(roles)}
+}
+
+// Forbidden aborts the request with the standard permission error.
+func Forbidden(c *gin.Context, perm string) {
+	p, _ := CurrentPrincipal(c)
+	if p == nil || (p.Anonymous && len(p.Permissions) == 0) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permission denied", "permission": perm})
+}
+
+// Require only lets the request through if the current principal has perm.
+func Require(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := CurrentPrincipal(c)
+		if !ok || !p.Can(perm) {
+			Forbidden(c, perm)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSelfOr lets the request through if the user_id route parameter is the current
+// user and they have selfPerm, or if they have perm.
+func RequireSelfOr(selfPerm string, perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := CurrentPrincipal(c)
+		if ok && p.Can(perm) {
+			c.Next()
+			return
+		}
+		if ok && !p.Anonymous && p.UserID.String() == c.Param("user_id") && p.Can(selfPerm) {
+			c.Next()
+			return
+		}
+		Forbidden(c, perm)
+	}
+}
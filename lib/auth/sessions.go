@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"reesource-tracker/lib/config"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const SESSION_COOKIE = "reesource_session"
+
+// StartSession creates a session for the user and sets the session cookie.
+// Only a hash of the cookie value is stored.
+func StartSession(c *gin.Context, userID []byte) error {
+	secret, err := randomSecret()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	duration := config.Current.SessionDuration
+	err = database.Connection.CreateSession(c, database.CreateSessionParams{
+		ID:        HashToken(secret),
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(duration),
+	})
+	if err != nil {
+		return err
+	}
+	setCookie(c, SESSION_COOKIE, secret, "/", int(duration.Seconds()))
+	return nil
+}
+
+// EndSession removes the current session, if there is one, and clears the cookie.
+func EndSession(c *gin.Context) error {
+	secret, err := c.Cookie(SESSION_COOKIE)
+	setCookie(c, SESSION_COOKIE, "", "/", -1)
+	if err != nil || secret == "" {
+		return nil
+	}
+	return database.Connection.DeleteSession(c, HashToken(secret))
+}
+
+// sessionPrincipal returns the principal for a session cookie, or nil if the session is
+// unknown or expired.
+func sessionPrincipal(c *gin.Context, secret string) (*Principal, error) {
+	session, err := database.Connection.GetSession(c, HashToken(secret))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, database.Connection.DeleteSession(c, session.ID)
+	}
+	userID, err := id_helper.UUIDFromBlob(session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := database.Connection.ListUserRoles(c, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	return &Principal{UserID: userID, Roles: roles, Permissions: PermissionsForRoles(roles)}, nil
+}
+
+func setCookie(c *gin.Context, name string, value string, path string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(name, value, maxAge, path, "", secure, true)
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	ScopeReadOnly     = "read-only"
+	ScopeSamplesWrite = "samples:write"
+	ScopeAdmin        = "admin"
+)
+
+const TOKEN_PREFIX = "rst_"
+
+var Scopes = []string{ScopeReadOnly, ScopeSamplesWrite, ScopeAdmin}
+
+// GenerateToken returns a new random API token and the hash that should be stored for it.
+// The plaintext token is only ever shown to the user once.
+func GenerateToken() (string, []byte, error) {
+	secret, err := randomSecret()
+	if err != nil {
+		return "", nil, err
+	}
+	token := TOKEN_PREFIX + secret
+	return token, HashToken(token), nil
+}
+
+func randomSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func HashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// ParseScopes validates a list of scopes and returns them in their stored (comma separated) form.
+func ParseScopes(scopes []string) (string, error) {
+	if len(scopes) == 0 {
+		return ScopeReadOnly, nil
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return "", fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	return strings.Join(scopes, ","), nil
+}
+
+func SplitScopes(scopes string) []string {
+	if scopes == "" {
+		return []string{}
+	}
+	return strings.Split(scopes, ",")
+}
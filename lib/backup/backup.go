@@ -0,0 +1,167 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reesource-tracker/lib/config"
+	"reesource-tracker/lib/database"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	FILE_PREFIX = "db-"
+	FILE_SUFFIX = ".sqlite"
+	// Milliseconds keep backups made in the same second apart. Names sort chronologically.
+	TIME_FORMAT = "20060102-150405.000"
+	// MAX_NAME_ATTEMPTS bounds how far Create moves a backup's time to find a free name.
+	MAX_NAME_ATTEMPTS = 1000
+)
+
+var ErrNotFound = errors.New("backup not found")
+
+// Backup describes a backup file.
+type Backup struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"-"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Dir is where backups are written, the configured backup directory or "backups" next to
+// the database.
+func Dir() string {
+	if dir := config.Current.BackupDir; dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(config.Current.DBPath), "backups")
+}
+
+// ErrUnsupported is returned on PostgreSQL, which should be backed up with its own tools
+// such as pg_dump.
+var ErrUnsupported = errors.New("backups are only supported on SQLite, use pg_dump for PostgreSQL")
+
+// Create writes a consistent copy of the database to a new timestamped file in Dir using
+// VACUUM INTO, which is safe while the server is handling requests. It returns the file's path.
+func Create(ctx context.Context) (string, error) {
+	if config.Current.DBDriver != config.DRIVER_SQLITE {
+		return "", ErrUnsupported
+	}
+	dir := Dir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path, err := reserve(dir, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+	// VACUUM INTO writes into an existing file as long as it's empty
+	if _, err := database.DB.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// reserve creates an empty backup file named after at, or the next free millisecond if
+// another backup has that name, and returns its path.
+func reserve(dir string, at time.Time) (string, error) {
+	for i := 0; i < MAX_NAME_ATTEMPTS; i++ {
+		path := filepath.Join(dir, FILE_PREFIX+at.Format(TIME_FORMAT)+FILE_SUFFIX)
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			return path, file.Close()
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		at = at.Add(time.Millisecond)
+	}
+	return "", fmt.Errorf("no free backup name in %s", dir)
+}
+
+// List returns the backup files in Dir, newest first.
+func List() ([]string, error) {
+	entries, err := os.ReadDir(Dir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, FILE_PREFIX) && strings.HasSuffix(name, FILE_SUFFIX) {
+			files = append(files, filepath.Join(Dir(), name))
+		}
+	}
+	// The timestamp format sorts chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	return files, nil
+}
+
+// All describes the backup files in Dir, newest first.
+func All() ([]Backup, error) {
+	files, err := List()
+	if err != nil {
+		return nil, err
+	}
+	backups := []Backup{}
+	for _, path := range files {
+		backup, err := describe(path)
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, backup)
+	}
+	return backups, nil
+}
+
+// Get describes the backup with the given file name. Only files in Dir are found, so the
+// name can come from a request.
+func Get(name string) (Backup, error) {
+	if name != filepath.Base(name) || !strings.HasPrefix(name, FILE_PREFIX) || !strings.HasSuffix(name, FILE_SUFFIX) {
+		return Backup{}, ErrNotFound
+	}
+	backup, err := describe(filepath.Join(Dir(), name))
+	if os.IsNotExist(err) {
+		return Backup{}, ErrNotFound
+	}
+	return backup, err
+}
+
+func describe(path string) (Backup, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Backup{}, err
+	}
+	name := filepath.Base(path)
+	// Parsing accepts the milliseconds without them in the layout, so this also reads names
+	// from before they were added
+	created, err := time.Parse("20060102-150405", strings.TrimSuffix(strings.TrimPrefix(name, FILE_PREFIX), FILE_SUFFIX))
+	if err != nil {
+		created = info.ModTime().UTC()
+	}
+	return Backup{Name: name, Path: path, Size: info.Size(), CreatedAt: created}, nil
+}
+
+// Prune deletes all but the newest keep backups, returning the deleted paths.
+func Prune(keep int) ([]string, error) {
+	files, err := List()
+	if err != nil || len(files) <= keep {
+		return nil, err
+	}
+	removed := []string{}
+	for _, path := range files[keep:] {
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
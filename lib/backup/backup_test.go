@@ -0,0 +1,187 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reesource-tracker/lib/config"
+	"reesource-tracker/lib/database"
+	sqlite_driver "reesource-tracker/lib/database/drivers/sqlite"
+	"reesource-tracker/lib/testenv"
+	"testing"
+	"time"
+)
+
+// Backups taken in the same second, or even the same millisecond, get their own files.
+func TestCreateSameSecond(t *testing.T) {
+	testenv.Open(t, config.DRIVER_SQLITE)
+	ctx := context.Background()
+	created := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		path, err := Create(ctx)
+		if err != nil {
+			t.Fatalf("backup %d: %v", i, err)
+		}
+		created[path] = true
+	}
+	at := time.Now().UTC()
+	for i := 0; i < 2; i++ {
+		path, err := reserve(Dir(), at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		created[path] = true
+	}
+	files, err := List()
+	if err != nil || len(files) != 5 || len(created) != 5 {
+		t.Fatalf("got %v, %v from %d backups, want 5", files, err, len(created))
+	}
+	// Newest first, with the one moved to the next millisecond ahead of the other
+	for i := 1; i < len(files); i++ {
+		newer, _ := describe(files[i-1])
+		older, _ := describe(files[i])
+		if !newer.CreatedAt.After(older.CreatedAt) {
+			t.Errorf("%s is listed before %s", newer.Name, older.Name)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		want time.Time
+	}{
+		{name: "db-20260102-030405.678.sqlite", want: time.Date(2026, 1, 2, 3, 4, 5, 678e6, time.UTC)},
+		{name: "db-20260102-030405.sqlite", want: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := describe(path); err != nil || !got.CreatedAt.Equal(tt.want) {
+				t.Errorf("got %s, %v, want %s", got.CreatedAt, err, tt.want)
+			}
+		})
+	}
+}
+
+// A backup restores the data it was taken with, but not while the server has the database
+// open.
+func TestRestore(t *testing.T) {
+	testenv.Open(t, config.DRIVER_SQLITE)
+	ctx := context.Background()
+	path, err := Create(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testenv.AdminToken(t)
+	if got := countUsers(t); got != 1 {
+		t.Fatalf("got %d users before restoring, want 1", got)
+	}
+
+	if _, _, err := Restore(ctx, path); !errors.Is(err, ErrDatabaseInUse) {
+		t.Fatalf("restoring while the database is open: got %v, want %v", err, ErrDatabaseInUse)
+	}
+	if got := countUsers(t); got != 1 {
+		t.Fatalf("got %d users after the refused restore, want 1", got)
+	}
+	if _, err := os.Stat(config.Current.DBPath + ".restoring"); !os.IsNotExist(err) {
+		t.Errorf("the refused restore left a copy behind: %v", err)
+	}
+
+	if err := database.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	report, previous, err := Restore(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Integrity != "ok" || report.Version != report.LatestVersion {
+		t.Errorf("got report %+v", report)
+	}
+	if err := database.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := countUsers(t); got != 0 {
+		t.Errorf("got %d users after restoring, want the backup's 0", got)
+	}
+	// The replaced database is kept, and can itself be restored
+	if _, err := Verify(ctx, previous); err != nil {
+		t.Errorf("verifying the replaced database %s: %v", previous, err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	testenv.Open(t, config.DRIVER_SQLITE)
+	ctx := context.Background()
+	dir := t.TempDir()
+	garbage := filepath.Join(dir, "garbage.sqlite")
+	if err := os.WriteFile(garbage, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty.sqlite")
+	if _, err := database.DB.ExecContext(ctx, "VACUUM INTO ?", empty); err != nil {
+		t.Fatal(err)
+	}
+	newer := filepath.Join(dir, "newer.sqlite")
+	if _, err := database.DB.ExecContext(ctx, "VACUUM INTO ?", newer); err != nil {
+		t.Fatal(err)
+	}
+	dirty := filepath.Join(dir, "dirty.sqlite")
+	if _, err := database.DB.ExecContext(ctx, "VACUUM INTO ?", dirty); err != nil {
+		t.Fatal(err)
+	}
+	for path, update := range map[string]string{
+		empty: "DROP TABLE schema_migrations",
+		newer: "UPDATE schema_migrations SET version = version + 1",
+		dirty: "UPDATE schema_migrations SET dirty = 1",
+	} {
+		if err := execFile(ctx, path, update); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		path string
+		err  error
+	}{
+		{name: "missing", path: filepath.Join(dir, "missing.sqlite"), err: os.ErrNotExist},
+		{name: "not a database", path: garbage, err: ErrNotDatabase},
+		{name: "no migrations", path: empty, err: ErrNotDatabase},
+		{name: "newer", path: newer, err: ErrNewerSchema},
+		{name: "dirty", path: dirty, err: ErrDirty},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(ctx, tt.path); !errors.Is(err, tt.err) {
+				t.Errorf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func countUsers(t *testing.T) int {
+	t.Helper()
+	var count int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+// execFile runs a statement against the SQLite database at path.
+func execFile(ctx context.Context, path string, query string) error {
+	db, err := sql.Open(sqlite_driver.DRIVER_NAME, path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.ExecContext(ctx, query)
+	return err
+}
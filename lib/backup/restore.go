@@ -0,0 +1,187 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reesource-tracker/lib/config"
+	sqlite_driver "reesource-tracker/lib/database/drivers/sqlite"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCorrupt       = errors.New("backup failed the integrity check")
+	ErrNotDatabase   = errors.New("backup is not a reesource-tracker database")
+	ErrDirty         = errors.New("backup was taken while a migration was failing")
+	ErrNewerSchema   = errors.New("backup is from a newer version of the server")
+	ErrDatabaseInUse = errors.New("the database is in use, stop the server before restoring")
+)
+
+// Report is the result of checking a backup.
+type Report struct {
+	// Integrity is "ok", or the problems found by PRAGMA integrity_check
+	Integrity string `json:"integrity"`
+	// Version is the migration the backup was taken at
+	Version uint `json:"version"`
+	Dirty   bool `json:"dirty"`
+	// LatestVersion is the newest migration this server has. Backups at an older version
+	// are migrated when the server starts.
+	LatestVersion uint `json:"latest_version"`
+}
+
+// Verify opens a backup read-only and checks its integrity and migration version.
+func Verify(ctx context.Context, path string) (Report, error) {
+	report := Report{}
+	latest, err := latestMigration()
+	if err != nil {
+		return report, err
+	}
+	report.LatestVersion = latest
+	if _, err := os.Stat(path); err != nil {
+		return report, err
+	}
+
+	db, err := sql.Open(sqlite_driver.DRIVER_NAME, "file:"+path+"?mode=ro")
+	if err != nil {
+		return report, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return report, fmt.Errorf("%w: %w", ErrNotDatabase, err)
+	}
+	problems := []string{}
+	for rows.Next() {
+		var problem string
+		if err := rows.Scan(&problem); err != nil {
+			rows.Close()
+			return report, err
+		}
+		problems = append(problems, problem)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, fmt.Errorf("%w: %w", ErrNotDatabase, err)
+	}
+	report.Integrity = strings.Join(problems, "\n")
+	if report.Integrity != "ok" {
+		return report, ErrCorrupt
+	}
+
+	err = db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&report.Version, &report.Dirty)
+	if err != nil {
+		return report, ErrNotDatabase
+	}
+	if report.Dirty {
+		return report, ErrDirty
+	}
+	if report.Version > report.LatestVersion {
+		return report, ErrNewerSchema
+	}
+	return report, nil
+}
+
+// latestMigration is the highest version in the migrations directory.
+func latestMigration() (uint, error) {
+	files, err := filepath.Glob(filepath.Join(config.Current.MigrationsPath, "*.up.sql"))
+	if err != nil {
+		return 0, err
+	}
+	var latest uint
+	for _, file := range files {
+		prefix, _, _ := strings.Cut(filepath.Base(file), "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err == nil && uint(version) > latest {
+			latest = uint(version)
+		}
+	}
+	if latest == 0 {
+		return 0, fmt.Errorf("no migrations found in %s", config.Current.MigrationsPath)
+	}
+	return latest, nil
+}
+
+// Restore verifies a backup and swaps it in as the database. The server must be stopped.
+// The current database is kept next to it, renamed with a .before-restore-<time> suffix,
+// and its path is returned.
+func Restore(ctx context.Context, path string) (Report, string, error) {
+	if config.Current.DBDriver != config.DRIVER_SQLITE {
+		return Report{}, "", ErrUnsupported
+	}
+	report, err := Verify(ctx, path)
+	if err != nil {
+		return report, "", err
+	}
+	target := config.Current.DBPath
+	if err := checkNotInUse(ctx, target); err != nil {
+		return report, "", err
+	}
+
+	// Copy next to the database first, so the swap is a rename on the same file system
+	restoring := target + ".restoring"
+	if err := copyFile(path, restoring); err != nil {
+		os.Remove(restoring)
+		return report, "", err
+	}
+	previous := ""
+	if _, err := os.Stat(target); err == nil {
+		previous = target + ".before-restore-" + time.Now().UTC().Format(TIME_FORMAT)
+		// The WAL belongs to the old database, so it moves with it
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			err := os.Rename(target+suffix, previous+suffix)
+			if err != nil && !os.IsNotExist(err) {
+				os.Remove(restoring)
+				return report, "", err
+			}
+		}
+	}
+	if err := os.Rename(restoring, target); err != nil {
+		return report, previous, err
+	}
+	return report, previous, nil
+}
+
+// checkNotInUse fails if another process, such as the server, has the database open.
+func checkNotInUse(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	db, err := sql.Open(sqlite_driver.DRIVER_NAME, path+"?_pragma=locking_mode(EXCLUSIVE)")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	// Taking a write lock in exclusive locking mode fails while anyone else has the file open
+	if _, err := db.ExecContext(ctx, "BEGIN EXCLUSIVE; COMMIT;"); err != nil {
+		return fmt.Errorf("%w: %w", ErrDatabaseInUse, err)
+	}
+	return nil
+}
+
+func copyFile(from string, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
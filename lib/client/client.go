@@ -0,0 +1,105 @@
+// Package client is a typed Go client for the server's API, for scripts and test automation.
+// IDs are decoded from the API's base64 blobs, so samples are identified by their XX-XX-XX
+// IDs and everything else by uuid.UUID.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls the API of one server. The zero value isn't usable; create one with New.
+type Client struct {
+	// BaseURL is the server's address, e.g. http://localhost:8080. Requests go to /api below it.
+	BaseURL string
+	// Token is an API token sent as a bearer token. Requests without one are anonymous.
+	Token string
+	// HTTPClient makes the requests, http.DefaultClient unless set
+	HTTPClient *http.Client
+}
+
+func New(baseURL string, token string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), Token: token, HTTPClient: http.DefaultClient}
+}
+
+// request builds a request to path below /api.
+func (c *Client) request(ctx context.Context, method string, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	target := c.BaseURL + "/api" + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return req, nil
+}
+
+// send makes a request and decodes the JSON response into out, if it isn't nil. Responses
+// other than 2xx are returned as an *Error.
+func (c *Client) send(req *http.Request, out any) error {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return responseError(res)
+	}
+	if out == nil {
+		_, err = io.Copy(io.Discard, res.Body)
+		return err
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// getJSON sends a GET request.
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, out any) error {
+	req, err := c.request(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	return c.send(req, out)
+}
+
+// sendJSON sends a request with body encoded as JSON, or without a body if it is nil.
+func (c *Client) sendJSON(ctx context.Context, method string, path string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := c.request(ctx, method, path, nil, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, out)
+}
+
+// sendForm sends a request with a URL encoded form.
+func (c *Client) sendForm(ctx context.Context, method string, path string, form url.Values, out any) error {
+	req, err := c.request(ctx, method, path, nil, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.send(req, out)
+}
@@ -0,0 +1,282 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reesource-tracker/api"
+	"reesource-tracker/lib/client"
+	"reesource-tracker/lib/config"
+	"reesource-tracker/lib/testenv"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// newServer serves the API on a fresh database and returns a client for it with an admin
+// token. Requests are passed to inspect, if it isn't nil, before the API handles them.
+func newServer(t *testing.T, inspect func(*http.Request)) (*httptest.Server, *client.Client) {
+	t.Helper()
+	testenv.Open(t, config.DRIVER_SQLITE)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api.Routes(r)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if inspect != nil {
+			inspect(req)
+		}
+		r.ServeHTTP(w, req)
+	}))
+	t.Cleanup(srv.Close)
+	token, _ := testenv.AdminToken(t)
+	return srv, client.New(srv.URL, token)
+}
+
+func TestInventory(t *testing.T) {
+	_, c := newServer(t, nil)
+	ctx := context.Background()
+
+	family, err := c.CreateProduct(ctx, "Boards", uuid.Nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := c.CreateProduct(ctx, "Board v2", family)
+	if err != nil {
+		t.Fatal(err)
+	}
+	product, err := c.GetProduct(ctx, model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.Name != "Board v2" || product.ParentID != family {
+		t.Errorf("GetProduct: got %+v", product)
+	}
+	product.PartNumber = "BV2"
+	if err := c.UpdateProduct(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+	products, err := c.ListProducts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 2 {
+		t.Errorf("ListProducts: got %d, want 2", len(products))
+	}
+
+	lab, err := c.CreateLocation(ctx, "Lab", "Ground floor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shelf, err := c.CreateLocation(ctx, "Shelf", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UpdateLocation(ctx, client.Location{ID: shelf, Name: "Shelf 1", ParentID: lab}); err != nil {
+		t.Fatal(err)
+	}
+	location, err := c.GetLocation(ctx, shelf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Name != "Shelf 1" || location.ParentID != lab {
+		t.Errorf("GetLocation: got %+v", location)
+	}
+
+	owner, err := c.CreateUser(ctx, "Grace", "grace@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := c.GetUser(ctx, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "grace@example.com" {
+		t.Errorf("GetUser: got %+v", user)
+	}
+	user.Email = ""
+	if err := c.UpdateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ = c.GetUser(ctx, owner); user.Email != "" {
+		t.Errorf("UpdateUser didn't remove the email: got %+v", user)
+	}
+
+	ids, err := c.GenerateSamples(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("GenerateSamples: got %v", ids)
+	}
+	sample, err := c.UpdateSample(ctx, ids[0], client.SampleUpdate{
+		LocationID: shelf,
+		ProductID:  model,
+		OwnerID:    owner,
+		State:      client.StateInUse,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample.ID != ids[0] || sample.State != client.StateInUse || sample.OwnerID != owner {
+		t.Errorf("UpdateSample: got %+v", sample)
+	}
+
+	if err := c.AddMod(ctx, ids[0], "Bodge wire"); err != nil {
+		t.Fatal(err)
+	}
+	mods, err := c.ListMods(ctx, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mods) != 1 || !mods[0].Current() || mods[0].SampleID != ids[0] {
+		t.Fatalf("ListMods: got %+v", mods)
+	}
+	if err := c.RemoveMod(ctx, ids[0], mods[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	sample, err = c.GetSample(ctx, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sample.Mods) != 1 || sample.Mods[0].Current() {
+		t.Errorf("GetSample: got mods %+v, want one removed", sample.Mods)
+	}
+
+	samples, err := c.ListSamples(ctx, client.SampleFilter{Product: family, ProductTree: true, Location: lab, LocationTree: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].ID != ids[0] || samples[0].OwnerName != "Grace" {
+		t.Errorf("ListSamples below Boards in Lab: got %+v", samples)
+	}
+	samples, err = c.ListSamples(ctx, client.SampleFilter{States: []string{client.StateUnassigned}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].ID != ids[1] {
+		t.Errorf("ListSamples unassigned: got %+v", samples)
+	}
+
+	spare, err := c.CreateLocation(ctx, "Spare", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteLocation(ctx, spare); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetLocation(ctx, spare); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("GetLocation after delete: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv, c := newServer(t, nil)
+	ctx := context.Background()
+
+	anonymous := client.New(srv.URL, "")
+	_, err := anonymous.ListProducts(ctx)
+	var apiErr *client.Error
+	if !errors.Is(err, client.ErrUnauthorized) || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("anonymous ListProducts: got %v, want ErrUnauthorized", err)
+	}
+	if _, err := c.GetProduct(ctx, uuid.New()); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("GetProduct of a missing product: got %v, want ErrNotFound", err)
+	}
+	if _, err := c.UpdateSample(ctx, "not-an-id", client.SampleUpdate{State: client.StateAvailable}); !errors.Is(err, client.ErrBadRequest) {
+		t.Errorf("UpdateSample with an invalid ID: got %v, want ErrBadRequest", err)
+	}
+}
+
+// The stream is dropped while a change is made, which Subscribe should receive once it has
+// reconnected with the ID of the last event it saw.
+func TestSubscribeReconnects(t *testing.T) {
+	var (
+		mu            sync.Mutex
+		lastEventIDs  []string
+		subscriptions int
+	)
+	srv, c := newServer(t, func(req *http.Request) {
+		if req.URL.Path == "/api/sync" {
+			mu.Lock()
+			subscriptions++
+			lastEventIDs = append(lastEventIDs, req.Header.Get("Last-Event-ID"))
+			mu.Unlock()
+		}
+	})
+	// Changes are made on their own connections, so dropping the stream doesn't drop them
+	writer := *c
+	writer.HTTPClient = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events := make(chan client.Event, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Subscribe(ctx, client.Topics{}, func(evt client.Event) { events <- evt })
+	}()
+	next := func(eventType string) client.Event {
+		t.Helper()
+		for {
+			select {
+			case evt := <-events:
+				if evt.Type == eventType {
+					return evt
+				}
+			case <-ctx.Done():
+				t.Fatalf("timed out waiting for %s", eventType)
+			}
+		}
+	}
+
+	next(client.EventInfo)
+	first, err := writer.CreateProduct(ctx, "First", uuid.Nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evt := next(client.EventProductsUpdated)
+	if change, err := evt.Change(); err != nil || change.ID != first.String() || change.Op != client.OpCreated {
+		t.Fatalf("first change: got %+v, %v", change, err)
+	}
+
+	srv.CloseClientConnections()
+	second, err := writer.CreateProduct(ctx, "Second", uuid.Nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next(client.EventInfo)
+	missed := next(client.EventProductsUpdated)
+	change, err := missed.Change()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product, err := change.Product(); err != nil || product.ID != second || product.Name != "Second" {
+		t.Errorf("missed change: got %+v, %v", product, err)
+	}
+
+	mu.Lock()
+	resumed := strconv.FormatUint(evt.ID, 10)
+	if subscriptions != 2 || lastEventIDs[0] != "" || lastEventIDs[1] != resumed {
+		t.Errorf("subscriptions: got %d with Last-Event-IDs %q, want 2 resuming after %s", subscriptions, lastEventIDs, resumed)
+	}
+	mu.Unlock()
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Subscribe: got %v, want context.Canceled", err)
+	}
+}
+
+func TestSubscribeUnauthorized(t *testing.T) {
+	srv, _ := newServer(t, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := client.New(srv.URL, "").Subscribe(ctx, client.Topics{}, func(client.Event) {})
+	if !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("got %v, want ErrUnauthorized", err)
+	}
+}
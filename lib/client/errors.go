@@ -0,0 +1,74 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Errors matching the status of an *Error, for use with errors.Is
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("permission denied")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrServer       = errors.New("server error")
+)
+
+// Error is a response other than 2xx.
+type Error struct {
+	StatusCode int
+	// Message is the "error" field of the response, or its body if it isn't JSON
+	Message string
+	// Permission is the permission the request was missing, for 403 responses
+	Permission string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is matches the Err* values by status code, so errors.Is(err, client.ErrNotFound) works.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnsupportedMediaType || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// MAX_ERROR_BODY is how much of a response that isn't JSON is kept as the message.
+const MAX_ERROR_BODY = 1024
+
+func responseError(res *http.Response) *Error {
+	e := &Error{StatusCode: res.StatusCode}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, MAX_ERROR_BODY))
+	var decoded struct {
+		Error      string `json:"error"`
+		Permission string `json:"permission"`
+	}
+	if json.Unmarshal(body, &decoded) == nil && decoded.Error != "" {
+		e.Message = decoded.Error
+		e.Permission = decoded.Permission
+	} else {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	return e
+}
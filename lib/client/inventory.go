@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// created is the reply to a create request.
+type created struct {
+	ID uuid.UUID `json:"id"`
+}
+
+func (c *Client) ListProducts(ctx context.Context) ([]Product, error) {
+	var rows []productRow
+	if err := c.getJSON(ctx, "/products", nil, &rows); err != nil {
+		return nil, err
+	}
+	return convert(rows, productRow.product), nil
+}
+
+func (c *Client) GetProduct(ctx context.Context, id uuid.UUID) (Product, error) {
+	var row productRow
+	if err := c.getJSON(ctx, "/product/"+id.String(), nil, &row); err != nil {
+		return Product{}, err
+	}
+	return row.product(), nil
+}
+
+// CreateProduct adds a product below parent, or at the top if it is uuid.Nil, and returns
+// its ID.
+func (c *Client) CreateProduct(ctx context.Context, name string, parent uuid.UUID) (uuid.UUID, error) {
+	body := map[string]any{"name": name}
+	if parent != uuid.Nil {
+		body["parent_product_id"] = parent.String()
+	}
+	var res created
+	err := c.sendJSON(ctx, http.MethodPost, "/product", body, &res)
+	return res.ID, err
+}
+
+// UpdateProduct writes every field of a product except its ID.
+func (c *Client) UpdateProduct(ctx context.Context, product Product) error {
+	body := map[string]string{
+		"name":              product.Name,
+		"parent_product_id": optionalUUID(product.ParentID),
+		"part_number":       product.PartNumber,
+	}
+	return c.sendJSON(ctx, http.MethodPost, "/product/"+product.ID.String(), body, nil)
+}
+
+func (c *Client) DeleteProduct(ctx context.Context, id uuid.UUID) error {
+	return c.sendJSON(ctx, http.MethodDelete, "/product/"+id.String(), nil, nil)
+}
+
+func (c *Client) ListLocations(ctx context.Context) ([]Location, error) {
+	var rows []locationRow
+	if err := c.getJSON(ctx, "/locations", nil, &rows); err != nil {
+		return nil, err
+	}
+	return convert(rows, locationRow.location), nil
+}
+
+func (c *Client) GetLocation(ctx context.Context, id uuid.UUID) (Location, error) {
+	var row locationRow
+	if err := c.getJSON(ctx, "/location/"+id.String(), nil, &row); err != nil {
+		return Location{}, err
+	}
+	return row.location(), nil
+}
+
+// CreateLocation adds a location at the top of the tree and returns its ID. Move it with
+// UpdateLocation.
+func (c *Client) CreateLocation(ctx context.Context, name string, description string) (uuid.UUID, error) {
+	body := map[string]string{"name": name, "description": description}
+	var res created
+	err := c.sendJSON(ctx, http.MethodPost, "/location", body, &res)
+	return res.ID, err
+}
+
+// UpdateLocation writes every field of a location except its ID.
+func (c *Client) UpdateLocation(ctx context.Context, location Location) error {
+	body := map[string]string{
+		"name":               location.Name,
+		"description":        location.Description,
+		"parent_location_id": optionalUUID(location.ParentID),
+	}
+	return c.sendJSON(ctx, http.MethodPost, "/location/"+location.ID.String(), body, nil)
+}
+
+func (c *Client) DeleteLocation(ctx context.Context, id uuid.UUID) error {
+	return c.sendJSON(ctx, http.MethodDelete, "/location/"+id.String(), nil, nil)
+}
+
+func (c *Client) ListUsers(ctx context.Context) ([]User, error) {
+	var rows []userRow
+	if err := c.getJSON(ctx, "/users", nil, &rows); err != nil {
+		return nil, err
+	}
+	return convert(rows, userRow.user), nil
+}
+
+func (c *Client) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
+	var row userRow
+	if err := c.getJSON(ctx, "/user/"+id.String(), nil, &row); err != nil {
+		return User{}, err
+	}
+	return row.user(), nil
+}
+
+// CreateUser adds a user and returns their ID. An empty email leaves them without one.
+func (c *Client) CreateUser(ctx context.Context, name string, email string) (uuid.UUID, error) {
+	body := map[string]string{"name": name}
+	if email != "" {
+		body["email"] = email
+	}
+	var res created
+	err := c.sendJSON(ctx, http.MethodPost, "/user", body, &res)
+	return res.ID, err
+}
+
+// UpdateUser writes the name and email of a user. An empty email removes it.
+func (c *Client) UpdateUser(ctx context.Context, user User) error {
+	body := map[string]string{"name": user.Name, "email": user.Email}
+	return c.sendJSON(ctx, http.MethodPost, "/user/"+user.ID.String(), body, nil)
+}
+
+func (c *Client) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return c.sendJSON(ctx, http.MethodDelete, "/user/"+id.String(), nil, nil)
+}
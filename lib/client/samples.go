@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// Values of SampleFilter.Modded
+const (
+	ModdedAny      = "any"
+	ModdedActive   = "active"
+	ModdedNoActive = "noactive"
+	ModdedNever    = "never"
+)
+
+// SampleFilter selects samples for ListSamples. The zero value selects every sample.
+type SampleFilter struct {
+	States []string
+	// ActiveOnly hides unassigned and archived samples, unless States asks for them
+	ActiveOnly bool
+	Product    uuid.UUID
+	Location   uuid.UUID
+	// ProductTree and LocationTree include the products or locations below Product and
+	// Location
+	ProductTree  bool
+	LocationTree bool
+	Owner        uuid.UUID
+	// Issue matches product issues containing it, ignoring case
+	Issue string
+	// Modded is one of the Modded constants
+	Modded string
+	// Mods is a comma separated list; samples match if any current mod contains one of them
+	Mods string
+}
+
+func (f SampleFilter) query() url.Values {
+	query := url.Values{}
+	for _, state := range f.States {
+		query.Add("state", state)
+	}
+	if f.ActiveOnly {
+		query.Set("active_only", "true")
+	}
+	tree := func(name string, id uuid.UUID, below bool) {
+		if id == uuid.Nil {
+			return
+		}
+		if below {
+			query.Set(name, "any-"+id.String())
+		} else {
+			query.Set(name, id.String())
+		}
+	}
+	tree("product", f.Product, f.ProductTree)
+	tree("location", f.Location, f.LocationTree)
+	if f.Owner != uuid.Nil {
+		query.Set("owner", f.Owner.String())
+	}
+	if f.Issue != "" {
+		query.Set("issue", f.Issue)
+	}
+	if f.Modded != "" {
+		query.Set("modded", f.Modded)
+	}
+	if f.Mods != "" {
+		query.Set("mods", f.Mods)
+	}
+	return query
+}
+
+// SampleUpdate is the new state of a sample. Every field is written, so uuid.Nil clears the
+// location, product or owner.
+type SampleUpdate struct {
+	LocationID   uuid.UUID
+	ProductID    uuid.UUID
+	OwnerID      uuid.UUID
+	State        string
+	ProductIssue string
+}
+
+// ListSamples returns the samples matching filter, with their mods.
+func (c *Client) ListSamples(ctx context.Context, filter SampleFilter) ([]Sample, error) {
+	var rows []sampleRow
+	if err := c.getJSON(ctx, "/samples", filter.query(), &rows); err != nil {
+		return nil, err
+	}
+	return convert(rows, sampleRow.sample), nil
+}
+
+// GetSample returns a sample with its mods.
+func (c *Client) GetSample(ctx context.Context, id string) (Sample, error) {
+	var res struct {
+		Sample sampleRow `json:"sample"`
+		Mods   []modRow  `json:"mods"`
+	}
+	if err := c.getJSON(ctx, "/sample/"+url.PathEscape(id), nil, &res); err != nil {
+		return Sample{}, err
+	}
+	res.Sample.Mods = res.Mods
+	return res.Sample.sample(), nil
+}
+
+// UpdateSample writes a sample, registering it if the ID is new, and returns the result. The
+// returned sample has no mods.
+func (c *Client) UpdateSample(ctx context.Context, id string, update SampleUpdate) (Sample, error) {
+	form := url.Values{
+		"location_id":   {optionalUUID(update.LocationID)},
+		"product_id":    {optionalUUID(update.ProductID)},
+		"owner_id":      {optionalUUID(update.OwnerID)},
+		"state":         {update.State},
+		"product_issue": {update.ProductIssue},
+	}
+	var row sampleRow
+	if err := c.sendForm(ctx, http.MethodPost, "/sample/"+url.PathEscape(id), form, &row); err != nil {
+		return Sample{}, err
+	}
+	return row.sample(), nil
+}
+
+// GenerateSamples registers count new unassigned samples with random IDs and returns the IDs.
+func (c *Client) GenerateSamples(ctx context.Context, count int) ([]string, error) {
+	var res struct {
+		SampleIDs []string `json:"sample_ids"`
+	}
+	query := url.Values{"num_samples": {strconv.Itoa(count)}}
+	if err := c.getJSON(ctx, "/generate_samples", query, &res); err != nil {
+		return nil, err
+	}
+	return res.SampleIDs, nil
+}
+
+// ListMods returns every mod of a sample, including removed ones.
+func (c *Client) ListMods(ctx context.Context, sampleID string) ([]Mod, error) {
+	var res struct {
+		Mods []modRow `json:"mods"`
+	}
+	if err := c.getJSON(ctx, "/sample/"+url.PathEscape(sampleID)+"/mods/", nil, &res); err != nil {
+		return nil, err
+	}
+	return convert(res.Mods, modRow.mod), nil
+}
+
+// AddMod adds a mod to a sample.
+func (c *Client) AddMod(ctx context.Context, sampleID string, name string) error {
+	body := map[string]string{"name": name}
+	return c.sendJSON(ctx, http.MethodPost, "/sample/"+url.PathEscape(sampleID)+"/mods/", body, nil)
+}
+
+// RemoveMod marks a mod of a sample as removed.
+func (c *Client) RemoveMod(ctx context.Context, sampleID string, modID uuid.UUID) error {
+	return c.sendJSON(ctx, http.MethodDelete, "/sample/"+url.PathEscape(sampleID)+"/mods/"+modID.String(), nil, nil)
+}
@@ -0,0 +1,251 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types sent by /api/sync, see docs/sync-events.md
+const (
+	EventInfo             = "info"
+	EventResyncRequired   = "resync_required"
+	EventShutdown         = "shutdown"
+	EventSamplesUpdated   = "samples_updated"
+	EventProductsUpdated  = "products_updated"
+	EventLocationsUpdated = "locations_updated"
+	EventUsersUpdated     = "users_updated"
+	EventImported         = "imported"
+	EventPresence         = "presence"
+)
+
+// Values of Change.Op
+const (
+	OpCreated = "created"
+	OpUpdated = "updated"
+	OpDeleted = "deleted"
+)
+
+// RECONNECT_DELAY is how long Subscribe waits before reconnecting. It doubles after each
+// failed attempt, up to MAX_RECONNECT_DELAY.
+const (
+	RECONNECT_DELAY     = time.Second
+	MAX_RECONNECT_DELAY = 30 * time.Second
+)
+
+// Event is a server-sent event. ID is 0 for events that can't be resumed from.
+type Event struct {
+	ID   uint64
+	Type string
+	Data json.RawMessage
+}
+
+// Change is the payload of the *_updated events.
+type Change struct {
+	// Kind is sample, product, location or user
+	Kind string `json:"kind"`
+	// ID is the XX-XX-XX ID for samples, otherwise a UUID
+	ID string `json:"id"`
+	// Op is one of the Op constants
+	Op string `json:"op"`
+	// Data is the new row, missing for deletions; decode it with Sample, Product, Location
+	// or User
+	Data    json.RawMessage            `json:"data"`
+	Changes map[string]json.RawMessage `json:"changes"`
+	Actor   struct {
+		UserID  string `json:"user_id"`
+		TokenID string `json:"token_id"`
+	} `json:"actor"`
+	Time time.Time `json:"time"`
+}
+
+// Change decodes the payload of a *_updated event.
+func (e Event) Change() (Change, error) {
+	var change Change
+	err := json.Unmarshal(e.Data, &change)
+	return change, err
+}
+
+// errNoData is returned when a change has no row to decode.
+var errNoData = errors.New("change has no data")
+
+func (ch Change) Sample() (Sample, error) {
+	var row sampleRow
+	if err := ch.decode(&row); err != nil {
+		return Sample{}, err
+	}
+	return row.sample(), nil
+}
+
+func (ch Change) Product() (Product, error) {
+	var row productRow
+	if err := ch.decode(&row); err != nil {
+		return Product{}, err
+	}
+	return row.product(), nil
+}
+
+func (ch Change) Location() (Location, error) {
+	var row locationRow
+	if err := ch.decode(&row); err != nil {
+		return Location{}, err
+	}
+	return row.location(), nil
+}
+
+func (ch Change) User() (User, error) {
+	var row userRow
+	if err := ch.decode(&row); err != nil {
+		return User{}, err
+	}
+	return row.user(), nil
+}
+
+func (ch Change) decode(row any) error {
+	if len(ch.Data) == 0 || string(ch.Data) == "null" {
+		return errNoData
+	}
+	return json.Unmarshal(ch.Data, row)
+}
+
+// Topics limits a subscription to part of the inventory. The zero value receives every event.
+type Topics struct {
+	Samples   []string
+	Locations []uuid.UUID
+	Products  []uuid.UUID
+	Owners    []uuid.UUID
+	// Mine subscribes to the token owner's samples
+	Mine bool
+}
+
+func (t Topics) query() url.Values {
+	query := url.Values{}
+	query["sample"] = t.Samples
+	for name, ids := range map[string][]uuid.UUID{"location": t.Locations, "product": t.Products, "owner": t.Owners} {
+		for _, id := range ids {
+			query.Add(name, id.String())
+		}
+	}
+	if t.Mine {
+		query.Set("mine", "true")
+	}
+	return query
+}
+
+// Subscribe calls fn with every event from /api/sync until ctx is done or the server refuses
+// the subscription. It reconnects whenever the stream ends, resuming after the last event
+// received, so fn only misses events if it gets EventResyncRequired, after which it should
+// refetch what it is tracking. fn is called from a single goroutine.
+//
+// The HTTP client shouldn't have a Timeout, since it would cut every stream short.
+func (c *Client) Subscribe(ctx context.Context, topics Topics, fn func(Event)) error {
+	var lastID uint64
+	delay := RECONNECT_DELAY
+	for {
+		retry, connected, err := c.stream(ctx, topics, &lastID, fn)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var apiErr *Error
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 && apiErr.StatusCode != http.StatusTooManyRequests {
+			return err
+		}
+		if connected {
+			delay = RECONNECT_DELAY
+		}
+		wait := delay
+		if retry > 0 {
+			wait = retry
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		if !connected {
+			delay = min(delay*2, MAX_RECONNECT_DELAY)
+		}
+	}
+}
+
+// stream reads events until the connection ends. It returns the reconnection delay the server
+// asked for, if any, and whether it connected.
+func (c *Client) stream(ctx context.Context, topics Topics, lastID *uint64, fn func(Event)) (time.Duration, bool, error) {
+	req, err := c.request(ctx, http.MethodGet, "/sync", topics.query(), nil)
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if *lastID != 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(*lastID, 10))
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return 0, false, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return 0, false, responseError(res)
+	}
+
+	var (
+		retry time.Duration
+		event Event
+		data  []string
+	)
+	reader := bufio.NewReader(res.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return retry, true, nil
+		}
+		if err != nil {
+			return retry, true, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if event.Type != "" || len(data) > 0 {
+				if event.Type == "" {
+					event.Type = "message"
+				}
+				event.Data = json.RawMessage(strings.Join(data, "\n"))
+				if event.ID != 0 {
+					*lastID = event.ID
+				}
+				fn(event)
+			}
+			event, data = Event{}, nil
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Type = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			if event.ID, err = strconv.ParseUint(value, 10, 64); err != nil {
+				return retry, true, fmt.Errorf("invalid event ID %q", value)
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	sampleid "reesource-tracker/lib/sample_id"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sample states
+const (
+	StateInUse      = "in_use"
+	StateBroken     = "broken"
+	StateAvailable  = "available"
+	StateArchived   = "archived"
+	StateUnassigned = "unassigned"
+)
+
+// Sample is a tracked sample. Unset IDs are uuid.Nil and unset times are zero.
+type Sample struct {
+	// ID is the XX-XX-XX sample ID
+	ID           string
+	LocationID   uuid.UUID
+	ProductID    uuid.UUID
+	OwnerID      uuid.UUID
+	State        string
+	ProductIssue string
+	Registered   time.Time
+	LastUpdate   time.Time
+	// OwnerName is only filled in by ListSamples
+	OwnerName string
+	// Mods are every mod the sample has had, including removed ones
+	Mods []Mod
+}
+
+// Mod is a modification made to a sample. Removed is zero while it is still in place.
+type Mod struct {
+	ID        uuid.UUID
+	SampleID  string
+	Name      string
+	Added     time.Time
+	Removed   time.Time
+	AddedBy   uuid.UUID
+	RemovedBy uuid.UUID
+}
+
+// Current reports whether the mod hasn't been removed.
+func (m Mod) Current() bool {
+	return m.Removed.IsZero()
+}
+
+type Product struct {
+	ID         uuid.UUID
+	Name       string
+	ParentID   uuid.UUID
+	PartNumber string
+}
+
+type Location struct {
+	ID          uuid.UUID
+	Name        string
+	Description string
+	ParentID    uuid.UUID
+}
+
+type User struct {
+	ID          uuid.UUID
+	Name        string
+	Email       string
+	OIDCSubject string
+}
+
+// The types below are rows as the API returns them, with Go field names, IDs as base64
+// blobs and nullable columns as objects.
+
+type nullString struct {
+	String string
+	Valid  bool
+}
+
+type nullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+func (t nullTime) value() time.Time {
+	if !t.Valid {
+		return time.Time{}
+	}
+	return t.Time
+}
+
+type sampleRow struct {
+	ID             []byte
+	LocationID     []byte
+	ProductID      []byte
+	OwnerID        []byte
+	TimeRegistered nullTime
+	LastUpdate     nullTime
+	State          string
+	ProductIssue   nullString
+	OwnerName      nullString
+	Mods           []modRow `json:"mods"`
+}
+
+func (row sampleRow) sample() Sample {
+	sample := Sample{
+		ID:           sampleID(row.ID),
+		LocationID:   blobUUID(row.LocationID),
+		ProductID:    blobUUID(row.ProductID),
+		OwnerID:      blobUUID(row.OwnerID),
+		State:        row.State,
+		ProductIssue: row.ProductIssue.String,
+		Registered:   row.TimeRegistered.value(),
+		LastUpdate:   row.LastUpdate.value(),
+		OwnerName:    row.OwnerName.String,
+	}
+	for _, mod := range row.Mods {
+		sample.Mods = append(sample.Mods, mod.mod())
+	}
+	return sample
+}
+
+type modRow struct {
+	ID          []byte
+	SampleID    []byte
+	Name        string
+	TimeAdded   time.Time
+	TimeRemoved nullTime
+	AddedBy     []byte
+	RemovedBy   []byte
+}
+
+func (row modRow) mod() Mod {
+	return Mod{
+		ID:        blobUUID(row.ID),
+		SampleID:  sampleID(row.SampleID),
+		Name:      row.Name,
+		Added:     row.TimeAdded,
+		Removed:   row.TimeRemoved.value(),
+		AddedBy:   blobUUID(row.AddedBy),
+		RemovedBy: blobUUID(row.RemovedBy),
+	}
+}
+
+type productRow struct {
+	ID              []byte
+	Name            string
+	ParentProductID []byte
+	PartNumber      nullString
+}
+
+func (row productRow) product() Product {
+	return Product{
+		ID:         blobUUID(row.ID),
+		Name:       row.Name,
+		ParentID:   blobUUID(row.ParentProductID),
+		PartNumber: row.PartNumber.String,
+	}
+}
+
+type locationRow struct {
+	ID               []byte
+	Name             string
+	Description      nullString
+	ParentLocationID []byte
+}
+
+func (row locationRow) location() Location {
+	return Location{
+		ID:          blobUUID(row.ID),
+		Name:        row.Name,
+		Description: row.Description.String,
+		ParentID:    blobUUID(row.ParentLocationID),
+	}
+}
+
+type userRow struct {
+	ID          []byte
+	Name        string
+	Email       nullString
+	OidcSubject nullString
+}
+
+func (row userRow) user() User {
+	return User{
+		ID:          blobUUID(row.ID),
+		Name:        row.Name,
+		Email:       row.Email.String,
+		OIDCSubject: row.OidcSubject.String,
+	}
+}
+
+// blobUUID decodes a UUID column, or returns uuid.Nil if it is NULL.
+func blobUUID(blob []byte) uuid.UUID {
+	id, err := uuid.FromBytes(blob)
+	if err != nil {
+		return uuid.Nil
+	}
+	return id
+}
+
+func sampleID(blob []byte) string {
+	id, err := sampleid.FormatSampleID(blob)
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+// optionalUUID formats an ID for a request, where uuid.Nil means none.
+func optionalUUID(id uuid.UUID) string {
+	if id == uuid.Nil {
+		return ""
+	}
+	return id.String()
+}
+
+// convert applies fn to every row.
+func convert[Row any, T any](rows []Row, fn func(Row) T) []T {
+	converted := make([]T, len(rows))
+	for i, row := range rows {
+		converted[i] = fn(row)
+	}
+	return converted
+}
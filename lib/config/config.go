@@ -0,0 +1,427 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config is the server's runtime configuration. Each setting can come from, in increasing
+// order of precedence, its default, the config file, the environment, or a flag.
+type Config struct {
+	// Host and Port are the address the server listens on
+	Host string
+	Port int
+	// DBDriver is the database engine, DRIVER_SQLITE or DRIVER_POSTGRES
+	DBDriver string
+	// DBPath is the SQLite database file
+	DBPath string
+	// DatabaseURL is the PostgreSQL connection URL
+	DatabaseURL string
+	// MigrationsPath is the directory holding the SQLite migrations. The PostgreSQL ones are
+	// built into the binary.
+	MigrationsPath string
+	// ClientPath is the directory holding the built frontend
+	ClientPath string
+	// DevProxy is where frontend requests are proxied to in DEV mode. When empty they go to
+	// port 5173 on the host the request was made to.
+	DevProxy string
+	// BaseURL is the public URL of the app, used for links in emails
+	BaseURL string
+	// Dev serves the frontend through the dev proxy instead of from ClientPath
+	Dev bool
+	// ShutdownTimeout is how long in-flight requests and background jobs get to finish
+	// when the server is stopped
+	ShutdownTimeout time.Duration
+	// AnonymousRole is the role given to requests without credentials, "none" for no role
+	AnonymousRole string
+	// SessionDuration is how long a login lasts
+	SessionDuration time.Duration
+	// OIDCIssuer enables single sign-on with the provider at that URL. The other OIDC
+	// settings are only read when it is set.
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	// OIDCScopes are requested in addition to openid, space separated
+	OIDCScopes      string
+	OIDCGroupsClaim string
+	// OIDCRoleMapping maps provider groups to roles, e.g. "lab-admins=admin,lab-techs=technician"
+	OIDCRoleMapping string
+	OIDCDefaultRole string
+	// MQTTBroker enables publishing to the broker at that URL
+	MQTTBroker      string
+	MQTTClientID    string
+	MQTTTopicPrefix string
+	MQTTQoS         int
+	MQTTUsername    string
+	MQTTPassword    string
+	// SMTPHost enables email notifications through that mail server
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	// LogLevel is debug, info, warn or error and LogFormat is json or text
+	LogLevel  string
+	LogFormat string
+	// MetricsListen is a separate address to serve /metrics on, without authentication
+	MetricsListen string
+	// BackupDir is where backups are written, "backups" next to the database when empty
+	BackupDir string
+	// BackupKeep is how many backups are kept after pruning
+	BackupKeep int
+	// StaleSampleDays is how long a sample can go without updates before its owner is
+	// reminded, and CleanupRetentionDays how long delivery history is kept
+	StaleSampleDays      int
+	CleanupRetentionDays int
+	// File is the config file that was read, if any
+	File string
+	// Args are the arguments after the flags: a command such as "backup" and its arguments
+	Args []string
+}
+
+// Database engines for DBDriver
+const (
+	DRIVER_SQLITE   = "sqlite"
+	DRIVER_POSTGRES = "postgres"
+)
+
+// setting describes one configuration value and where it is read from.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	field func(cfg *Config) *string
+}
+
+// intSetting and durationSetting are settings that are parsed from their text.
+type intSetting struct {
+	flag  string
+	env   string
+	usage string
+	field func(cfg *Config) *int
+}
+
+type durationSetting struct {
+	flag  string
+	env   string
+	usage string
+	field func(cfg *Config) *time.Duration
+}
+
+var settings = []setting{
+	{"host", "HOST", "address to listen on", func(cfg *Config) *string { return &cfg.Host }},
+	{"db-driver", "DB_DRIVER", "database engine, sqlite or postgres", func(cfg *Config) *string { return &cfg.DBDriver }},
+	{"db", "DB_PATH", "SQLite database file", func(cfg *Config) *string { return &cfg.DBPath }},
+	{"database-url", "DATABASE_URL", "PostgreSQL connection URL", func(cfg *Config) *string { return &cfg.DatabaseURL }},
+	{"migrations", "MIGRATIONS_PATH", "directory of SQL migrations", func(cfg *Config) *string { return &cfg.MigrationsPath }},
+	{"client", "CLIENT_PATH", "directory of the built frontend", func(cfg *Config) *string { return &cfg.ClientPath }},
+	{"dev-proxy", "DEV_PROXY", "frontend dev server URL used in DEV mode", func(cfg *Config) *string { return &cfg.DevProxy }},
+	{"base-url", "BASE_URL", "public URL of the app", func(cfg *Config) *string { return &cfg.BaseURL }},
+	{"anonymous-role", "ANONYMOUS_ROLE", "role of requests without credentials, or none", func(cfg *Config) *string { return &cfg.AnonymousRole }},
+	{"oidc-issuer", "OIDC_ISSUER", "OpenID Connect issuer URL, enables single sign-on", func(cfg *Config) *string { return &cfg.OIDCIssuer }},
+	{"oidc-client-id", "OIDC_CLIENT_ID", "OpenID Connect client ID", func(cfg *Config) *string { return &cfg.OIDCClientID }},
+	{"oidc-client-secret", "OIDC_CLIENT_SECRET", "OpenID Connect client secret", func(cfg *Config) *string { return &cfg.OIDCClientSecret }},
+	{"oidc-redirect-url", "OIDC_REDIRECT_URL", "OpenID Connect callback URL", func(cfg *Config) *string { return &cfg.OIDCRedirectURL }},
+	{"oidc-scopes", "OIDC_SCOPES", "scopes requested besides openid, space separated", func(cfg *Config) *string { return &cfg.OIDCScopes }},
+	{"oidc-groups-claim", "OIDC_GROUPS_CLAIM", "ID token claim holding the user's groups", func(cfg *Config) *string { return &cfg.OIDCGroupsClaim }},
+	{"oidc-role-mapping", "OIDC_ROLE_MAPPING", "group to role mapping, e.g. lab-admins=admin", func(cfg *Config) *string { return &cfg.OIDCRoleMapping }},
+	{"oidc-default-role", "OIDC_DEFAULT_ROLE", "role of single sign-on users in no mapped group", func(cfg *Config) *string { return &cfg.OIDCDefaultRole }},
+	{"mqtt-broker", "MQTT_BROKER", "MQTT broker URL, enables publishing", func(cfg *Config) *string { return &cfg.MQTTBroker }},
+	{"mqtt-client-id", "MQTT_CLIENT_ID", "MQTT client ID", func(cfg *Config) *string { return &cfg.MQTTClientID }},
+	{"mqtt-topic-prefix", "MQTT_TOPIC_PREFIX", "prefix of published MQTT topics", func(cfg *Config) *string { return &cfg.MQTTTopicPrefix }},
+	{"mqtt-username", "MQTT_USERNAME", "MQTT username", func(cfg *Config) *string { return &cfg.MQTTUsername }},
+	{"mqtt-password", "MQTT_PASSWORD", "MQTT password", func(cfg *Config) *string { return &cfg.MQTTPassword }},
+	{"smtp-host", "SMTP_HOST", "mail server, enables email notifications", func(cfg *Config) *string { return &cfg.SMTPHost }},
+	{"smtp-username", "SMTP_USERNAME", "mail server username", func(cfg *Config) *string { return &cfg.SMTPUsername }},
+	{"smtp-password", "SMTP_PASSWORD", "mail server password", func(cfg *Config) *string { return &cfg.SMTPPassword }},
+	{"smtp-from", "SMTP_FROM", "sender address of notifications", func(cfg *Config) *string { return &cfg.SMTPFrom }},
+	{"log-level", "LOG_LEVEL", "debug, info, warn or error", func(cfg *Config) *string { return &cfg.LogLevel }},
+	{"log-format", "LOG_FORMAT", "json or text", func(cfg *Config) *string { return &cfg.LogFormat }},
+	{"metrics-listen", "METRICS_LISTEN", "separate address to serve /metrics on", func(cfg *Config) *string { return &cfg.MetricsListen }},
+	{"backup-dir", "BACKUP_DIR", "directory backups are written to", func(cfg *Config) *string { return &cfg.BackupDir }},
+}
+
+var intSettings = []intSetting{
+	{"port", "PORT", "port to listen on", func(cfg *Config) *int { return &cfg.Port }},
+	{"mqtt-qos", "MQTT_QOS", "MQTT quality of service, 0, 1 or 2", func(cfg *Config) *int { return &cfg.MQTTQoS }},
+	{"smtp-port", "SMTP_PORT", "mail server port", func(cfg *Config) *int { return &cfg.SMTPPort }},
+	{"backup-keep", "BACKUP_KEEP", "number of backups to keep", func(cfg *Config) *int { return &cfg.BackupKeep }},
+	{"stale-sample-days", "STALE_SAMPLE_DAYS", "days without updates before owners are reminded", func(cfg *Config) *int { return &cfg.StaleSampleDays }},
+	{"cleanup-retention-days", "CLEANUP_RETENTION_DAYS", "days delivery history is kept", func(cfg *Config) *int { return &cfg.CleanupRetentionDays }},
+}
+
+var durationSettings = []durationSetting{
+	{"shutdown-timeout", "SHUTDOWN_TIMEOUT", "time to wait for requests and jobs on shutdown, e.g. 30s", func(cfg *Config) *time.Duration { return &cfg.ShutdownTimeout }},
+	{"session-duration", "SESSION_DURATION", "how long a login lasts, e.g. 12h", func(cfg *Config) *time.Duration { return &cfg.SessionDuration }},
+}
+
+// Current is the configuration loaded at startup.
+var Current = Defaults()
+
+// Defaults returns the built-in configuration. The migrations directory is "migrations"
+// next to the binary if it exists, as in the Docker image and release builds, and
+// "database/migrations" in a checkout.
+func Defaults() *Config {
+	cfg := &Config{
+		Host:            "0.0.0.0",
+		Port:            80,
+		DBDriver:        DRIVER_SQLITE,
+		DBPath:          "database/db.sqlite",
+		MigrationsPath:  "database/migrations",
+		ClientPath:      "client",
+		ShutdownTimeout: 15 * time.Second,
+		AnonymousRole:   "none",
+		SessionDuration: 7 * 24 * time.Hour,
+		OIDCScopes:      "profile email groups",
+		OIDCGroupsClaim: "groups",
+		MQTTClientID:    "reesource-tracker",
+		MQTTTopicPrefix: "reesource",
+		SMTPPort:        25,
+		LogLevel:        "info",
+		LogFormat:       "json",
+		BackupKeep:      7,
+
+		StaleSampleDays:      90,
+		CleanupRetentionDays: 30,
+	}
+	if _, err := os.Stat("migrations"); err == nil {
+		cfg.MigrationsPath = "migrations"
+	}
+	_, cfg.Dev = os.LookupEnv("DEV")
+	if runtime.GOOS == "windows" && cfg.Dev {
+		cfg.Host = "localhost"
+	}
+	return cfg
+}
+
+// Load reads the configuration from the config file, environment and command line flags
+// in args, validates it and makes it Current. The config file is given with -config or
+// CONFIG_FILE and has KEY=value lines like .env; it can set any environment variable the
+// server reads, but variables already in the environment take precedence.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("reesource-tracker", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: reesource-tracker [flags] [command]")
+		fmt.Fprintln(fs.Output(), "Commands: serve (default), migrate up|down|version, generate <count>, import <kind> <file>,")
+		fmt.Fprintln(fs.Output(), "export <kind> <file>, create-admin <name>, check, backup, restore <file>, dump <file>, load <file>")
+		fs.PrintDefaults()
+	}
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "config file of KEY=value lines (env: CONFIG_FILE)")
+	flags := map[string]*string{}
+	for _, s := range settings {
+		flags[s.flag] = fs.String(s.flag, "", s.usage+" (env: "+s.env+")")
+	}
+	ints := map[string]*int{}
+	for _, s := range intSettings {
+		ints[s.flag] = fs.Int(s.flag, 0, s.usage+" (env: "+s.env+")")
+	}
+	durations := map[string]*time.Duration{}
+	for _, s := range durationSettings {
+		durations[s.flag] = fs.Duration(s.flag, 0, s.usage+" (env: "+s.env+")")
+	}
+	dev := fs.Bool("dev", false, "proxy the frontend to the dev server (env: DEV)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *configFile != "" {
+		if err := godotenv.Load(*configFile); err != nil {
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	cfg := Defaults()
+	cfg.File = *configFile
+	cfg.Args = fs.Args()
+	for _, s := range settings {
+		if set[s.flag] {
+			*s.field(cfg) = *flags[s.flag]
+		} else if value, ok := os.LookupEnv(s.env); ok {
+			*s.field(cfg) = value
+		}
+	}
+	for _, s := range intSettings {
+		if set[s.flag] {
+			*s.field(cfg) = *ints[s.flag]
+		} else if value, ok := os.LookupEnv(s.env); ok {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %q is not a number", s.env, value)
+			}
+			*s.field(cfg) = parsed
+		}
+	}
+	for _, s := range durationSettings {
+		if set[s.flag] {
+			*s.field(cfg) = *durations[s.flag]
+		} else if value, ok := os.LookupEnv(s.env); ok {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %q is not a duration such as 30s", s.env, value)
+			}
+			*s.field(cfg) = parsed
+		}
+	}
+	if set["dev"] {
+		cfg.Dev = *dev
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	cfg.MQTTTopicPrefix = strings.Trim(cfg.MQTTTopicPrefix, "/")
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	Current = cfg
+	return cfg, nil
+}
+
+// Validate checks that the addresses and URLs parse, that the paths exist and that numbers
+// are in range. Settings of optional features, such as the OIDC ones, are checked when the
+// feature is set up, which disables it if they are wrong.
+func (cfg *Config) Validate() error {
+	problems := []error{}
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		problems = append(problems, fmt.Errorf("port %d is out of range", cfg.Port))
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		problems = append(problems, fmt.Errorf("shutdown timeout %s must be positive", cfg.ShutdownTimeout))
+	}
+	if cfg.SessionDuration <= 0 {
+		problems = append(problems, fmt.Errorf("session duration %s must be positive", cfg.SessionDuration))
+	}
+	if cfg.MQTTQoS < 0 || cfg.MQTTQoS > 2 {
+		problems = append(problems, fmt.Errorf("MQTT QoS %d is not 0, 1 or 2", cfg.MQTTQoS))
+	}
+	if cfg.SMTPPort < 1 || cfg.SMTPPort > 65535 {
+		problems = append(problems, fmt.Errorf("SMTP port %d is out of range", cfg.SMTPPort))
+	}
+	if cfg.BackupKeep < 1 {
+		problems = append(problems, fmt.Errorf("backups to keep %d must be at least 1", cfg.BackupKeep))
+	}
+	if cfg.StaleSampleDays < 1 || cfg.CleanupRetentionDays < 1 {
+		problems = append(problems, errors.New("stale sample and cleanup retention days must be at least 1"))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil && cfg.LogLevel != "" {
+		problems = append(problems, fmt.Errorf("log level %q is not debug, info, warn or error", cfg.LogLevel))
+	}
+	if format := strings.ToLower(cfg.LogFormat); format != "" && format != "json" && format != "text" {
+		problems = append(problems, fmt.Errorf("log format %q is not json or text", cfg.LogFormat))
+	}
+	if strings.ContainsAny(cfg.Host, " /") {
+		problems = append(problems, fmt.Errorf("host %q is not a valid address", cfg.Host))
+	}
+	switch cfg.DBDriver {
+	case DRIVER_SQLITE:
+		if dir := filepath.Dir(cfg.DBPath); !isDir(dir) {
+			problems = append(problems, fmt.Errorf("database directory %q does not exist", dir))
+		}
+		if !isDir(cfg.MigrationsPath) {
+			problems = append(problems, fmt.Errorf("migrations directory %q does not exist", cfg.MigrationsPath))
+		}
+	case DRIVER_POSTGRES:
+		if u, err := url.Parse(cfg.DatabaseURL); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			problems = append(problems, errors.New("database URL must be set to a postgres:// URL"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("database driver %q is not sqlite or postgres", cfg.DBDriver))
+	}
+	// Only the server needs the frontend, so the other commands work without it
+	if !cfg.Dev && cfg.Command() == "serve" {
+		if _, err := os.Stat(filepath.Join(cfg.ClientPath, "index.html")); err != nil {
+			problems = append(problems, fmt.Errorf("client directory %q has no index.html", cfg.ClientPath))
+		}
+	}
+	if cfg.DevProxy != "" {
+		if err := checkURL(cfg.DevProxy); err != nil {
+			problems = append(problems, fmt.Errorf("dev proxy: %w", err))
+		}
+	}
+	if cfg.BaseURL != "" {
+		if err := checkURL(cfg.BaseURL); err != nil {
+			problems = append(problems, fmt.Errorf("base URL: %w", err))
+		}
+	}
+	return errors.Join(problems...)
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func checkURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http(s) URL", value)
+	}
+	return nil
+}
+
+// redactURL hides the password in a connection URL so it can be logged.
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+// Command is the command to run, "serve" if none was given.
+func (cfg *Config) Command() string {
+	if len(cfg.Args) == 0 {
+		return "serve"
+	}
+	return cfg.Args[0]
+}
+
+// Address is the host and port to listen on.
+func (cfg *Config) Address() string {
+	return net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+}
+
+// MigrationsURL is the migrations directory as a golang-migrate source URL.
+func (cfg *Config) MigrationsURL() string {
+	return "file://" + filepath.ToSlash(cfg.MigrationsPath)
+}
+
+// Log prints the effective configuration. Passwords and secrets are left out.
+func (cfg *Config) Log() {
+	slog.Info("Configuration",
+		"file", cfg.File,
+		"address", cfg.Address(),
+		"db_driver", cfg.DBDriver,
+		"db_path", cfg.DBPath,
+		"database_url", redactURL(cfg.DatabaseURL),
+		"migrations_path", cfg.MigrationsPath,
+		"client_path", cfg.ClientPath,
+		"dev", cfg.Dev,
+		"dev_proxy", cfg.DevProxy,
+		"base_url", cfg.BaseURL,
+		"shutdown_timeout", cfg.ShutdownTimeout.String(),
+		"anonymous_role", cfg.AnonymousRole,
+		"session_duration", cfg.SessionDuration.String(),
+		"oidc_issuer", cfg.OIDCIssuer,
+		"mqtt_broker", redactURL(cfg.MQTTBroker),
+		"smtp_host", cfg.SMTPHost,
+		"log_level", cfg.LogLevel,
+		"log_format", cfg.LogFormat,
+		"metrics_listen", cfg.MetricsListen,
+		"backup_dir", cfg.BackupDir,
+		"backup_keep", cfg.BackupKeep,
+	)
+}
@@ -3,7 +3,9 @@ package sqlite_driver
 import (
 	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/sqlite"
@@ -15,31 +17,45 @@ const FOREIGN_KEY_PRAGMA = "PRAGMA foreign_keys = ON;"
 const JOURNAL_MODE_PRAGMA = "PRAGMA journal_mode=WAL;"
 const DRIVER_NAME = "sqlite"
 
-func Connect(ctx context.Context, migration_dir string) (*sql.DB, *migrate.Migrate, error) {
-	db, err := sql.Open(DRIVER_NAME, "database/db.sqlite")
+// CHECKPOINT_PRAGMA copies the WAL into the database file and empties it, so the file is
+// complete on its own once the server has stopped.
+const CHECKPOINT_PRAGMA = "PRAGMA wal_checkpoint(TRUNCATE);"
+
+// BUSY_TIMEOUT makes writers wait for each other instead of failing with SQLITE_BUSY, e.g. while
+// a backup is running. It is set in the DSN so it applies to every pooled connection.
+const BUSY_TIMEOUT = "_pragma=busy_timeout(5000)"
+
+func Connect(ctx context.Context, db_path string, migration_dir string) (*sql.DB, *migrate.Migrate, error) {
+	db, err := sql.Open(DRIVER_NAME, db_path+"?"+BUSY_TIMEOUT)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	_, err = db.Exec(FOREIGN_KEY_PRAGMA + JOURNAL_MODE_PRAGMA)
 	if err != nil {
-		log.Fatal("Failed to enable foreign keys and WAL mode:", err)
-		return nil, nil, err
+		return nil, nil, fmt.Errorf("failed to enable foreign keys and WAL mode: %w", err)
 	}
 
 	driver, err := sqlite.WithInstance(db, &sqlite.Config{})
 	if err != nil {
-		println("Error creating sqlite driver", err.Error())
-		return nil, nil, err
+		return nil, nil, fmt.Errorf("error creating sqlite driver: %w", err)
 	}
 	m, err := migrate.NewWithDatabaseInstance(
 		migration_dir,
 		DRIVER_NAME, driver)
 	if err != nil {
-		println("Error initialising migration", err.Error())
-		return nil, nil, err
+		return nil, nil, fmt.Errorf("error initialising migration: %w", err)
 	}
-	println("Connected to sqlite database")
+	slog.Info("Connected to sqlite database")
 
 	return db, m, nil
 }
+
+// Close checkpoints the WAL and closes the database.
+func Close(ctx context.Context, db *sql.DB) error {
+	_, checkpointErr := db.ExecContext(ctx, CHECKPOINT_PRAGMA)
+	if checkpointErr != nil {
+		checkpointErr = fmt.Errorf("failed to checkpoint the WAL: %w", checkpointErr)
+	}
+	return errors.Join(checkpointErr, db.Close())
+}
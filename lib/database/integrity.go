@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reesource-tracker/lib/config"
+)
+
+// Integrity is the result of checking the database.
+type Integrity struct {
+	// Problems are those found by SQLite's integrity check
+	Problems []string
+	// ForeignKeys are rows referring to rows that don't exist, which SQLite allows if foreign
+	// keys were off when they were written
+	ForeignKeys []string
+	Version     uint
+	Dirty       bool
+}
+
+// OK reports whether no problems were found.
+func (i Integrity) OK() bool {
+	return len(i.Problems) == 0 && len(i.ForeignKeys) == 0 && !i.Dirty
+}
+
+// CheckIntegrity checks the database's structure and foreign keys, and that no migration
+// failed part way. PostgreSQL enforces both itself, so only the migration is checked there.
+func CheckIntegrity(ctx context.Context) (Integrity, error) {
+	result := Integrity{}
+	var err error
+	if result.Version, result.Dirty, err = MigrationVersion(); err != nil {
+		return result, err
+	}
+	if config.Current.DBDriver == config.DRIVER_POSTGRES {
+		return result, nil
+	}
+
+	rows, err := DB.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return result, err
+	}
+	for rows.Next() {
+		var problem string
+		if err := rows.Scan(&problem); err != nil {
+			rows.Close()
+			return result, err
+		}
+		if problem != "ok" {
+			result.Problems = append(result.Problems, problem)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	rows, err = DB.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			table, parent string
+			rowID         *int64
+			key           int
+		)
+		if err := rows.Scan(&table, &rowID, &parent, &key); err != nil {
+			return result, err
+		}
+		row := "without rowid"
+		if rowID != nil {
+			row = fmt.Sprintf("row %d", *rowID)
+		}
+		result.ForeignKeys = append(result.ForeignKeys, fmt.Sprintf("%s %s refers to a missing row in %s", table, row, parent))
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"reesource-tracker/lib/metrics"
+	"strings"
+	"time"
+)
+
+// timedDB wraps a connection to record how long each query takes, and to log queries at
+// debug level with the request ID from the context.
+type timedDB struct {
+	db DBTX
+}
+
+// queryName returns the sqlc query name from the "-- name: X :kind" comment that starts
+// every generated query, or "other" for hand-written SQL.
+func queryName(query string) string {
+	rest, ok := strings.CutPrefix(query, "-- name: ")
+	if !ok {
+		return "other"
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	return name
+}
+
+func observe(ctx context.Context, query string, start time.Time, err error) {
+	name := queryName(query)
+	duration := time.Since(start)
+	metrics.DBQueryDuration.WithLabelValues(name).Observe(duration.Seconds())
+	if !slog.Default().Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("query", name),
+		slog.Float64("duration_ms", float64(duration.Microseconds())/1000),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	slog.LogAttrs(ctx, slog.LevelDebug, "db query", attrs...)
+}
+
+func (t timedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := t.db.ExecContext(ctx, query, args...)
+	observe(ctx, query, start, err)
+	return result, err
+}
+
+func (t timedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.db.PrepareContext(ctx, query)
+}
+
+func (t timedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	observe(ctx, query, start, err)
+	return rows, err
+}
+
+func (t timedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := t.db.QueryRowContext(ctx, query, args...)
+	observe(ctx, query, start, row.Err())
+	return row
+}
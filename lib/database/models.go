@@ -1,6 +1,6 @@
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
-//   sqlc v1.29.0
+//   sqlc v1.30.0
 
 package database
 
@@ -9,6 +9,29 @@ import (
 	"time"
 )
 
+type ActivityLog struct {
+	ID         interface{}
+	UserID     interface{}
+	TokenID    interface{}
+	EntityKind string
+	EntityID   string
+	Action     string
+	Details    sql.NullString
+	Time       time.Time
+}
+
+type ApiToken struct {
+	ID         interface{}
+	UserID     interface{}
+	Name       string
+	TokenHash  interface{}
+	Scopes     string
+	CreatedAt  time.Time
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
 type AppliedTag struct {
 	ID          interface{}
 	SampleID    interface{}
@@ -17,6 +40,15 @@ type AppliedTag struct {
 	DateRemoved sql.NullTime
 }
 
+type JobRun struct {
+	ID         int64
+	JobName    string
+	StartedAt  time.Time
+	FinishedAt sql.NullTime
+	Status     string
+	Output     string
+}
+
 type Location struct {
 	ID               interface{}
 	Name             string
@@ -24,6 +56,22 @@ type Location struct {
 	ParentLocationID interface{}
 }
 
+type Notification struct {
+	ID        interface{}
+	UserID    interface{}
+	Kind      string
+	Subject   string
+	Body      string
+	CreatedAt time.Time
+	SentAt    sql.NullTime
+}
+
+type NotificationPreference struct {
+	UserID        interface{}
+	Digest        string
+	DisabledKinds string
+}
+
 type Product struct {
 	ID              interface{}
 	Name            string
@@ -47,6 +95,17 @@ type SampleComment struct {
 	SampleID  interface{}
 	Comment   string
 	CreatedAt sql.NullTime
+	AuthorID  interface{}
+}
+
+type SampleLoan struct {
+	ID         interface{}
+	SampleID   interface{}
+	UserID     interface{}
+	LoanedAt   time.Time
+	DueAt      time.Time
+	ReturnedAt sql.NullTime
+	RemindedAt sql.NullTime
 }
 
 type SampleMod struct {
@@ -55,6 +114,8 @@ type SampleMod struct {
 	Name        string
 	TimeAdded   time.Time
 	TimeRemoved sql.NullTime
+	AddedBy     interface{}
+	RemovedBy   interface{}
 }
 
 type SampleNote struct {
@@ -62,6 +123,22 @@ type SampleNote struct {
 	SampleID interface{}
 	Contents string
 	TimeMade time.Time
+	AuthorID interface{}
+}
+
+type ScheduledJob struct {
+	Name       string
+	Schedule   string
+	Enabled    bool
+	LastRunAt  sql.NullTime
+	LastStatus sql.NullString
+}
+
+type Session struct {
+	ID        interface{}
+	UserID    interface{}
+	CreatedAt time.Time
+	ExpiresAt time.Time
 }
 
 type Tag struct {
@@ -71,6 +148,46 @@ type Tag struct {
 }
 
 type User struct {
-	ID   interface{}
-	Name string
+	ID          interface{}
+	Name        string
+	OidcSubject sql.NullString
+	Email       sql.NullString
+}
+
+type UserRole struct {
+	UserID interface{}
+	Role   string
+}
+
+type Webhook struct {
+	ID         interface{}
+	Url        string
+	EventTypes string
+	Secret     string
+	Active     bool
+	CreatedBy  interface{}
+	CreatedAt  time.Time
+}
+
+type WebhookAttempt struct {
+	ID             int64
+	DeliveryID     interface{}
+	AttemptedAt    time.Time
+	DurationMs     int64
+	ResponseStatus sql.NullInt64
+	ResponseBody   sql.NullString
+	Error          sql.NullString
+}
+
+type WebhookDelivery struct {
+	ID            interface{}
+	WebhookID     interface{}
+	EventID       int64
+	EventType     string
+	Payload       string
+	Status        string
+	Attempts      int64
+	NextAttemptAt time.Time
+	LastAttemptAt sql.NullTime
+	CreatedAt     time.Time
 }
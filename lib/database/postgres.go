@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"reesource-tracker/database/postgres"
+	sqlite_queries "reesource-tracker/database/queries"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The Go code in this package is generated from the SQLite queries. On PostgreSQL each query
+// is swapped for the one with the same name in database/postgres/queries, which takes the
+// same parameters (as $1, $2, ...) and returns the same columns. The PostgreSQL queries are
+// generated into pgdb as well, and TestPostgresQueriesMatch compares the two.
+type postgresDB struct {
+	db      DBTX
+	queries map[string]string
+}
+
+var queryStart = regexp.MustCompile(`(?m)^-- name: (\w+) :\w+`)
+
+// loadPostgresQueries reads the queries built into the binary, keyed by name. Every generated
+// query must have a PostgreSQL version, so the server refuses to start rather than run SQLite
+// SQL against PostgreSQL.
+func loadPostgresQueries() (map[string]string, error) {
+	queries, err := readQueries(postgres.Queries, "queries/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	generated, err := readQueries(sqlite_queries.Files, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	missing := []string{}
+	for name := range generated {
+		if _, ok := queries[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("queries missing from database/postgres/queries: %s", strings.Join(missing, ", "))
+	}
+	return queries, nil
+}
+
+// readQueries reads the sqlc queries in the files matching pattern, keyed by name.
+func readQueries(fsys fs.FS, pattern string) (map[string]string, error) {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	queries := map[string]string{}
+	for _, file := range files {
+		text, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		starts := queryStart.FindAllStringSubmatchIndex(string(text), -1)
+		for i, start := range starts {
+			end := len(text)
+			if i+1 < len(starts) {
+				end = starts[i+1][0]
+			}
+			name := string(text[start[2]:start[3]])
+			if _, ok := queries[name]; ok {
+				return nil, fmt.Errorf("%s: query %s is defined twice", file, name)
+			}
+			queries[name] = strings.TrimSpace(string(text[start[0]:end]))
+		}
+	}
+	return queries, nil
+}
+
+// query returns the PostgreSQL version of a generated query. Hand-written SQL, which has no
+// name, is passed through unchanged. loadPostgresQueries makes sure every generated query has
+// a version, so the fallback only catches queries added without regenerating.
+func (p postgresDB) query(ctx context.Context, query string) string {
+	name := queryName(query)
+	if name == "other" {
+		return query
+	}
+	if translated, ok := p.queries[name]; ok {
+		return translated
+	}
+	slog.ErrorContext(ctx, "Query has no PostgreSQL version", "query", name)
+	return query
+}
+
+func (p postgresDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.db.ExecContext(ctx, p.query(ctx, query), args...)
+}
+
+func (p postgresDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.db.PrepareContext(ctx, p.query(ctx, query))
+}
+
+func (p postgresDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.db.QueryContext(ctx, p.query(ctx, query), args...)
+}
+
+func (p postgresDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.db.QueryRowContext(ctx, p.query(ctx, query), args...)
+}
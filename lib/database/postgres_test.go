@@ -0,0 +1,99 @@
+package database
+
+import (
+	"reesource-tracker/lib/database/pgdb"
+	"reflect"
+	"testing"
+)
+
+// handWritten are the methods of Queries that aren't generated from a query.
+var handWritten = map[string]bool{
+	"WithTx":             true,
+	"EachSample":         true,
+	"GetSampleData":      true,
+	"ListSampleData":     true,
+	"RegisterNewSamples": true,
+}
+
+// Every generated query has a PostgreSQL version that takes the same parameters and returns
+// the same columns, since the PostgreSQL SQL is run in place of the SQLite SQL. The
+// PostgreSQL package is generated from database/postgres/queries only to be compared here.
+func TestPostgresQueriesMatch(t *testing.T) {
+	sqlite := reflect.TypeOf(&Queries{})
+	postgres := reflect.TypeOf(&pgdb.Queries{})
+	for i := 0; i < sqlite.NumMethod(); i++ {
+		method := sqlite.Method(i)
+		if handWritten[method.Name] {
+			continue
+		}
+		t.Run(method.Name, func(t *testing.T) {
+			other, ok := postgres.MethodByName(method.Name)
+			if !ok {
+				t.Fatal("no PostgreSQL version")
+			}
+			if method.Type.NumIn() != other.Type.NumIn() || method.Type.NumOut() != other.Type.NumOut() {
+				t.Fatalf("got %s, want the shape of %s", other.Type, method.Type)
+			}
+			for in := 1; in < method.Type.NumIn(); in++ {
+				sameShape(t, "parameter", method.Type.In(in), other.Type.In(in))
+			}
+			for out := 0; out < method.Type.NumOut(); out++ {
+				sameShape(t, "result", method.Type.Out(out), other.Type.Out(out))
+			}
+		})
+	}
+	for i := 0; i < postgres.NumMethod(); i++ {
+		if name := postgres.Method(i).Name; !handWritten[name] {
+			if _, ok := sqlite.MethodByName(name); !ok {
+				t.Errorf("%s has no SQLite version", name)
+			}
+		}
+	}
+}
+
+var anyType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// sameShape checks that two generated types have the same fields, allowing for SQLite
+// BLOB columns being generated as interface{} where PostgreSQL BYTEA columns are []byte, and
+// for LIMIT being an int32 in PostgreSQL.
+func sameShape(t *testing.T, what string, sqlite reflect.Type, postgres reflect.Type) {
+	t.Helper()
+	if sqlite.Kind() != postgres.Kind() {
+		if sqlite == anyType && postgres == reflect.TypeOf([]byte(nil)) {
+			return
+		}
+		if sqlite.Kind() == reflect.Int64 && postgres.Kind() == reflect.Int32 {
+			return
+		}
+		t.Errorf("%s: got %s, want %s", what, postgres, sqlite)
+		return
+	}
+	switch sqlite.Kind() {
+	case reflect.Slice:
+		sameShape(t, what, sqlite.Elem(), postgres.Elem())
+	case reflect.Struct:
+		if sqlite.PkgPath() != reflect.TypeOf(Queries{}).PkgPath() {
+			// time.Time, sql.NullString and the like
+			if sqlite != postgres {
+				t.Errorf("%s: got %s, want %s", what, postgres, sqlite)
+			}
+			return
+		}
+		if sqlite.NumField() != postgres.NumField() {
+			t.Errorf("%s %s: got %d fields, want %d", what, sqlite.Name(), postgres.NumField(), sqlite.NumField())
+			return
+		}
+		for i := 0; i < sqlite.NumField(); i++ {
+			field, other := sqlite.Field(i), postgres.Field(i)
+			if field.Name != other.Name {
+				t.Errorf("%s %s field %d: got %s, want %s", what, sqlite.Name(), i, other.Name, field.Name)
+				continue
+			}
+			sameShape(t, what+" "+sqlite.Name()+"."+field.Name, field.Type, other.Type)
+		}
+	default:
+		if sqlite != postgres {
+			t.Errorf("%s: got %s, want %s", what, postgres, sqlite)
+		}
+	}
+}
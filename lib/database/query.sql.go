@@ -1,6 +1,6 @@
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
-//   sqlc v1.29.0
+//   sqlc v1.30.0
 // source: query.sql
 
 package database
@@ -13,9 +13,9 @@ import (
 
 const addSampleMod = `-- name: AddSampleMod :exec
 INSERT INTO
-    sample_mods (id, sample_id, name, time_added, time_removed)
+    sample_mods (id, sample_id, name, time_added, time_removed, added_by)
 VALUES
-    (?, ?, ?, ?, NULL)
+    (?, ?, ?, ?, NULL, ?)
 `
 
 type AddSampleModParams struct {
@@ -23,6 +23,7 @@ type AddSampleModParams struct {
 	SampleID  interface{}
 	Name      string
 	TimeAdded time.Time
+	AddedBy   interface{}
 }
 
 func (q *Queries) AddSampleMod(ctx context.Context, arg AddSampleModParams) error {
@@ -31,6 +32,7 @@ func (q *Queries) AddSampleMod(ctx context.Context, arg AddSampleModParams) erro
 		arg.SampleID,
 		arg.Name,
 		arg.TimeAdded,
+		arg.AddedBy,
 	)
 	return err
 }
@@ -206,8 +208,86 @@ func (q *Queries) GetSampleById(ctx context.Context, id interface{}) (Sample, er
 	return i, err
 }
 
+const getSampleMod = `-- name: GetSampleMod :one
+SELECT
+    id, sample_id, name, time_added, time_removed, added_by, removed_by
+FROM
+    sample_mods
+WHERE
+    id = ?
+`
+
+func (q *Queries) GetSampleMod(ctx context.Context, id interface{}) (SampleMod, error) {
+	row := q.db.QueryRowContext(ctx, getSampleMod, id)
+	var i SampleMod
+	err := row.Scan(
+		&i.ID,
+		&i.SampleID,
+		&i.Name,
+		&i.TimeAdded,
+		&i.TimeRemoved,
+		&i.AddedBy,
+		&i.RemovedBy,
+	)
+	return i, err
+}
+
+const getSampleSummary = `-- name: GetSampleSummary :one
+SELECT
+    samples.id, samples.location_id, samples.product_id, samples.time_registered, samples.last_update, samples.state, samples.owner_id, samples.product_issue,
+    COALESCE(
+        (
+            SELECT
+                GROUP_CONCAT (sample_mods.name, ', ')
+            FROM
+                sample_mods
+            WHERE
+                sample_mods.sample_id = samples.id
+                AND sample_mods.time_removed IS NULL
+        ),
+        ''
+    ) AS current_mods_summary,
+    users.name AS owner_name
+FROM
+    samples
+LEFT JOIN users ON samples.owner_id = users.id
+WHERE
+    samples.id = ?
+`
+
+type GetSampleSummaryRow struct {
+	ID                 interface{}
+	LocationID         interface{}
+	ProductID          interface{}
+	TimeRegistered     sql.NullTime
+	LastUpdate         sql.NullTime
+	State              string
+	OwnerID            interface{}
+	ProductIssue       sql.NullString
+	CurrentModsSummary interface{}
+	OwnerName          sql.NullString
+}
+
+func (q *Queries) GetSampleSummary(ctx context.Context, id interface{}) (GetSampleSummaryRow, error) {
+	row := q.db.QueryRowContext(ctx, getSampleSummary, id)
+	var i GetSampleSummaryRow
+	err := row.Scan(
+		&i.ID,
+		&i.LocationID,
+		&i.ProductID,
+		&i.TimeRegistered,
+		&i.LastUpdate,
+		&i.State,
+		&i.OwnerID,
+		&i.ProductIssue,
+		&i.CurrentModsSummary,
+		&i.OwnerName,
+	)
+	return i, err
+}
+
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, name
+SELECT id, name, oidc_subject, email
 FROM
     users
 WHERE
@@ -217,12 +297,17 @@ WHERE
 func (q *Queries) GetUserByID(ctx context.Context, id interface{}) (User, error) {
 	row := q.db.QueryRowContext(ctx, getUserByID, id)
 	var i User
-	err := row.Scan(&i.ID, &i.Name)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.OidcSubject,
+		&i.Email,
+	)
 	return i, err
 }
 
 const getUsers = `-- name: GetUsers :many
-SELECT id, name
+SELECT id, name, oidc_subject, email
 FROM
     users
 ORDER BY
@@ -238,7 +323,12 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 	var items []User
 	for rows.Next() {
 		var i User
-		if err := rows.Scan(&i.ID, &i.Name); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.OidcSubject,
+			&i.Email,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -291,7 +381,7 @@ func (q *Queries) ListProducts(ctx context.Context) ([]Product, error) {
 
 const listSampleMods = `-- name: ListSampleMods :many
 SELECT
-    id, sample_id, name, time_added, time_removed
+    id, sample_id, name, time_added, time_removed, added_by, removed_by
 FROM
     sample_mods
 WHERE
@@ -315,6 +405,8 @@ func (q *Queries) ListSampleMods(ctx context.Context, sampleID interface{}) ([]S
 			&i.Name,
 			&i.TimeAdded,
 			&i.TimeRemoved,
+			&i.AddedBy,
+			&i.RemovedBy,
 		); err != nil {
 			return nil, err
 		}
@@ -399,21 +491,66 @@ func (q *Queries) ListSamples(ctx context.Context) ([]ListSamplesRow, error) {
 	return items, nil
 }
 
+const listStaleSamples = `-- name: ListStaleSamples :many
+SELECT id, location_id, product_id, time_registered, last_update, state, owner_id, product_issue
+FROM
+    samples
+WHERE
+    last_update < ?
+    AND state NOT IN ('archived', 'unassigned')
+ORDER BY
+    last_update
+`
+
+func (q *Queries) ListStaleSamples(ctx context.Context, lastUpdate sql.NullTime) ([]Sample, error) {
+	rows, err := q.db.QueryContext(ctx, listStaleSamples, lastUpdate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Sample
+	for rows.Next() {
+		var i Sample
+		if err := rows.Scan(
+			&i.ID,
+			&i.LocationID,
+			&i.ProductID,
+			&i.TimeRegistered,
+			&i.LastUpdate,
+			&i.State,
+			&i.OwnerID,
+			&i.ProductIssue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const removeSampleMod = `-- name: RemoveSampleMod :exec
 UPDATE sample_mods
 SET
-    time_removed = ?
+    time_removed = ?,
+    removed_by = ?
 WHERE
     id = ?
 `
 
 type RemoveSampleModParams struct {
 	TimeRemoved sql.NullTime
+	RemovedBy   interface{}
 	ID          interface{}
 }
 
 func (q *Queries) RemoveSampleMod(ctx context.Context, arg RemoveSampleModParams) error {
-	_, err := q.db.ExecContext(ctx, removeSampleMod, arg.TimeRemoved, arg.ID)
+	_, err := q.db.ExecContext(ctx, removeSampleMod, arg.TimeRemoved, arg.RemovedBy, arg.ID)
 	return err
 }
 
@@ -477,6 +614,24 @@ func (q *Queries) UpdateOrCreateSample(ctx context.Context, arg UpdateOrCreateSa
 	return i, err
 }
 
+const updateUserEmail = `-- name: UpdateUserEmail :exec
+UPDATE users
+SET
+    email = ?
+WHERE
+    id = ?
+`
+
+type UpdateUserEmailParams struct {
+	Email sql.NullString
+	ID    interface{}
+}
+
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserEmail, arg.Email, arg.ID)
+	return err
+}
+
 const upsertLocation = `-- name: UpsertLocation :exec
 INSERT INTO
     locations (id, name, description, parent_location_id)
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	sampleid "reesource-tracker/lib/sample_id"
+)
+
+// SampleData is a sample together with its mods, in the shape returned by the samples API.
+type SampleData struct {
+	ListSamplesRow
+	Mods []SampleMod `json:"mods"`
+}
+
+// GetSampleData loads a single sample in the same shape as ListSampleData.
+func (q *Queries) GetSampleData(ctx context.Context, id interface{}) (SampleData, error) {
+	row, err := q.GetSampleSummary(ctx, id)
+	if err != nil {
+		return SampleData{}, err
+	}
+	mods, err := q.ListSampleMods(ctx, id)
+	if err != nil {
+		return SampleData{}, err
+	}
+	return SampleData{ListSamplesRow(row), mods}, nil
+}
+
+// ListSampleData loads every sample with its mods.
+func (q *Queries) ListSampleData(ctx context.Context) ([]SampleData, error) {
+	rows, err := q.ListSamples(ctx)
+	if err != nil {
+		return nil, err
+	}
+	samples := []SampleData{}
+	for _, row := range rows {
+		mods, err := q.ListSampleMods(ctx, row.ID)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, SampleData{row, mods})
+	}
+	return samples, nil
+}
+
+// EachSample calls fn with every sample in the order of ListSamples, reading them one at a
+// time so that exports don't hold every sample in memory. It stops at the first error.
+func (q *Queries) EachSample(ctx context.Context, fn func(ListSamplesRow) error) error {
+	rows, err := q.db.QueryContext(ctx, listSamples)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i ListSamplesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.LocationID,
+			&i.ProductID,
+			&i.TimeRegistered,
+			&i.LastUpdate,
+			&i.State,
+			&i.OwnerID,
+			&i.ProductIssue,
+			&i.CurrentModsSummary,
+			&i.OwnerName,
+		); err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+// ErrNoFreeSampleIDs is returned when random sample IDs keep colliding with existing ones.
+var ErrNoFreeSampleIDs = errors.New("could not find unused sample IDs")
+
+// GENERATE_ATTEMPTS is how many random IDs RegisterNewSamples tries for each sample.
+const GENERATE_ATTEMPTS = 10
+
+// RegisterNewSamples adds count unassigned samples with random IDs that aren't in use yet,
+// e.g. to print labels for, and returns their raw IDs. Run it in a transaction so a batch is
+// added entirely or not at all.
+func (q *Queries) RegisterNewSamples(ctx context.Context, count int) ([][]byte, error) {
+	ids := make([][]byte, 0, count)
+	for attempts := 0; len(ids) < count; attempts++ {
+		if attempts >= count*GENERATE_ATTEMPTS {
+			return nil, ErrNoFreeSampleIDs
+		}
+		_, raw, err := sampleid.GenerateNewSampleID()
+		if err != nil {
+			return nil, err
+		}
+		_, err = q.GetSampleById(ctx, raw[:])
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		_, err = q.UpdateOrCreateSample(ctx, UpdateOrCreateSampleParams{
+			ID:    raw[:],
+			State: "unassigned",
+		})
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, raw[:])
+	}
+	return ids, nil
+}
@@ -2,27 +2,125 @@ package database
 
 import (
 	"context"
-	"os"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reesource-tracker/lib/config"
+	postgres_driver "reesource-tracker/lib/database/drivers/postgres"
 	sqlite_driver "reesource-tracker/lib/database/drivers/sqlite"
+
+	"github.com/golang-migrate/migrate/v4"
 )
 
 var Connection *Queries
 
-func Connect(ctx context.Context) {
-	migration_dir := "file://database/migrations"
-	if _, err := os.Stat("migrations"); err == nil {
-		migration_dir = "file://migrations"
+// DB is the underlying handle, for statements sqlc can't express such as VACUUM INTO.
+var DB *sql.DB
+
+// wrap adds timing and, on PostgreSQL, query translation to a connection or transaction.
+var wrap = func(db DBTX) DBTX { return timedDB{db} }
+
+// migrator applies the migrations to the open database.
+var migrator *migrate.Migrate
+
+// Connect opens the database selected in the configuration and applies any pending
+// migrations.
+func Connect(ctx context.Context) error {
+	if err := Open(ctx); err != nil {
+		return err
+	}
+	if err := MigrateUp(); err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	return nil
+}
+
+// Open opens the database selected in the configuration without migrating it.
+func Open(ctx context.Context) error {
+	var (
+		db  *sql.DB
+		m   *migrate.Migrate
+		err error
+	)
+	switch config.Current.DBDriver {
+	case config.DRIVER_POSTGRES:
+		queries, loadErr := loadPostgresQueries()
+		if loadErr != nil {
+			return fmt.Errorf("failed to load postgres queries: %w", loadErr)
+		}
+		db, m, err = postgres_driver.Connect(ctx, config.Current.DatabaseURL)
+		wrap = func(db DBTX) DBTX { return timedDB{postgresDB{db, queries}} }
+	default:
+		db, m, err = sqlite_driver.Connect(ctx, config.Current.DBPath, config.Current.MigrationsURL())
+		wrap = func(db DBTX) DBTX { return timedDB{db} }
 	}
-	db, m, err := sqlite_driver.Connect(ctx, migration_dir)
 	if err != nil {
-		println("Got error", err.Error())
-		return
+		return err
+	}
+	DB = db
+	Connection = New(wrap(db))
+	migrator = m
+	return nil
+}
+
+// MigrateUp applies every pending migration.
+func MigrateUp() error {
+	err := migrator.Up()
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// ErrNoMigrations is returned when migrating down a database that has no migrations applied.
+var ErrNoMigrations = errors.New("no migrations have been applied")
+
+// MigrateDown reverts the most recent steps migrations. If fewer were applied, they are all
+// reverted and an error says how many.
+func MigrateDown(steps int) error {
+	if _, _, err := migrator.Version(); errors.Is(err, migrate.ErrNilVersion) {
+		return ErrNoMigrations
 	}
-	Connection = New(db)
+	err := migrator.Steps(-steps)
+	var short migrate.ErrShortLimit
+	if errors.As(err, &short) {
+		return fmt.Errorf("only %d migrations were applied, all of them were reverted", uint(steps)-short.Short)
+	}
+	return err
+}
+
+// MigrationVersion is the migration the database is at, 0 if it has none, and whether the
+// last one failed part way.
+func MigrationVersion() (uint, bool, error) {
+	version, dirty, err := migrator.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
 
-	err = m.Up()
-	if err != nil && err.Error() != "no change" {
-		println("Error applying migration", err.Error())
-		return
+// Close closes the database, checkpointing SQLite's WAL first. It is called once the server
+// has stopped.
+func Close(ctx context.Context) error {
+	if DB == nil {
+		return nil
+	}
+	if config.Current.DBDriver == config.DRIVER_POSTGRES {
+		return postgres_driver.Close(ctx, DB)
+	}
+	return sqlite_driver.Close(ctx, DB)
+}
+
+// Transaction runs fn with queries that use a single transaction, which is committed if fn
+// returns nil and rolled back otherwise.
+func Transaction(ctx context.Context, fn func(q *Queries) error) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(New(wrap(tx))); err != nil {
+		tx.Rollback()
+		return err
 	}
+	return tx.Commit()
 }
@@ -0,0 +1,253 @@
+// Package dump writes every table to a JSON or NDJSON archive and loads such archives back,
+// to copy the data between instances regardless of their database engine.
+package dump
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reesource-tracker/lib/config"
+	"reesource-tracker/lib/database"
+	"strings"
+	"time"
+)
+
+// Archive formats. JSON is a single document with a list of rows per table; NDJSON is a header
+// line followed by one line per row, so it can be read a row at a time.
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// FORMAT_NAME identifies archives written by this package.
+const FORMAT_NAME = "reesource-tracker-dump"
+
+// FORMAT_VERSION changes whenever the archive layout does. Changes to the tables are tracked
+// by the schema version instead.
+const FORMAT_VERSION = 1
+
+var (
+	ErrUnknownFormat  = errors.New("unknown dump format, expected json or ndjson")
+	ErrInvalidArchive = errors.New("not a reesource-tracker dump")
+	ErrNewerFormat    = errors.New("dump was written by a newer version of the server")
+	ErrSchemaMismatch = errors.New("dump and database are at different schema versions")
+)
+
+// Header starts every archive.
+type Header struct {
+	Format        string    `json:"format"`
+	Version       int       `json:"version"`
+	SchemaVersion uint      `json:"schema_version"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ndjsonRow is each line after the header of an NDJSON archive.
+type ndjsonRow struct {
+	Table string          `json:"table"`
+	Row   json.RawMessage `json:"row"`
+}
+
+// FormatFor picks the format of an archive from its file name: NDJSON for .ndjson and .jsonl
+// files, JSON otherwise.
+func FormatFor(path string) string {
+	if strings.HasSuffix(path, ".ndjson") || strings.HasSuffix(path, ".jsonl") {
+		return FormatNDJSON
+	}
+	return FormatJSON
+}
+
+// Dump writes every table to w. The tables are read in one transaction, so the archive is a
+// consistent snapshot even while the server is running.
+func Dump(ctx context.Context, w io.Writer, format string) error {
+	if format != FormatJSON && format != FormatNDJSON {
+		return ErrUnknownFormat
+	}
+	opts := &sql.TxOptions{ReadOnly: true}
+	if config.Current.DBDriver == config.DRIVER_POSTGRES {
+		opts.Isolation = sql.LevelRepeatableRead
+	}
+	tx, err := database.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := checkSchema(ctx, tx); err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+	header, err := json.Marshal(Header{FORMAT_NAME, FORMAT_VERSION, SCHEMA_VERSION, time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	if format == FormatNDJSON {
+		out.Write(header)
+		out.WriteString("\n")
+	} else {
+		// The header fields, followed by the tables
+		out.Write(header[:len(header)-1])
+		out.WriteString(`,"tables":[`)
+	}
+	for i, t := range tables {
+		if format == FormatJSON {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			fmt.Fprintf(out, "\n{\"name\":%q,\"rows\":[", t.name)
+		}
+		first := true
+		err := t.each(ctx, tx, func(row []byte) error {
+			if format == FormatNDJSON {
+				fmt.Fprintf(out, "{\"table\":%q,\"row\":%s}\n", t.name, row)
+			} else {
+				if !first {
+					out.WriteString(",")
+				}
+				out.WriteString("\n")
+				out.Write(row)
+			}
+			first = false
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.name, err)
+		}
+		if format == FormatJSON {
+			out.WriteString("]}")
+		}
+	}
+	if format == FormatJSON {
+		out.WriteString("\n]}\n")
+	}
+	return out.Flush()
+}
+
+// checkSchema makes sure the database is at the version the tables describe.
+func checkSchema(ctx context.Context, tx *sql.Tx) error {
+	var (
+		version uint
+		dirty   bool
+	)
+	if err := tx.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations").Scan(&version, &dirty); err != nil {
+		return err
+	}
+	if dirty || version != SCHEMA_VERSION {
+		return fmt.Errorf("%w: the database is at %d, dumps are at %d", ErrSchemaMismatch, version, SCHEMA_VERSION)
+	}
+	return nil
+}
+
+// each calls fn with every row of the table as a JSON object. Rows of self-referential
+// tables are read into memory to put parents first; other tables are streamed.
+func (t *table) each(ctx context.Context, tx *sql.Tx, fn func([]byte) error) error {
+	names := make([]string, len(t.columns))
+	for i, col := range t.columns {
+		names[i] = col.name
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT "+strings.Join(names, ", ")+" FROM "+t.name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	values := make([]any, len(t.columns))
+	targets := make([]any, len(t.columns))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	var held []encodedRow
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return err
+		}
+		row, err := t.encode(values)
+		if err != nil {
+			return err
+		}
+		if t.parent != "" {
+			held = append(held, row)
+		} else if err := fn(row.json); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, row := range parentsFirst(held) {
+		if err := fn(row.json); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodedRow is a row in its archive form, with the IDs needed to order it.
+type encodedRow struct {
+	json       []byte
+	id, parent string
+}
+
+// encode writes a row as a JSON object with the columns in table order.
+func (t *table) encode(values []any) (encodedRow, error) {
+	row := encodedRow{}
+	var b strings.Builder
+	b.WriteString("{")
+	for i, col := range t.columns {
+		value, err := col.encode(values[i])
+		if err != nil {
+			return row, fmt.Errorf("%s: %w", col.name, err)
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return row, err
+		}
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, "%q:%s", col.name, encoded)
+		if s, ok := value.(string); ok && i == 0 {
+			row.id = s
+		} else if ok && col.name == t.parent {
+			row.parent = s
+		}
+	}
+	b.WriteString("}")
+	row.json = []byte(b.String())
+	return row, nil
+}
+
+// parentsFirst orders rows so every row comes after its parent. Rows whose parent isn't among
+// them, and rows in a cycle, keep their original order.
+func parentsFirst(rows []encodedRow) []encodedRow {
+	children := map[string][]int{}
+	ids := map[string]bool{}
+	for _, row := range rows {
+		ids[row.id] = true
+	}
+	ordered := make([]encodedRow, 0, len(rows))
+	placed := make([]bool, len(rows))
+	queue := []int{}
+	for i, row := range rows {
+		if row.parent != "" && ids[row.parent] && row.parent != row.id {
+			children[row.parent] = append(children[row.parent], i)
+		} else {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		placed[i] = true
+		ordered = append(ordered, rows[i])
+		queue = append(queue, children[rows[i].id]...)
+	}
+	for i, row := range rows {
+		if !placed[i] {
+			ordered = append(ordered, row)
+		}
+	}
+	return ordered
+}
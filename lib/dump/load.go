@@ -0,0 +1,269 @@
+package dump
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reesource-tracker/lib/config"
+	"reesource-tracker/lib/database"
+	"strconv"
+	"strings"
+)
+
+// What to do with a row whose primary key is already in the database
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictFail      = "fail"
+)
+
+var (
+	ErrUnknownPolicy = errors.New("unknown conflict policy, expected skip, overwrite or fail")
+	ErrConflict      = errors.New("row already exists")
+)
+
+// TableResult counts what happened to the rows of one table.
+type TableResult struct {
+	Table    string `json:"table"`
+	Inserted int    `json:"inserted"`
+	Updated  int    `json:"updated"`
+	Skipped  int    `json:"skipped"`
+}
+
+// Result describes a loaded archive.
+type Result struct {
+	Header Header        `json:"header"`
+	Tables []TableResult `json:"tables"`
+}
+
+// jsonArchive is the document of a JSON archive. Tables is missing from the header line of an
+// NDJSON one.
+type jsonArchive struct {
+	Header
+	Tables *[]struct {
+		Name string            `json:"name"`
+		Rows []json.RawMessage `json:"rows"`
+	} `json:"tables"`
+}
+
+// Load adds the rows of a JSON or NDJSON archive to the database in one transaction, so
+// nothing is loaded if any row fails. Rows already in the database are handled by policy.
+// NDJSON archives are read a row at a time; JSON ones are read into memory first.
+func Load(ctx context.Context, r io.Reader, policy string) (Result, error) {
+	result := Result{}
+	if policy != ConflictSkip && policy != ConflictOverwrite && policy != ConflictFail {
+		return result, ErrUnknownPolicy
+	}
+	decoder := json.NewDecoder(r)
+	var archive jsonArchive
+	if err := decoder.Decode(&archive); err != nil {
+		return result, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	result.Header = archive.Header
+	switch {
+	case archive.Format != FORMAT_NAME || archive.Version < 1:
+		return result, ErrInvalidArchive
+	case archive.Version > FORMAT_VERSION:
+		return result, ErrNewerFormat
+	case archive.SchemaVersion != SCHEMA_VERSION:
+		return result, fmt.Errorf("%w: the dump is at %d, this server is at %d", ErrSchemaMismatch, archive.SchemaVersion, SCHEMA_VERSION)
+	}
+
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+	if err := checkSchema(ctx, tx); err != nil {
+		return result, err
+	}
+	l := &loader{tx: tx, policy: policy, counts: map[string]*TableResult{}}
+	if archive.Tables != nil {
+		for _, t := range *archive.Tables {
+			for _, row := range t.Rows {
+				if err := l.add(ctx, t.Name, row); err != nil {
+					return result, err
+				}
+			}
+		}
+	} else {
+		for line := 2; ; line++ {
+			var row ndjsonRow
+			err := decoder.Decode(&row)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return result, fmt.Errorf("%w: line %d: %w", ErrInvalidArchive, line, err)
+			}
+			if err := l.add(ctx, row.Table, row.Row); err != nil {
+				return result, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+	}
+	if err := l.flush(ctx); err != nil {
+		return result, err
+	}
+	if config.Current.DBDriver == config.DRIVER_POSTGRES {
+		if err := l.moveSequences(ctx); err != nil {
+			return result, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+	for _, name := range l.order {
+		result.Tables = append(result.Tables, *l.counts[name])
+	}
+	return result, nil
+}
+
+type loader struct {
+	tx     *sql.Tx
+	policy string
+	counts map[string]*TableResult
+	order  []string
+	// held are the rows of the current table when it refers to itself, waiting to be put in
+	// order once the table ends
+	current *table
+	held    []encodedRow
+}
+
+// add loads a row, or holds it until the end of the table if the table refers to itself.
+func (l *loader) add(ctx context.Context, name string, raw json.RawMessage) error {
+	t, ok := findTable(name)
+	if !ok {
+		return fmt.Errorf("%w: unknown table %q", ErrInvalidArchive, name)
+	}
+	if t != l.current {
+		if err := l.flush(ctx); err != nil {
+			return err
+		}
+		l.current = t
+		if l.counts[name] == nil {
+			l.counts[name] = &TableResult{Table: name}
+			l.order = append(l.order, name)
+		}
+	}
+	if t.parent == "" {
+		return l.insert(ctx, t, raw)
+	}
+	var ids map[string]any
+	if err := json.Unmarshal(raw, &ids); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidArchive, name, err)
+	}
+	row := encodedRow{json: raw}
+	row.id, _ = ids[t.columns[0].name].(string)
+	row.parent, _ = ids[t.parent].(string)
+	l.held = append(l.held, row)
+	return nil
+}
+
+// flush loads the held rows, parents first.
+func (l *loader) flush(ctx context.Context) error {
+	held := l.held
+	l.held = nil
+	for _, row := range parentsFirst(held) {
+		if err := l.insert(ctx, l.current, row.json); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *loader) insert(ctx context.Context, t *table, raw json.RawMessage) error {
+	values, key, err := t.decode(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidArchive, t.name, err)
+	}
+	counts := l.counts[t.name]
+	where := make([]string, t.key)
+	for i, col := range t.columns[:t.key] {
+		where[i] = col.name + " = " + placeholder(i+1)
+	}
+	err = l.tx.QueryRowContext(ctx, "SELECT 1 FROM "+t.name+" WHERE "+strings.Join(where, " AND "), values[:t.key]...).Scan(new(int))
+	exists := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s %s: %w", t.name, key, err)
+	}
+
+	switch {
+	case !exists:
+		names := make([]string, len(t.columns))
+		params := make([]string, len(t.columns))
+		for i, col := range t.columns {
+			names[i] = col.name
+			params[i] = placeholder(i + 1)
+		}
+		_, err = l.tx.ExecContext(ctx, "INSERT INTO "+t.name+" ("+strings.Join(names, ", ")+") VALUES ("+strings.Join(params, ", ")+")", values...)
+		counts.Inserted++
+	case l.policy == ConflictFail:
+		return fmt.Errorf("%w: %s %s", ErrConflict, t.name, key)
+	case l.policy == ConflictSkip || len(t.columns) == t.key:
+		counts.Skipped++
+	default:
+		set := make([]string, 0, len(t.columns)-t.key)
+		for i, col := range t.columns[t.key:] {
+			set = append(set, col.name+" = "+placeholder(i+1))
+		}
+		for i, col := range t.columns[:t.key] {
+			where[i] = col.name + " = " + placeholder(len(set)+i+1)
+		}
+		args := append(append([]any{}, values[t.key:]...), values[:t.key]...)
+		_, err = l.tx.ExecContext(ctx, "UPDATE "+t.name+" SET "+strings.Join(set, ", ")+" WHERE "+strings.Join(where, " AND "), args...)
+		counts.Updated++
+	}
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", t.name, key, err)
+	}
+	return nil
+}
+
+// decode reads a row from the archive. key describes its primary key for error messages.
+func (t *table) decode(raw json.RawMessage) (values []any, key string, err error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, "", err
+	}
+	values = make([]any, len(t.columns))
+	keys := []string{}
+	for i, col := range t.columns {
+		if values[i], err = col.decode(fields[col.name]); err != nil {
+			return nil, "", fmt.Errorf("%s: %w", col.name, err)
+		}
+		if i < t.key {
+			keys = append(keys, string(fields[col.name]))
+		}
+		delete(fields, col.name)
+	}
+	for name := range fields {
+		return nil, "", fmt.Errorf("unknown column %q", name)
+	}
+	return values, strings.Join(keys, ", "), nil
+}
+
+// moveSequences sets the generated IDs of serial tables to continue after the loaded rows.
+func (l *loader) moveSequences(ctx context.Context) error {
+	for _, t := range tables {
+		if !t.serial || l.counts[t.name] == nil {
+			continue
+		}
+		id := t.columns[0].name
+		_, err := l.tx.ExecContext(ctx, "SELECT setval(pg_get_serial_sequence('"+t.name+"', '"+id+"'), COALESCE(MAX("+id+"), 0) + 1, false) FROM "+t.name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+// placeholder is the nth query parameter in the database's syntax.
+func placeholder(n int) string {
+	if config.Current.DBDriver == config.DRIVER_POSTGRES {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
@@ -0,0 +1,152 @@
+package dump
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reesource-tracker/lib/config"
+	"reesource-tracker/lib/database"
+	sqlite_driver "reesource-tracker/lib/database/drivers/sqlite"
+	"reesource-tracker/lib/testenv"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParentsFirst(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []string // id or id>parent
+		want []string
+	}{
+		{name: "none", rows: nil, want: []string{}},
+		{name: "in order", rows: []string{"a", "b>a", "c>b"}, want: []string{"a", "b", "c"}},
+		{name: "reversed", rows: []string{"c>b", "b>a", "a"}, want: []string{"a", "b", "c"}},
+		{name: "siblings keep their order", rows: []string{"y>r", "x>r", "r", "z>r"}, want: []string{"r", "y", "x", "z"}},
+		{name: "parent elsewhere", rows: []string{"b>a", "c>b"}, want: []string{"b", "c"}},
+		{name: "own parent", rows: []string{"b>a", "a>a"}, want: []string{"a", "b"}},
+		{name: "cycle", rows: []string{"r", "a>b", "b>a", "c>r"}, want: []string{"r", "c", "a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := []encodedRow{}
+			for _, row := range tt.rows {
+				id, parent, _ := strings.Cut(row, ">")
+				rows = append(rows, encodedRow{json: []byte(id), id: id, parent: parent})
+			}
+			got := []string{}
+			for _, row := range parentsFirst(rows) {
+				got = append(got, string(row.json))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Loading an archive whose first location is already in the database, listed with its
+// children's children first.
+func TestLoad(t *testing.T) {
+	testenv.Engines(t, func(t *testing.T) {
+		ctx := context.Background()
+		enforceForeignKeys(t)
+		lab, shelf, box := uuid.New(), uuid.New(), uuid.New()
+		rows := []string{location(box, "Box", &shelf), location(shelf, "Shelf", &lab), location(lab, "Main lab", nil)}
+
+		tests := []struct {
+			policy  string
+			format  string
+			want    TableResult
+			labName string
+			err     error
+		}{
+			{policy: ConflictSkip, format: FormatJSON, want: TableResult{Table: "locations", Inserted: 2, Skipped: 1}, labName: "Lab"},
+			{policy: ConflictSkip, format: FormatNDJSON, want: TableResult{Table: "locations", Inserted: 2, Skipped: 1}, labName: "Lab"},
+			{policy: ConflictOverwrite, format: FormatJSON, want: TableResult{Table: "locations", Inserted: 2, Updated: 1}, labName: "Main lab"},
+			{policy: ConflictOverwrite, format: FormatNDJSON, want: TableResult{Table: "locations", Inserted: 2, Updated: 1}, labName: "Main lab"},
+			{policy: ConflictFail, format: FormatJSON, labName: "Lab", err: ErrConflict},
+			{policy: ConflictFail, format: FormatNDJSON, labName: "Lab", err: ErrConflict},
+			{policy: "merge", format: FormatJSON, labName: "Lab", err: ErrUnknownPolicy},
+		}
+		for _, tt := range tests {
+			t.Run(tt.policy+"/"+tt.format, func(t *testing.T) {
+				for _, id := range []uuid.UUID{box, shelf, lab} {
+					if err := database.Connection.DeleteLocationByID(ctx, id[:]); err != nil {
+						t.Fatal(err)
+					}
+				}
+				err := database.Connection.UpsertLocation(ctx, database.UpsertLocationParams{ID: lab[:], Name: "Lab"})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				result, err := Load(ctx, archive(t, tt.format, "locations", rows...), tt.policy)
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got %v, want %v", err, tt.err)
+				}
+				if got, err := database.Connection.GetLocation(ctx, lab[:]); err != nil || got.Name != tt.labName {
+					t.Errorf("existing location: got %q, %v, want %q", got.Name, err, tt.labName)
+				}
+				_, err = database.Connection.GetLocation(ctx, box[:])
+				if tt.err != nil {
+					// Nothing is loaded when a row fails
+					if !errors.Is(err, sql.ErrNoRows) {
+						t.Errorf("new location after a failed load: got %v, want none", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Errorf("new location: %v", err)
+				}
+				if len(result.Tables) != 1 || result.Tables[0] != tt.want {
+					t.Errorf("got %+v, want %+v", result.Tables, tt.want)
+				}
+			})
+		}
+	})
+}
+
+// enforceForeignKeys keeps the test to the one connection Connect switched foreign keys on
+// for, so on SQLite too a child loaded before its parent fails.
+func enforceForeignKeys(t *testing.T) {
+	t.Helper()
+	if config.Current.DBDriver != config.DRIVER_SQLITE {
+		return
+	}
+	database.DB.SetMaxOpenConns(1)
+	if _, err := database.DB.Exec(sqlite_driver.FOREIGN_KEY_PRAGMA); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func location(id uuid.UUID, name string, parent *uuid.UUID) string {
+	parentID := "null"
+	if parent != nil {
+		parentID = fmt.Sprintf("%q", parent.String())
+	}
+	return fmt.Sprintf(`{"id":%q,"name":%q,"description":null,"parent_location_id":%s}`, id.String(), name, parentID)
+}
+
+// archive writes rows of one table as a dump in format.
+func archive(t *testing.T, format string, table string, rows ...string) io.Reader {
+	t.Helper()
+	header, err := json.Marshal(Header{FORMAT_NAME, FORMAT_VERSION, SCHEMA_VERSION, time.Now().UTC()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format == FormatNDJSON {
+		lines := []string{string(header)}
+		for _, row := range rows {
+			lines = append(lines, fmt.Sprintf(`{"table":%q,"row":%s}`, table, row))
+		}
+		return strings.NewReader(strings.Join(lines, "\n"))
+	}
+	return strings.NewReader(fmt.Sprintf(`%s,"tables":[{"name":%q,"rows":[%s]}]}`, header[:len(header)-1], table, strings.Join(rows, ",")))
+}
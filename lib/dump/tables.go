@@ -0,0 +1,233 @@
+package dump
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	sampleid "reesource-tracker/lib/sample_id"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SCHEMA_VERSION is the migration the tables below describe. Dumps are only written from,
+// and loaded into, a database at this version, so it has to be updated along with the
+// tables whenever a migration is added.
+const SCHEMA_VERSION = 9
+
+// Types of column, which decide how values are written in the archive
+const (
+	colText = iota
+	colInt
+	colBool
+	colTime
+	// colUUID is a 16 byte ID, written as a UUID string
+	colUUID
+	// colSampleID is a 4 byte sample ID, written in the XX-XX-XX format
+	colSampleID
+	// colBytes is any other blob, written as hex
+	colBytes
+)
+
+type column struct {
+	name string
+	kind int
+}
+
+type table struct {
+	name    string
+	columns []column
+	// key is the number of leading columns that make up the primary key
+	key int
+	// parent is the column referring to another row of the same table, if any. Rows are
+	// ordered so that parents come before their children.
+	parent string
+	// serial tables have generated integer IDs, whose sequence has to be moved past the
+	// loaded rows on PostgreSQL
+	serial bool
+}
+
+// tables are every table in the schema except sessions, in an order where rows only refer to
+// rows of earlier tables. Sessions are left out since they are short lived, and copying them
+// would let cookies from one instance sign in to another.
+var tables = []table{
+	{name: "users", key: 1, columns: []column{
+		{"id", colUUID}, {"name", colText}, {"email", colText}, {"oidc_subject", colText},
+	}},
+	{name: "user_roles", key: 2, columns: []column{
+		{"user_id", colUUID}, {"role", colText},
+	}},
+	{name: "notification_preferences", key: 1, columns: []column{
+		{"user_id", colUUID}, {"digest", colText}, {"disabled_kinds", colText},
+	}},
+	{name: "locations", key: 1, parent: "parent_location_id", columns: []column{
+		{"id", colUUID}, {"name", colText}, {"description", colText}, {"parent_location_id", colUUID},
+	}},
+	{name: "products", key: 1, parent: "parent_product_id", columns: []column{
+		{"id", colUUID}, {"name", colText}, {"parent_product_id", colUUID}, {"part_number", colText},
+	}},
+	{name: "samples", key: 1, columns: []column{
+		{"id", colSampleID}, {"location_id", colUUID}, {"product_id", colUUID}, {"time_registered", colTime},
+		{"last_update", colTime}, {"state", colText}, {"owner_id", colUUID}, {"product_issue", colText},
+	}},
+	{name: "sample_mods", key: 1, columns: []column{
+		{"id", colUUID}, {"sample_id", colSampleID}, {"name", colText}, {"time_added", colTime},
+		{"time_removed", colTime}, {"added_by", colUUID}, {"removed_by", colUUID},
+	}},
+	{name: "sample_notes", key: 1, columns: []column{
+		{"id", colUUID}, {"sample_id", colSampleID}, {"contents", colText}, {"time_made", colTime}, {"author_id", colUUID},
+	}},
+	{name: "sample_comments", key: 1, columns: []column{
+		{"id", colUUID}, {"sample_id", colSampleID}, {"comment", colText}, {"created_at", colTime}, {"author_id", colUUID},
+	}},
+	{name: "tags", key: 1, columns: []column{
+		{"id", colUUID}, {"name", colText}, {"removable", colBool},
+	}},
+	{name: "applied_tags", key: 1, columns: []column{
+		{"id", colUUID}, {"sample_id", colSampleID}, {"tag_id", colUUID}, {"date_added", colTime}, {"date_removed", colTime},
+	}},
+	{name: "sample_loans", key: 1, columns: []column{
+		{"id", colUUID}, {"sample_id", colSampleID}, {"user_id", colUUID}, {"loaned_at", colTime},
+		{"due_at", colTime}, {"returned_at", colTime}, {"reminded_at", colTime},
+	}},
+	{name: "api_tokens", key: 1, columns: []column{
+		{"id", colUUID}, {"user_id", colUUID}, {"name", colText}, {"token_hash", colBytes}, {"scopes", colText},
+		{"created_at", colTime}, {"expires_at", colTime}, {"last_used_at", colTime}, {"revoked_at", colTime},
+	}},
+	{name: "activity_log", key: 1, columns: []column{
+		{"id", colUUID}, {"user_id", colUUID}, {"token_id", colUUID}, {"entity_kind", colText},
+		{"entity_id", colText}, {"action", colText}, {"details", colText}, {"time", colTime},
+	}},
+	{name: "webhooks", key: 1, columns: []column{
+		{"id", colUUID}, {"url", colText}, {"event_types", colText}, {"secret", colText},
+		{"active", colBool}, {"created_by", colUUID}, {"created_at", colTime},
+	}},
+	{name: "webhook_deliveries", key: 1, columns: []column{
+		{"id", colUUID}, {"webhook_id", colUUID}, {"event_id", colInt}, {"event_type", colText}, {"payload", colText},
+		{"status", colText}, {"attempts", colInt}, {"next_attempt_at", colTime}, {"last_attempt_at", colTime}, {"created_at", colTime},
+	}},
+	{name: "webhook_attempts", key: 1, serial: true, columns: []column{
+		{"id", colInt}, {"delivery_id", colUUID}, {"attempted_at", colTime}, {"duration_ms", colInt},
+		{"response_status", colInt}, {"response_body", colText}, {"error", colText},
+	}},
+	{name: "notifications", key: 1, columns: []column{
+		{"id", colUUID}, {"user_id", colUUID}, {"kind", colText}, {"subject", colText},
+		{"body", colText}, {"created_at", colTime}, {"sent_at", colTime},
+	}},
+	{name: "scheduled_jobs", key: 1, columns: []column{
+		{"name", colText}, {"schedule", colText}, {"enabled", colBool}, {"last_run_at", colTime}, {"last_status", colText},
+	}},
+	{name: "job_runs", key: 1, serial: true, columns: []column{
+		{"id", colInt}, {"job_name", colText}, {"started_at", colTime}, {"finished_at", colTime},
+		{"status", colText}, {"output", colText},
+	}},
+}
+
+func findTable(name string) (*table, bool) {
+	for i := range tables {
+		if tables[i].name == name {
+			return &tables[i], true
+		}
+	}
+	return nil, false
+}
+
+// encode turns a value read from the database into its archive form.
+func (col column) encode(value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch col.kind {
+	case colUUID:
+		raw, ok := value.([]byte)
+		if !ok {
+			break
+		}
+		id, err := uuid.FromBytes(raw)
+		if err != nil {
+			return nil, err
+		}
+		return id.String(), nil
+	case colSampleID:
+		raw, ok := value.([]byte)
+		if !ok {
+			break
+		}
+		return sampleid.FormatSampleID(raw)
+	case colBytes:
+		if raw, ok := value.([]byte); ok {
+			return hex.EncodeToString(raw), nil
+		}
+	case colTime:
+		switch t := value.(type) {
+		case time.Time:
+			return t.UTC().Format(time.RFC3339Nano), nil
+		case string:
+			// SQLite defaults such as CURRENT_TIMESTAMP are stored as text
+			parsed, err := time.Parse(time.DateTime, t)
+			if err != nil {
+				return nil, err
+			}
+			return parsed.UTC().Format(time.RFC3339Nano), nil
+		}
+	case colBool:
+		switch b := value.(type) {
+		case bool:
+			return b, nil
+		case int64:
+			return b != 0, nil
+		}
+	case colInt:
+		if n, ok := value.(int64); ok {
+			return n, nil
+		}
+	case colText:
+		switch s := value.(type) {
+		case string:
+			return s, nil
+		case []byte:
+			return string(s), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %T", value)
+}
+
+// decode turns a value from an archive into one to write to the database.
+func (col column) decode(raw json.RawMessage) (any, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	switch col.kind {
+	case colInt:
+		var n int64
+		err := json.Unmarshal(raw, &n)
+		return n, err
+	case colBool:
+		var b bool
+		err := json.Unmarshal(raw, &b)
+		return b, err
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	switch col.kind {
+	case colUUID:
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		return id[:], nil
+	case colSampleID:
+		id, err := sampleid.ParseSampleID(s)
+		if err != nil {
+			return nil, err
+		}
+		return id[:], nil
+	case colBytes:
+		return hex.DecodeString(s)
+	case colTime:
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	return s, nil
+}
@@ -0,0 +1,215 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	"reesource-tracker/lib/reports"
+	samplefilter "reesource-tracker/lib/sample_filter"
+	sampleid "reesource-tracker/lib/sample_id"
+	"strings"
+	"time"
+)
+
+// Kinds of table that can be exported
+const (
+	KindSamples   = "samples"
+	KindProducts  = "products"
+	KindLocations = "locations"
+	KindUsers     = "users"
+)
+
+var ErrUnknownKind = errors.New("unknown export kind")
+
+// PATH_SEPARATOR joins the names in product and location paths, as in the reports. The
+// importer accepts these paths.
+const PATH_SEPARATOR = " / "
+
+// TIME_FORMAT is how times are written, in UTC, so spreadsheets recognise them.
+const TIME_FORMAT = "2006-01-02 15:04:05"
+
+// Headers are the columns of each kind of export.
+var Headers = map[string][]string{
+	KindSamples:   {"id", "product", "part_number", "location", "owner", "state", "product_issue", "mods", "registered", "last_update"},
+	KindProducts:  {"id", "name", "path", "parent", "part_number"},
+	KindLocations: {"id", "name", "path", "parent", "description"},
+	KindUsers:     {"id", "name", "email"},
+}
+
+// Export writes the table of the given kind to w. Samples are read from the database as
+// they are written; filter selects which, and may be nil.
+func Export(ctx context.Context, kind string, format string, w io.Writer, filter *samplefilter.Matcher) error {
+	header, ok := Headers[kind]
+	if !ok {
+		return ErrUnknownKind
+	}
+	out, err := NewWriter(format, w, kind)
+	if err != nil {
+		return err
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	switch kind {
+	case KindSamples:
+		err = exportSamples(ctx, out, filter)
+	case KindProducts:
+		err = exportProducts(ctx, out)
+	case KindLocations:
+		err = exportLocations(ctx, out)
+	case KindUsers:
+		err = exportUsers(ctx, out)
+	}
+	if err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func exportSamples(ctx context.Context, out Writer, filter *samplefilter.Matcher) error {
+	products, err := loadProducts(ctx)
+	if err != nil {
+		return err
+	}
+	locations, err := loadLocations(ctx)
+	if err != nil {
+		return err
+	}
+	return database.Connection.EachSample(ctx, func(sample database.ListSamplesRow) error {
+		if filter != nil {
+			match, err := filter.Match(ctx, sample)
+			if err != nil || !match {
+				return err
+			}
+		}
+		raw, _ := sample.ID.([]byte)
+		display_id, err := sampleid.FormatSampleID(raw)
+		if err != nil {
+			return err
+		}
+		product := products.nodes[id_helper.BlobToString(sample.ProductID)]
+		return out.Write([]string{
+			display_id,
+			product.name,
+			product.extra,
+			locations.path(id_helper.BlobToString(sample.LocationID)),
+			sample.OwnerName.String,
+			sample.State,
+			sample.ProductIssue.String,
+			samplefilter.ModsSummary(sample),
+			formatTime(sample.TimeRegistered),
+			formatTime(sample.LastUpdate),
+		})
+	})
+}
+
+func exportProducts(ctx context.Context, out Writer) error {
+	products, err := loadProducts(ctx)
+	if err != nil {
+		return err
+	}
+	return products.write(out)
+}
+
+func exportLocations(ctx context.Context, out Writer) error {
+	locations, err := loadLocations(ctx)
+	if err != nil {
+		return err
+	}
+	return locations.write(out)
+}
+
+func exportUsers(ctx context.Context, out Writer) error {
+	users, err := database.Connection.GetUsers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if err := out.Write([]string{id_helper.BlobToString(user.ID), user.Name, user.Email.String}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatTime(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.UTC().Format(TIME_FORMAT)
+}
+
+// tree holds the products or locations to look up names and paths.
+type tree struct {
+	order []string
+	nodes map[string]treeNode
+}
+
+type treeNode struct {
+	name, parent string
+	// extra is the part number of a product or the description of a location
+	extra string
+}
+
+func loadProducts(ctx context.Context) (*tree, error) {
+	products, err := database.Connection.GetProducts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t := &tree{nodes: map[string]treeNode{}}
+	for _, product := range products {
+		t.add(id_helper.BlobToString(product.ID), treeNode{product.Name, id_helper.BlobToString(product.ParentProductID), product.PartNumber.String})
+	}
+	return t, nil
+}
+
+func loadLocations(ctx context.Context) (*tree, error) {
+	locations, err := database.Connection.GetLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t := &tree{nodes: map[string]treeNode{}}
+	for _, location := range locations {
+		t.add(id_helper.BlobToString(location.ID), treeNode{location.Name, id_helper.BlobToString(location.ParentLocationID), location.Description.String})
+	}
+	return t, nil
+}
+
+func (t *tree) add(id string, node treeNode) {
+	t.order = append(t.order, id)
+	t.nodes[id] = node
+}
+
+// path joins the names from the top of the tree down to id.
+func (t *tree) path(id string) string {
+	names := []string{}
+	seen := map[string]bool{}
+	for depth := 0; id != "" && !seen[id] && depth < reports.MAX_TREE_DEPTH; depth++ {
+		seen[id] = true
+		node, ok := t.nodes[id]
+		if !ok {
+			break
+		}
+		names = append([]string{node.name}, names...)
+		id = node.parent
+	}
+	return strings.Join(names, PATH_SEPARATOR)
+}
+
+func (t *tree) write(out Writer) error {
+	for _, id := range t.order {
+		node := t.nodes[id]
+		if err := out.Write([]string{id, node.name, t.path(id), t.path(node.parent), node.extra}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Filename is the download name for an export made at the given time.
+func Filename(kind string, format string, at time.Time) string {
+	return kind + "-" + at.UTC().Format("20060102-150405") + "." + format
+}
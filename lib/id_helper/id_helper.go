@@ -1,6 +1,8 @@
 package id_helper
 
 import (
+	"fmt"
+
 	"github.com/google/uuid"
 )
 
@@ -25,3 +27,21 @@ func MustParseAndMarshalUUID(id string) ([]byte, string, bool) {
 	}
 	return b, "", true
 }
+
+// UUIDFromBlob converts a BLOB(16) column value, as scanned by sqlc into an interface{}, back to a UUID.
+func UUIDFromBlob(value interface{}) (uuid.UUID, error) {
+	b, ok := value.([]byte)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("unexpected ID type %T", value)
+	}
+	return uuid.FromBytes(b)
+}
+
+// BlobToString formats a BLOB(16) column value as a UUID string, or "" if it is NULL or invalid.
+func BlobToString(value interface{}) string {
+	u, err := UUIDFromBlob(value)
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
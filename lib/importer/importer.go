@@ -0,0 +1,561 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	"reesource-tracker/lib/notifications"
+	sampleid "reesource-tracker/lib/sample_id"
+	samplestate "reesource-tracker/lib/sample_state"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of rows that can be imported
+const (
+	KindSamples   = "samples"
+	KindProducts  = "products"
+	KindLocations = "locations"
+	KindUsers     = "users"
+)
+
+// Fields are the fields each kind of row has. The first one is required.
+var Fields = map[string][]string{
+	KindSamples:   {"id", "product", "location", "owner", "state", "product_issue"},
+	KindProducts:  {"name", "parent", "part_number"},
+	KindLocations: {"name", "parent", "description"},
+	KindUsers:     {"name", "email"},
+}
+
+var (
+	ErrUnknownKind = errors.New("unknown import kind")
+	ErrInvalidFile = errors.New("invalid file")
+	ErrInvalidRows = errors.New("some rows are invalid, nothing was imported")
+	// errRollback ends the transaction of a dry run or an import with invalid rows
+	errRollback = errors.New("rollback")
+)
+
+// Options control how a file is imported.
+type Options struct {
+	// Mapping maps fields to column headers. Fields that aren't mapped are read from the
+	// column with the same name, ignoring case and treating spaces as underscores.
+	Mapping map[string]string
+	// CreateMissing creates products and locations that rows refer to but don't exist
+	CreateMissing bool
+	// DryRun checks and applies every row, then rolls everything back
+	DryRun bool
+	// Record, if set, is called through the import's transaction for each change once every
+	// row is valid, to add it to the activity history. An error rolls the import back.
+	Record func(q *database.Queries, change Change) error
+}
+
+// RowError is a problem with one row of the file.
+type RowError struct {
+	// Line is the row's line in the file, where the header is line 1
+	Line  int    `json:"line"`
+	Field string `json:"field,omitempty"`
+	Error string `json:"error"`
+}
+
+// FieldChange is the old and new value of a changed field.
+type FieldChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Change is an entity the import created or updated, including products and locations
+// created for sample rows.
+type Change struct {
+	// Kind is the activity kind, e.g. activity.KindSample
+	Kind string `json:"kind"`
+	// ID is the formatted sample ID for samples and a UUID string otherwise
+	ID string `json:"id"`
+	// Action is activity.ActionCreated or activity.ActionUpdated
+	Action  string                 `json:"action"`
+	Changes map[string]FieldChange `json:"changes,omitempty"`
+	RawID   []byte                 `json:"-"`
+}
+
+// Result describes what an import did, or would do for a dry run.
+type Result struct {
+	Kind   string `json:"kind"`
+	DryRun bool   `json:"dry_run"`
+	Rows   int    `json:"rows"`
+	// Created, Updated and Unchanged count the valid rows
+	Created   int        `json:"created"`
+	Updated   int        `json:"updated"`
+	Unchanged int        `json:"unchanged"`
+	Changes   []Change   `json:"changes"`
+	Errors    []RowError `json:"errors"`
+}
+
+// rowError is a problem with a row. Any other error aborts the import.
+type rowError struct {
+	field   string
+	message string
+}
+
+func (err rowError) Error() string {
+	return err.message
+}
+
+type row struct {
+	line   int
+	values map[string]string
+}
+
+// Import reads CSV rows of the given kind from r and applies them in one transaction. Every
+// row is checked; if any is invalid the transaction is rolled back and ErrInvalidRows is
+// returned along with the result listing the errors. A dry run is always rolled back.
+func Import(ctx context.Context, kind string, r io.Reader, opts Options) (Result, error) {
+	result := Result{Kind: kind, DryRun: opts.DryRun, Changes: []Change{}, Errors: []RowError{}}
+	rows, err := readRows(kind, r, opts.Mapping)
+	if err != nil {
+		return result, err
+	}
+	result.Rows = len(rows)
+
+	err = database.Transaction(ctx, func(q *database.Queries) error {
+		imp, err := newImporter(ctx, q, opts, &result)
+		if err != nil {
+			return err
+		}
+		apply := map[string]func(context.Context, row) error{
+			KindSamples:   imp.sample,
+			KindProducts:  imp.product,
+			KindLocations: imp.location,
+			KindUsers:     imp.user,
+		}[kind]
+		for _, row := range rows {
+			err := apply(ctx, row)
+			var invalid rowError
+			if errors.As(err, &invalid) {
+				result.Errors = append(result.Errors, RowError{Line: row.line, Field: invalid.field, Error: invalid.message})
+			} else if err != nil {
+				return fmt.Errorf("line %d: %w", row.line, err)
+			}
+		}
+		if opts.DryRun || len(result.Errors) > 0 {
+			return errRollback
+		}
+		if opts.Record != nil {
+			for _, change := range result.Changes {
+				if err := opts.Record(q, change); err != nil {
+					return fmt.Errorf("recording activity: %w", err)
+				}
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errRollback) {
+		if !opts.DryRun {
+			return result, ErrInvalidRows
+		}
+		return result, nil
+	}
+	return result, err
+}
+
+// readRows parses the whole file, so a malformed file is rejected before anything is applied.
+func readRows(kind string, r io.Reader, mapping map[string]string) ([]row, error) {
+	fields, ok := Fields[kind]
+	if !ok {
+		return nil, ErrUnknownKind
+	}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+	columns, err := mapColumns(fields, header, mapping)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+	rows := []row{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+		}
+		line, _ := reader.FieldPos(0)
+		values := map[string]string{}
+		empty := true
+		for field, index := range columns {
+			if index < len(record) {
+				values[field] = strings.TrimSpace(record[index])
+				empty = empty && values[field] == ""
+			}
+		}
+		// Spreadsheets often export trailing rows of empty cells
+		if !empty {
+			rows = append(rows, row{line: line, values: values})
+		}
+	}
+}
+
+// mapColumns finds the column index of each field in the header.
+func mapColumns(fields []string, header []string, mapping map[string]string) (map[string]int, error) {
+	if len(header) > 0 {
+		// Excel writes a byte order mark at the start of UTF-8 files
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	for field := range mapping {
+		if !slices.Contains(fields, field) {
+			return nil, fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(fields, ", "))
+		}
+	}
+	normalize := func(name string) string {
+		return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+	}
+	columns := map[string]int{}
+	for _, field := range fields {
+		column, mapped := mapping[field]
+		if !mapped {
+			column = field
+		}
+		index := slices.Index(header, column)
+		if index < 0 {
+			index = slices.IndexFunc(header, func(name string) bool { return normalize(name) == normalize(column) })
+		}
+		if index >= 0 {
+			columns[field] = index
+		} else if mapped {
+			return nil, fmt.Errorf("column %q for %s not found", column, field)
+		}
+	}
+	if _, ok := columns[fields[0]]; !ok {
+		return nil, fmt.Errorf("no %s column", fields[0])
+	}
+	return columns, nil
+}
+
+type importer struct {
+	q         *database.Queries
+	opts      Options
+	result    *Result
+	products  *tree
+	locations *tree
+	users     []database.User
+	// samples maps the sample IDs seen so far to their line
+	samples map[string]int
+	now     time.Time
+}
+
+func newImporter(ctx context.Context, q *database.Queries, opts Options, result *Result) (*importer, error) {
+	imp := &importer{q: q, opts: opts, result: result, samples: map[string]int{}, now: time.Now()}
+	var err error
+	if imp.products, err = loadProducts(ctx, q, imp.changed); err != nil {
+		return nil, err
+	}
+	if imp.locations, err = loadLocations(ctx, q, imp.changed); err != nil {
+		return nil, err
+	}
+	if imp.users, err = q.GetUsers(ctx); err != nil {
+		return nil, err
+	}
+	return imp, nil
+}
+
+// changed records a created or updated entity.
+func (imp *importer) changed(kind string, id string, rawID []byte, action string, changes map[string]FieldChange) {
+	if len(changes) == 0 {
+		changes = nil
+	}
+	imp.result.Changes = append(imp.result.Changes, Change{Kind: kind, ID: id, Action: action, Changes: changes, RawID: rawID})
+}
+
+// counted tallies a row by what happened to the entity it describes.
+func (imp *importer) counted(action string) {
+	switch action {
+	case activity.ActionCreated:
+		imp.result.Created++
+	case activity.ActionUpdated:
+		imp.result.Updated++
+	default:
+		imp.result.Unchanged++
+	}
+}
+
+// compare adds a field to changes if its value differs.
+func compare(changes map[string]FieldChange, field string, from string, to string) {
+	if from != to {
+		changes[field] = FieldChange{From: from, To: to}
+	}
+}
+
+func (imp *importer) sample(ctx context.Context, r row) error {
+	raw, err := sampleid.ParseSampleID(r.values["id"])
+	if err != nil {
+		return rowError{"id", fmt.Sprintf("invalid sample ID %q, expected XX-XX-XX", r.values["id"])}
+	}
+	rawID := raw[:]
+	display_id, _ := sampleid.FormatSampleID(rawID)
+	if line, ok := imp.samples[display_id]; ok {
+		return rowError{"id", fmt.Sprintf("sample %s is also on line %d", display_id, line)}
+	}
+	imp.samples[display_id] = r.line
+
+	previous, err := imp.q.GetSampleById(ctx, rawID)
+	created := errors.Is(err, sql.ErrNoRows)
+	if err != nil && !created {
+		return err
+	}
+	params := database.UpdateOrCreateSampleParams{
+		ID:             rawID,
+		LocationID:     previous.LocationID,
+		ProductID:      previous.ProductID,
+		OwnerID:        previous.OwnerID,
+		ProductIssue:   previous.ProductIssue,
+		State:          previous.State,
+		TimeRegistered: sql.NullTime{Time: imp.now, Valid: true},
+		LastUpdate:     sql.NullTime{Time: imp.now, Valid: true},
+	}
+	if created {
+		params.State = "unassigned"
+	}
+	if value := r.values["state"]; value != "" {
+		state := strings.ReplaceAll(strings.ToLower(value), " ", "_")
+		if !slices.Contains(samplestate.States, state) {
+			return rowError{"state", fmt.Sprintf("unknown state %q, expected one of %s", value, strings.Join(samplestate.States, ", "))}
+		}
+		params.State = state
+	}
+	if value := r.values["owner"]; value != "" {
+		user, err := imp.findUser(value)
+		if err != nil {
+			return rowError{"owner", err.Error()}
+		}
+		params.OwnerID = user.ID
+	}
+	if value := r.values["product_issue"]; value != "" {
+		params.ProductIssue = sql.NullString{String: value, Valid: true}
+	}
+	if value := r.values["product"]; value != "" {
+		id, err := imp.products.resolve(ctx, imp.q, value, imp.opts.CreateMissing)
+		if err != nil {
+			return withField(err, "product")
+		}
+		params.ProductID = id
+	}
+	if value := r.values["location"]; value != "" {
+		id, err := imp.locations.resolve(ctx, imp.q, value, imp.opts.CreateMissing)
+		if err != nil {
+			return withField(err, "location")
+		}
+		params.LocationID = id
+	}
+
+	changes := map[string]FieldChange{}
+	compare(changes, "location_id", id_helper.BlobToString(previous.LocationID), id_helper.BlobToString(params.LocationID))
+	compare(changes, "product_id", id_helper.BlobToString(previous.ProductID), id_helper.BlobToString(params.ProductID))
+	compare(changes, "owner_id", id_helper.BlobToString(previous.OwnerID), id_helper.BlobToString(params.OwnerID))
+	compare(changes, "state", previous.State, params.State)
+	compare(changes, "product_issue", previous.ProductIssue.String, params.ProductIssue.String)
+	action := activity.ActionUpdated
+	if created {
+		action = activity.ActionCreated
+	} else if len(changes) == 0 {
+		imp.counted("")
+		return nil
+	}
+	if _, err := imp.q.UpdateOrCreateSample(ctx, params); err != nil {
+		return err
+	}
+	imp.changed(activity.KindSample, display_id, rawID, action, changes)
+	imp.counted(action)
+	return nil
+}
+
+func (imp *importer) product(ctx context.Context, r row) error {
+	node, action, err := imp.treeRow(ctx, imp.products, r)
+	if err != nil {
+		return err
+	}
+	if value := r.values["part_number"]; value != "" {
+		product, err := imp.q.GetProductByID(ctx, node.id)
+		if err != nil {
+			return err
+		}
+		if product.PartNumber.String != value {
+			err := imp.q.UpsertProduct(ctx, database.UpsertProductParams{
+				ID:              product.ID,
+				Name:            product.Name,
+				ParentProductID: product.ParentProductID,
+				PartNumber:      sql.NullString{String: value, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+			if action == "" {
+				action = activity.ActionUpdated
+				imp.changed(activity.KindProduct, node.key, node.id, action, map[string]FieldChange{
+					"part_number": {From: product.PartNumber.String, To: value},
+				})
+			}
+		}
+	}
+	imp.counted(action)
+	return nil
+}
+
+func (imp *importer) location(ctx context.Context, r row) error {
+	node, action, err := imp.treeRow(ctx, imp.locations, r)
+	if err != nil {
+		return err
+	}
+	if value := r.values["description"]; value != "" {
+		location, err := imp.q.GetLocation(ctx, node.id)
+		if err != nil {
+			return err
+		}
+		if location.Description.String != value {
+			err := imp.q.UpsertLocation(ctx, database.UpsertLocationParams{
+				ID:               location.ID,
+				Name:             location.Name,
+				Description:      sql.NullString{String: value, Valid: true},
+				ParentLocationID: location.ParentLocationID,
+			})
+			if err != nil {
+				return err
+			}
+			if action == "" {
+				action = activity.ActionUpdated
+				imp.changed(activity.KindLocation, node.key, node.id, action, map[string]FieldChange{
+					"description": {From: location.Description.String, To: value},
+				})
+			}
+		}
+	}
+	imp.counted(action)
+	return nil
+}
+
+// treeRow finds the product or location a row describes by its name and parent, creating
+// it if it doesn't exist. action is activity.ActionCreated if it was created and empty
+// otherwise.
+func (imp *importer) treeRow(ctx context.Context, t *tree, r row) (*node, string, error) {
+	name := r.values["name"]
+	if name == "" {
+		return nil, "", rowError{"name", "name is required"}
+	}
+	var parentID []byte
+	if value := r.values["parent"]; value != "" {
+		id, err := t.resolve(ctx, imp.q, value, imp.opts.CreateMissing)
+		if err != nil {
+			return nil, "", withField(err, "parent")
+		}
+		parentID = id
+	}
+	if existing := t.child(parentID, name); existing != nil {
+		return existing, "", nil
+	}
+	created, err := t.add(ctx, imp.q, name, parentID)
+	return created, activity.ActionCreated, err
+}
+
+func (imp *importer) user(ctx context.Context, r row) error {
+	name := r.values["name"]
+	if name == "" {
+		return rowError{"name", "name is required"}
+	}
+	email, err := notifications.ParseEmail(r.values["email"])
+	if err != nil {
+		return rowError{"email", err.Error()}
+	}
+	matches := []database.User{}
+	for _, user := range imp.users {
+		if strings.EqualFold(user.Name, name) {
+			matches = append(matches, user)
+		}
+	}
+	if len(matches) > 1 {
+		return rowError{"name", fmt.Sprintf("%d users are called %q", len(matches), name)}
+	}
+	if len(matches) == 0 {
+		id, err := uuid.New().MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := imp.q.UpsertUser(ctx, database.UpsertUserParams{ID: id, Name: name}); err != nil {
+			return err
+		}
+		if email.Valid {
+			if err := imp.q.UpdateUserEmail(ctx, database.UpdateUserEmailParams{Email: email, ID: id}); err != nil {
+				return err
+			}
+		}
+		imp.users = append(imp.users, database.User{ID: id, Name: name, Email: email})
+		imp.changed(activity.KindUser, id_helper.BlobToString(id), id, activity.ActionCreated, nil)
+		imp.counted(activity.ActionCreated)
+		return nil
+	}
+	user := matches[0]
+	changes := map[string]FieldChange{}
+	if email.Valid {
+		compare(changes, "email", user.Email.String, email.String)
+	}
+	if len(changes) == 0 {
+		imp.counted("")
+		return nil
+	}
+	if err := imp.q.UpdateUserEmail(ctx, database.UpdateUserEmailParams{Email: email, ID: user.ID}); err != nil {
+		return err
+	}
+	for i := range imp.users {
+		if id_helper.BlobToString(imp.users[i].ID) == id_helper.BlobToString(user.ID) {
+			imp.users[i].Email = email
+		}
+	}
+	rawID, _ := user.ID.([]byte)
+	imp.changed(activity.KindUser, id_helper.BlobToString(user.ID), rawID, activity.ActionUpdated, changes)
+	imp.counted(activity.ActionUpdated)
+	return nil
+}
+
+// findUser finds a sample owner by UUID, email address or name.
+func (imp *importer) findUser(value string) (database.User, error) {
+	matches := []database.User{}
+	_, uuidErr := uuid.Parse(value)
+	for _, user := range imp.users {
+		switch {
+		case uuidErr == nil && strings.EqualFold(id_helper.BlobToString(user.ID), value),
+			strings.Contains(value, "@") && strings.EqualFold(user.Email.String, value),
+			strings.EqualFold(user.Name, value):
+			matches = append(matches, user)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return database.User{}, fmt.Errorf("user %q not found", value)
+	case 1:
+		return matches[0], nil
+	default:
+		return database.User{}, fmt.Errorf("%d users match %q, use their email address or ID instead", len(matches), value)
+	}
+}
+
+// withField sets the field of a row error.
+func withField(err error, field string) error {
+	var invalid rowError
+	if errors.As(err, &invalid) {
+		invalid.field = field
+		return invalid
+	}
+	return err
+}
@@ -0,0 +1,150 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// PATH_SEPARATOR separates the names in a product or location path, e.g. "Lab 1/Shelf A".
+const PATH_SEPARATOR = "/"
+
+// tree indexes the products or locations, so rows can refer to them by UUID, by name if
+// it is unique, or by their path from the top of the tree.
+type tree struct {
+	// kind is the activity kind, used in errors and changes
+	kind   string
+	byID   map[string]*node
+	byName map[string][]*node
+	insert func(ctx context.Context, q *database.Queries, id []byte, name string, parentID interface{}) error
+	// changed is told about the nodes that are created
+	changed func(kind string, id string, rawID []byte, action string, changes map[string]FieldChange)
+}
+
+type node struct {
+	id  []byte
+	key string
+	// parent is the parent's key, or "" at the top of the tree
+	parent string
+}
+
+func newTree(kind string, changed func(string, string, []byte, string, map[string]FieldChange)) *tree {
+	return &tree{kind: kind, byID: map[string]*node{}, byName: map[string][]*node{}, changed: changed}
+}
+
+func loadProducts(ctx context.Context, q *database.Queries, changed func(string, string, []byte, string, map[string]FieldChange)) (*tree, error) {
+	t := newTree(activity.KindProduct, changed)
+	t.insert = func(ctx context.Context, q *database.Queries, id []byte, name string, parentID interface{}) error {
+		return q.UpsertProduct(ctx, database.UpsertProductParams{ID: id, Name: name, ParentProductID: parentID})
+	}
+	products, err := q.ListProducts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, product := range products {
+		t.index(product.ID, product.Name, product.ParentProductID)
+	}
+	return t, nil
+}
+
+func loadLocations(ctx context.Context, q *database.Queries, changed func(string, string, []byte, string, map[string]FieldChange)) (*tree, error) {
+	t := newTree(activity.KindLocation, changed)
+	t.insert = func(ctx context.Context, q *database.Queries, id []byte, name string, parentID interface{}) error {
+		return q.UpsertLocation(ctx, database.UpsertLocationParams{ID: id, Name: name, Description: sql.NullString{}, ParentLocationID: parentID})
+	}
+	locations, err := q.GetLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, location := range locations {
+		t.index(location.ID, location.Name, location.ParentLocationID)
+	}
+	return t, nil
+}
+
+func (t *tree) index(id interface{}, name string, parentID interface{}) *node {
+	raw, _ := id.([]byte)
+	n := &node{id: raw, key: id_helper.BlobToString(id), parent: id_helper.BlobToString(parentID)}
+	t.byID[n.key] = n
+	lower := strings.ToLower(name)
+	t.byName[lower] = append(t.byName[lower], n)
+	return n
+}
+
+// child finds the node called name directly below parentID, or at the top if it is nil.
+func (t *tree) child(parentID []byte, name string) *node {
+	parent := id_helper.BlobToString(parentID)
+	for _, n := range t.byName[strings.ToLower(name)] {
+		if n.parent == parent {
+			return n
+		}
+	}
+	return nil
+}
+
+// add creates a node called name below parentID.
+func (t *tree) add(ctx context.Context, q *database.Queries, name string, parentID []byte) (*node, error) {
+	id, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	var parent interface{}
+	if parentID != nil {
+		parent = parentID
+	}
+	if err := t.insert(ctx, q, id, name, parent); err != nil {
+		return nil, err
+	}
+	n := t.index(id, name, parent)
+	t.changed(t.kind, n.key, n.id, activity.ActionCreated, nil)
+	return n, nil
+}
+
+// resolve finds the node a cell refers to. If create is set, the missing parts of a path,
+// or a missing name, are created.
+func (t *tree) resolve(ctx context.Context, q *database.Queries, value string, create bool) ([]byte, error) {
+	if id, err := uuid.Parse(value); err == nil {
+		if n, ok := t.byID[id.String()]; ok {
+			return n.id, nil
+		}
+		return nil, rowError{message: fmt.Sprintf("%s %s not found", t.kind, value)}
+	}
+	// A name on its own can be anywhere in the tree, as long as it is unique. Names that
+	// contain the separator are matched whole first.
+	matches := t.byName[strings.ToLower(value)]
+	if len(matches) == 1 {
+		return matches[0].id, nil
+	}
+	if len(matches) > 1 {
+		return nil, rowError{message: fmt.Sprintf("%d %ss are called %q, use the path or ID instead", len(matches), t.kind, value)}
+	}
+
+	var parentID []byte
+	for _, name := range strings.Split(value, PATH_SEPARATOR) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		n := t.child(parentID, name)
+		if n == nil {
+			if !create {
+				return nil, rowError{message: fmt.Sprintf("%s %q not found", t.kind, value)}
+			}
+			var err error
+			if n, err = t.add(ctx, q, name, parentID); err != nil {
+				return nil, err
+			}
+		}
+		parentID = n.id
+	}
+	if parentID == nil {
+		return nil, rowError{message: fmt.Sprintf("%s %q not found", t.kind, value)}
+	}
+	return parentID, nil
+}
@@ -0,0 +1,158 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reesource-tracker/lib/backup"
+	"reesource-tracker/lib/config"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	"reesource-tracker/lib/notifications"
+	sampleid "reesource-tracker/lib/sample_id"
+	"reesource-tracker/lib/scheduler"
+	"strings"
+	"time"
+)
+
+const (
+	// Borrowers are reminded about an overdue loan at most once a day
+	REMINDER_INTERVAL = 23 * time.Hour
+)
+
+// Register adds the built-in jobs to the scheduler. Their settings, such as how long a
+// sample can go without an update before it is stale, are read from config.Current.
+func Register() {
+	scheduler.Register(scheduler.Job{
+		Name:        "stale_samples",
+		Description: "Reminds owners about samples that haven't been updated recently",
+		Schedule:    "0 7 * * 1",
+		Run:         staleSamples,
+	})
+	scheduler.Register(scheduler.Job{
+		Name:        "overdue_loans",
+		Description: "Reminds borrowers about samples that are overdue for return",
+		Schedule:    "0 8 * * *",
+		Run:         overdueLoans,
+	})
+	if config.Current.DBDriver == config.DRIVER_SQLITE {
+		scheduler.Register(scheduler.Job{
+			Name:        "database_backup",
+			Description: "Backs up the database and removes old backups",
+			Schedule:    "0 2 * * *",
+			Run:         databaseBackup,
+		})
+	}
+	scheduler.Register(scheduler.Job{
+		Name:        "orphan_cleanup",
+		Description: "Removes orphaned rows, expired sessions and old delivery history",
+		Schedule:    "30 3 * * *",
+		Run:         orphanCleanup,
+	})
+}
+
+func staleSamples(ctx context.Context) (string, error) {
+	days := config.Current.StaleSampleDays
+	cutoff := time.Now().UTC().AddDate(0, 0, -days)
+	samples, err := database.Connection.ListStaleSamples(ctx, sql.NullTime{Time: cutoff, Valid: true})
+	if err != nil {
+		return "", err
+	}
+	notified := 0
+	for _, sample := range samples {
+		if id_helper.BlobToString(sample.OwnerID) == "" {
+			continue
+		}
+		raw, _ := sample.ID.([]byte)
+		display_id, err := sampleid.FormatSampleID(raw)
+		if err != nil {
+			continue
+		}
+		notifications.Queue(ctx, sample.OwnerID, notifications.KindSampleStale, notifications.Message{
+			SampleID: display_id,
+			Days:     int(time.Since(sample.LastUpdate.Time).Hours() / 24),
+		})
+		notified++
+	}
+	return fmt.Sprintf("%d samples not updated in %d days, %d owners reminded", len(samples), days, notified), nil
+}
+
+func overdueLoans(ctx context.Context) (string, error) {
+	now := time.Now().UTC()
+	loans, err := database.Connection.ListOverdueLoans(ctx, database.ListOverdueLoansParams{
+		DueAt:      now,
+		RemindedAt: sql.NullTime{Time: now.Add(-REMINDER_INTERVAL), Valid: true},
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, loan := range loans {
+		raw, _ := loan.SampleID.([]byte)
+		display_id, err := sampleid.FormatSampleID(raw)
+		if err != nil {
+			continue
+		}
+		notifications.Queue(ctx, loan.UserID, notifications.KindLoanOverdue, notifications.Message{
+			SampleID: display_id,
+			DueDate:  loan.DueAt.Local().Format("2 January 2006"),
+			Days:     int(now.Sub(loan.DueAt).Hours()/24) + 1,
+		})
+		err = database.Connection.MarkLoanReminded(ctx, database.MarkLoanRemindedParams{
+			RemindedAt: sql.NullTime{Time: now, Valid: true},
+			ID:         loan.ID,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%d overdue loans reminded", len(loans)), nil
+}
+
+func databaseBackup(ctx context.Context) (string, error) {
+	path, err := backup.Create(ctx)
+	if err != nil {
+		return "", err
+	}
+	removed, err := backup.Prune(config.Current.BackupKeep)
+	output := fmt.Sprintf("backed up to %s, removed %d old backups", path, len(removed))
+	return output, err
+}
+
+func orphanCleanup(ctx context.Context) (string, error) {
+	now := time.Now().UTC()
+	before := now.AddDate(0, 0, -config.Current.CleanupRetentionDays)
+	steps := []struct {
+		name string
+		run  func() (int64, error)
+	}{
+		{"sample mods", func() (int64, error) { return database.Connection.DeleteOrphanSampleMods(ctx) }},
+		{"sample notes", func() (int64, error) { return database.Connection.DeleteOrphanSampleNotes(ctx) }},
+		{"sample comments", func() (int64, error) { return database.Connection.DeleteOrphanSampleComments(ctx) }},
+		{"sample loans", func() (int64, error) { return database.Connection.DeleteOrphanSampleLoans(ctx) }},
+		{"user roles", func() (int64, error) { return database.Connection.DeleteOrphanUserRoles(ctx) }},
+		{"API tokens", func() (int64, error) { return database.Connection.DeleteOrphanAPITokens(ctx) }},
+		{"sessions", func() (int64, error) { return database.Connection.DeleteExpiredSessions(ctx, now) }},
+		{"notifications", func() (int64, error) {
+			return database.Connection.DeleteOldNotifications(ctx, sql.NullTime{Time: before, Valid: true})
+		}},
+		{"webhook deliveries", func() (int64, error) { return database.Connection.DeleteOldWebhookDeliveries(ctx, before) }},
+		{"webhook attempts", func() (int64, error) { return database.Connection.DeleteOrphanWebhookAttempts(ctx) }},
+		{"job runs", func() (int64, error) {
+			return database.Connection.DeleteOldJobRuns(ctx, sql.NullTime{Time: before, Valid: true})
+		}},
+	}
+	removed := []string{}
+	for _, step := range steps {
+		count, err := step.run()
+		if err != nil {
+			return strings.Join(removed, ", "), fmt.Errorf("%s: %w", step.name, err)
+		}
+		if count > 0 {
+			removed = append(removed, fmt.Sprintf("%d %s", count, step.name))
+		}
+	}
+	if len(removed) == 0 {
+		return "nothing to remove", nil
+	}
+	return "removed " + strings.Join(removed, ", "), nil
+}
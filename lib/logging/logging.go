@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reesource-tracker/lib/config"
+	"strings"
+)
+
+// REQUEST_ID_KEY is the gin context key holding the request ID.
+const REQUEST_ID_KEY = "request_id"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying a request ID, for code that has a plain
+// context.Context rather than the gin context.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, which may be a gin context or a request
+// context, or "" outside a request.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	// gin.Context looks string keys up in its own keys
+	if id, ok := ctx.Value(REQUEST_ID_KEY).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// contextHandler adds the request ID from the context to every record, so logs written
+// with slog.InfoContext(c, ...) in handlers and database code can be tied to the request.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String(REQUEST_ID_KEY, id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// ParseLevel reads a level name: debug, info, warn or error.
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if value == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q, use debug, info, warn or error", value)
+	}
+	return level, nil
+}
+
+// Setup makes slog the default logger, writing to stderr with the configured level and
+// format. The debug level includes every database query.
+func Setup() error {
+	return Configure(os.Stderr, config.Current.LogLevel, config.Current.LogFormat)
+}
+
+// Configure sets the default logger to write to w with the given level and format.
+func Configure(w io.Writer, levelName string, format string) error {
+	level, err := ParseLevel(levelName)
+	if err != nil {
+		return err
+	}
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", "json":
+		handler = slog.NewJSONHandler(w, options)
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return fmt.Errorf("invalid log format %q, use json or text", format)
+	}
+	slog.SetDefault(slog.New(contextHandler{handler}))
+	return nil
+}
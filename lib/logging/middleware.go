@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const REQUEST_ID_HEADER = "X-Request-ID"
+
+// Request IDs from clients or proxies are kept if they look safe to log.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// Middleware gives each request an ID, taken from the X-Request-ID header or generated,
+// echoes it in the response and makes it available to logs through the context. Once the
+// request is handled it writes an access log line; user describes who made the request.
+func Middleware(user func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(REQUEST_ID_HEADER)
+		if !validRequestID.MatchString(id) {
+			id = uuid.New().String()
+		}
+		c.Set(REQUEST_ID_KEY, id)
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+		c.Header(REQUEST_ID_HEADER, id)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user", user(c)),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+		slog.LogAttrs(c, level, "request", attrs...)
+	}
+}
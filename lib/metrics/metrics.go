@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"log/slog"
+	"net/http"
+	"reesource-tracker/lib/config"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const NAMESPACE = "reesource"
+
+// Registry holds every metric exposed on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"method", "route", "status"})
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route. Sync streams are observed when they close.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query duration by sqlc query name.",
+		Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, 1},
+	}, []string{"query"})
+	SyncClients = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: NAMESPACE,
+		Name:      "sync_clients",
+		Help:      "Connected sync clients by transport.",
+	}, []string{"transport"})
+	SyncEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "sync_events_total",
+		Help:      "Sync events published by type.",
+	}, []string{"type"})
+	SyncDroppedEvents = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "sync_dropped_events_total",
+		Help:      "Events missed by sync clients that fell behind the replay buffer.",
+	})
+	SyncResyncs = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "sync_resyncs_total",
+		Help:      "resync_required events sent to sync clients.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		DBQueryDuration,
+		SyncClients,
+		SyncEvents,
+		SyncDroppedEvents,
+		SyncResyncs,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Middleware records the count and latency of requests, labelled with the route pattern
+// rather than the path so sample IDs don't create a series each.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		HTTPRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		HTTPDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Listen serves /metrics on a separate address when one is configured (e.g. ":9090"),
+// so it can be kept off the public port. It returns false if metrics should be served on
+// the main router instead.
+func Listen() bool {
+	addr := config.Current.MetricsListen
+	if addr == "" {
+		return false
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	go func() {
+		slog.Info("Serving metrics", "addr", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			slog.Error("Metrics listener failed", "error", err)
+		}
+	}()
+	return true
+}
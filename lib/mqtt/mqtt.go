@@ -0,0 +1,111 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reesource-tracker/lib/config"
+	"strings"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	// PUBLISH_TIMEOUT is how long a publish may wait for the broker before it is logged as failed.
+	PUBLISH_TIMEOUT = 10 * time.Second
+	// DISCONNECT_WAIT is how long Close waits for queued messages to be sent.
+	DISCONNECT_WAIT = time.Second
+)
+
+var (
+	client paho.Client
+	prefix string
+	qos    byte
+)
+
+// Enabled reports whether an MQTT broker is configured.
+func Enabled() bool {
+	return client != nil
+}
+
+// Setup connects to the configured broker, if any. The connection is retried in the
+// background, and messages published while disconnected are sent once it is back.
+// onConnect is called after every (re)connect, so retained messages can be republished in
+// case the broker lost them.
+func Setup(onConnect func()) error {
+	cfg := config.Current
+	broker := cfg.MQTTBroker
+	if broker == "" {
+		return nil
+	}
+	clientID := cfg.MQTTClientID
+	if clientID == "" {
+		clientID = config.Defaults().MQTTClientID
+	}
+	prefix = cfg.MQTTTopicPrefix
+	qos = byte(cfg.MQTTQoS)
+
+	opts := paho.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(clientID).
+		SetUsername(cfg.MQTTUsername).
+		SetPassword(cfg.MQTTPassword).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetMaxReconnectInterval(time.Minute).
+		SetOnConnectHandler(func(paho.Client) {
+			slog.Info("Connected to MQTT broker", "broker", broker)
+			if onConnect != nil {
+				go onConnect()
+			}
+		}).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			slog.Warn("Lost connection to MQTT broker", "error", err)
+		})
+	client = paho.NewClient(opts)
+	// Connection errors are retried in the background, so there is nothing to wait for
+	client.Connect()
+	return nil
+}
+
+// Close disconnects from the broker, waiting up to DISCONNECT_WAIT for messages still
+// being sent.
+func Close() {
+	if client != nil {
+		client.Disconnect(uint(DISCONNECT_WAIT.Milliseconds()))
+	}
+}
+
+// Topic joins topic levels under the configured prefix, e.g. Topic("samples", id).
+func Topic(levels ...string) string {
+	if prefix != "" {
+		levels = append([]string{prefix}, levels...)
+	}
+	return strings.Join(levels, "/")
+}
+
+// Publish sends payload as JSON without waiting for the broker. A nil payload sends an empty
+// message, which clears a retained message.
+func Publish(topic string, payload interface{}, retained bool) {
+	if client == nil {
+		return
+	}
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			slog.Error("Failed to encode MQTT message", "error", err)
+			return
+		}
+	}
+	token := client.Publish(topic, qos, retained, body)
+	go func() {
+		if !token.WaitTimeout(PUBLISH_TIMEOUT) {
+			return
+		}
+		if err := token.Error(); err != nil {
+			slog.Error("Failed to publish MQTT message", "topic", topic, "error", err)
+		}
+	}()
+}
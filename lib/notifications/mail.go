@@ -0,0 +1,138 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	"strings"
+	"time"
+)
+
+// CHECK_INTERVAL is how often the queue is checked for digests that are due.
+const CHECK_INTERVAL = 30 * time.Second
+
+// emailData is passed to the "email" template in layout.tmpl.
+type emailData struct {
+	Recipient string
+	Items     []database.ListPendingNotificationsRow
+	Digest    bool
+	Link      string
+}
+
+// Run sends queued notifications until ctx is cancelled. A user's notifications are collected
+// until the oldest has waited for their digest delay, then sent together as one email.
+func Run(ctx context.Context) {
+	if !Enabled() {
+		return
+	}
+	ticker := time.NewTicker(CHECK_INTERVAL)
+	defer ticker.Stop()
+	for {
+		sendDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func sendDue(ctx context.Context) {
+	pending, err := database.Connection.ListPendingNotifications(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load notifications", "error", err)
+		return
+	}
+	// Rows are ordered by user, so each user's notifications are next to each other
+	for start := 0; start < len(pending); {
+		end := start + 1
+		userID := id_helper.BlobToString(pending[start].UserID)
+		for end < len(pending) && id_helper.BlobToString(pending[end].UserID) == userID {
+			end++
+		}
+		batch := pending[start:end]
+		start = end
+
+		digest := batch[0].Digest.String
+		if _, ok := DigestDelays[digest]; !ok {
+			digest = DigestImmediate
+		}
+		if time.Since(batch[0].CreatedAt) < DigestDelays[digest] {
+			continue
+		}
+		if batch[0].Email.Valid && batch[0].Email.String != "" {
+			if err := sendBatch(batch); err != nil {
+				slog.ErrorContext(ctx, "Failed to send notification email", "error", err)
+				continue
+			}
+		}
+		// Notifications for users who have since removed their address are dropped
+		err := database.Connection.MarkNotificationsSent(ctx, database.MarkNotificationsSentParams{
+			SentAt:    sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			UserID:    batch[0].UserID,
+			CreatedAt: batch[len(batch)-1].CreatedAt,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to mark notifications sent", "error", err)
+		}
+	}
+}
+
+// sendBatch sends one user's notifications, as a digest if there is more than one.
+func sendBatch(batch []database.ListPendingNotificationsRow) error {
+	data := emailData{
+		Recipient: batch[0].Name,
+		Items:     batch,
+		Digest:    len(batch) > 1,
+	}
+	if config.BaseURL != "" {
+		data.Link = config.BaseURL + "/app"
+	}
+	subject := batch[0].Subject
+	layout := templates["layout"]
+	if data.Digest {
+		var buf bytes.Buffer
+		if err := layout.ExecuteTemplate(&buf, "digest_subject", data); err != nil {
+			return err
+		}
+		subject = buf.String()
+	}
+	var body bytes.Buffer
+	if err := layout.ExecuteTemplate(&body, "email", data); err != nil {
+		return err
+	}
+	to := mail.Address{Name: batch[0].Name, Address: batch[0].Email.String}
+	return Send(to, subject, body.String())
+}
+
+// Send sends a plain text email through the configured SMTP server.
+func Send(to mail.Address, subject string, body string) error {
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP_FROM: %w", err)
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", to.String())
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+	addr := net.JoinHostPort(config.Host, config.Port)
+	return smtp.SendMail(addr, auth, from.Address, []string{to.Address}, msg.Bytes())
+}
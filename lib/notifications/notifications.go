@@ -0,0 +1,291 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"reesource-tracker/lib/activity"
+	"reesource-tracker/lib/auth"
+	app_config "reesource-tracker/lib/config"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Notification kinds. Each has a template in templates/<kind>.tmpl defining "subject" and "body".
+const (
+	KindOwnerChanged   = "owner_changed"
+	KindStateBroken    = "state_broken"
+	KindCommentMention = "comment_mention"
+	KindSampleStale    = "sample_stale"
+	KindLoanOverdue    = "loan_overdue"
+)
+
+var Kinds = []string{KindOwnerChanged, KindStateBroken, KindCommentMention, KindSampleStale, KindLoanOverdue}
+
+// Digest settings: how long notifications are collected before they are sent as one email.
+// Even "immediate" waits a minute, so a burst of changes becomes a single email.
+const (
+	DigestImmediate = "immediate"
+	DigestHourly    = "hourly"
+	DigestDaily     = "daily"
+)
+
+var DigestDelays = map[string]time.Duration{
+	DigestImmediate: time.Minute,
+	DigestHourly:    time.Hour,
+	DigestDaily:     24 * time.Hour,
+}
+
+// Message holds the values the templates can use. Recipient and Actor are filled in by Notify.
+type Message struct {
+	Recipient string
+	Actor     string
+	SampleID  string
+	// Assigned is set for the new owner in owner_changed, OtherOwner is the other owner's name
+	Assigned   bool
+	OtherOwner string
+	// PreviousState is the state before a state change
+	PreviousState string
+	Comment       string
+	// Days is how long a stale sample has gone without an update, or how overdue a loan is
+	Days    int
+	DueDate string
+}
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+var templates = map[string]*template.Template{}
+
+func init() {
+	for _, kind := range append(slices.Clone(Kinds), "layout") {
+		templates[kind] = template.Must(template.ParseFS(templateFiles, "templates/"+kind+".tmpl"))
+	}
+}
+
+// Config holds the SMTP settings, copied from the app configuration by Setup.
+type Config struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	// BaseURL is where the app is served, used for links in emails
+	BaseURL string
+}
+
+var config *Config
+
+// Enabled reports whether email notifications are configured.
+func Enabled() bool {
+	return config != nil
+}
+
+// Setup reads the SMTP settings. Notifications are disabled when no SMTP host is set. The
+// username and password are optional, for PLAIN authentication, and STARTTLS is used when
+// the server offers it. Links in emails use the configured base URL.
+func Setup() error {
+	app := app_config.Current
+	if app.SMTPHost == "" {
+		return nil
+	}
+	if app.SMTPFrom == "" {
+		return errors.New("SMTP_FROM is required when SMTP_HOST is set")
+	}
+	config = &Config{
+		Host:     app.SMTPHost,
+		Port:     strconv.Itoa(app.SMTPPort),
+		Username: app.SMTPUsername,
+		Password: app.SMTPPassword,
+		From:     app.SMTPFrom,
+		BaseURL:  app.BaseURL,
+	}
+	return nil
+}
+
+func render(kind string, msg Message) (subject string, body string, err error) {
+	tmpl, ok := templates[kind]
+	if !ok {
+		return "", "", fmt.Errorf("unknown notification kind %q", kind)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "subject", msg); err != nil {
+		return "", "", err
+	}
+	subject = strings.TrimSpace(buf.String())
+	buf.Reset()
+	if err := tmpl.ExecuteTemplate(&buf, "body", msg); err != nil {
+		return "", "", err
+	}
+	return subject, strings.TrimSpace(buf.String()), nil
+}
+
+// Notify queues a notification to a user about a change made by the current request.
+// Users aren't notified about their own changes.
+func Notify(c *gin.Context, userID interface{}, kind string, msg Message) {
+	if !Enabled() || id_helper.BlobToString(userID) == "" {
+		return
+	}
+	if id_helper.BlobToString(userID) == id_helper.BlobToString(activity.ActorUserID(c)) {
+		return
+	}
+	msg.Actor = actorName(c)
+	Queue(c, userID, kind, msg)
+}
+
+// Queue stores a notification for the user, unless they have no email address or have turned
+// that kind of notification off. It is sent by Run once the user's digest delay has passed.
+func Queue(ctx context.Context, userID interface{}, kind string, msg Message) {
+	if !Enabled() {
+		return
+	}
+	user, err := database.Connection.GetUserByID(ctx, userID)
+	if err != nil || !user.Email.Valid || user.Email.String == "" {
+		return
+	}
+	prefs, err := LoadPreferences(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load notification preferences", "error", err)
+		return
+	}
+	if !prefs.Kinds[kind] {
+		return
+	}
+	msg.Recipient = user.Name
+	subject, body, err := render(kind, msg)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to render notification", "error", err)
+		return
+	}
+	new_uid, _ := uuid.New().MarshalBinary()
+	err = database.Connection.QueueNotification(ctx, database.QueueNotificationParams{
+		ID:        new_uid,
+		UserID:    userID,
+		Kind:      kind,
+		Subject:   subject,
+		Body:      body,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to queue notification", "error", err)
+	}
+}
+
+// UserName returns a user's name, or "" if userID is NULL or unknown.
+func UserName(ctx context.Context, userID interface{}) string {
+	if id_helper.BlobToString(userID) == "" {
+		return ""
+	}
+	user, err := database.Connection.GetUserByID(ctx, userID)
+	if err != nil {
+		return ""
+	}
+	return user.Name
+}
+
+func actorName(c *gin.Context) string {
+	if name := UserName(c, activity.ActorUserID(c)); name != "" {
+		return name
+	}
+	if p, ok := auth.CurrentPrincipal(c); ok && p.TokenID != nil {
+		return "An API client"
+	}
+	return "Someone"
+}
+
+// Preferences are a user's notification settings.
+type Preferences struct {
+	Email  string          `json:"email"`
+	Digest string          `json:"digest"`
+	Kinds  map[string]bool `json:"kinds"`
+}
+
+// LoadPreferences returns a user's settings. Users who haven't changed them get an
+// immediate digest and every kind of notification.
+func LoadPreferences(ctx context.Context, userID interface{}) (Preferences, error) {
+	prefs := Preferences{Digest: DigestImmediate, Kinds: map[string]bool{}}
+	user, err := database.Connection.GetUserByID(ctx, userID)
+	if err != nil {
+		return prefs, err
+	}
+	prefs.Email = user.Email.String
+	row, err := database.Connection.GetNotificationPreferences(ctx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return prefs, err
+	}
+	if err == nil {
+		prefs.Digest = row.Digest
+	}
+	disabled := strings.Split(row.DisabledKinds, ",")
+	for _, kind := range Kinds {
+		prefs.Kinds[kind] = !slices.Contains(disabled, kind)
+	}
+	return prefs, nil
+}
+
+// Validate checks the digest and kinds.
+func (prefs Preferences) Validate() error {
+	if _, ok := DigestDelays[prefs.Digest]; !ok {
+		return fmt.Errorf("digest must be one of %s, %s or %s", DigestImmediate, DigestHourly, DigestDaily)
+	}
+	for kind := range prefs.Kinds {
+		if !slices.Contains(Kinds, kind) {
+			return fmt.Errorf("unknown notification kind %q", kind)
+		}
+	}
+	return nil
+}
+
+// SavePreferences stores the digest and kinds from prefs; the email address is stored on the
+// user with SetEmail.
+func SavePreferences(ctx context.Context, userID interface{}, prefs Preferences) error {
+	if err := prefs.Validate(); err != nil {
+		return err
+	}
+	disabled := []string{}
+	for kind, enabled := range prefs.Kinds {
+		if !enabled {
+			disabled = append(disabled, kind)
+		}
+	}
+	slices.Sort(disabled)
+	return database.Connection.UpsertNotificationPreferences(ctx, database.UpsertNotificationPreferencesParams{
+		UserID:        userID,
+		Digest:        prefs.Digest,
+		DisabledKinds: strings.Join(disabled, ","),
+	})
+}
+
+// ParseEmail validates an email address for storage on a user. An empty address is NULL.
+func ParseEmail(email string) (sql.NullString, error) {
+	if email == "" {
+		return sql.NullString{}, nil
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Name != "" {
+		return sql.NullString{}, errors.New("Invalid email address")
+	}
+	return sql.NullString{String: address.Address, Valid: true}, nil
+}
+
+// SetEmail validates and stores a user's email address. An empty address removes it.
+func SetEmail(ctx context.Context, userID interface{}, email string) error {
+	value, err := ParseEmail(email)
+	if err != nil {
+		return err
+	}
+	return database.Connection.UpdateUserEmail(ctx, database.UpdateUserEmailParams{Email: value, ID: userID})
+}
@@ -0,0 +1,195 @@
+package notifications
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	app_config "reesource-tracker/lib/config"
+	"reesource-tracker/lib/database"
+	"reesource-tracker/lib/testenv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type email struct {
+	to      []string
+	subject string
+	body    string
+}
+
+// sink is an SMTP server that keeps the emails it is sent.
+type sink struct {
+	mu     sync.Mutex
+	emails []email
+}
+
+func newSink(t *testing.T) (*sink, int) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &sink{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s, ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *sink) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 sink ready")
+	var to []string
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO", "MAIL", "RSET", "NOOP":
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			// Go's client sends "RCPT TO:<address>"
+			to = append(to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+			if err != nil {
+				tp.PrintfLine("554 %s", err)
+				continue
+			}
+			subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			body, _ := io.ReadAll(msg.Body)
+			s.mu.Lock()
+			s.emails = append(s.emails, email{to: to, subject: subject, body: string(body)})
+			s.mu.Unlock()
+			to = nil
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// take returns the emails received so far and forgets them.
+func (s *sink) take() []email {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	emails := s.emails
+	s.emails = nil
+	return emails
+}
+
+func newUser(t *testing.T, name string, address string, digest string, disabled ...string) []byte {
+	t.Helper()
+	ctx := context.Background()
+	id, _ := uuid.New().MarshalBinary()
+	if err := database.Connection.UpsertUser(ctx, database.UpsertUserParams{ID: id, Name: name}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetEmail(ctx, id, address); err != nil {
+		t.Fatal(err)
+	}
+	prefs := Preferences{Digest: digest, Kinds: map[string]bool{}}
+	for _, kind := range disabled {
+		prefs.Kinds[kind] = false
+	}
+	if err := SavePreferences(ctx, id, prefs); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// age moves every queued notification back in time, in place of waiting for digest delays.
+func age(t *testing.T, by time.Duration) {
+	t.Helper()
+	if _, err := database.DB.Exec("UPDATE notifications SET created_at = ?", time.Now().UTC().Add(-by)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDigests(t *testing.T) {
+	testenv.Open(t, app_config.DRIVER_SQLITE)
+	s, port := newSink(t)
+	cfg := app_config.Current
+	cfg.SMTPHost = "127.0.0.1"
+	cfg.SMTPPort = port
+	cfg.SMTPFrom = "Reesource Tracker <tracker@example.com>"
+	cfg.BaseURL = "http://tracker.test"
+	if err := Setup(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config = nil })
+	ctx := context.Background()
+
+	ada := newUser(t, "Ada", "ada@example.com", DigestImmediate)
+	bob := newUser(t, "Bob", "bob@example.com", DigestImmediate, KindStateBroken)
+	cy := newUser(t, "Cy", "cy@example.com", DigestHourly)
+	Queue(ctx, ada, KindOwnerChanged, Message{SampleID: "0A-1B-2C", Actor: "Cy", Assigned: true})
+	Queue(ctx, ada, KindStateBroken, Message{SampleID: "0A-1B-2C", Actor: "Cy"})
+	Queue(ctx, bob, KindStateBroken, Message{SampleID: "0A-1B-2C", Actor: "Cy"})
+	Queue(ctx, bob, KindOwnerChanged, Message{SampleID: "0A-1B-2C", Actor: "Cy", OtherOwner: "Ada"})
+	Queue(ctx, cy, KindStateBroken, Message{SampleID: "3D-4E-5F", Actor: "Ada"})
+
+	sendDue(ctx)
+	if emails := s.take(); len(emails) != 0 {
+		t.Fatalf("sent %d emails before the digest delay", len(emails))
+	}
+
+	age(t, 2*time.Minute)
+	sendDue(ctx)
+	emails := map[string]email{}
+	for _, e := range s.take() {
+		emails[strings.Join(e.to, ",")] = e
+	}
+	if len(emails) != 2 {
+		t.Fatalf("got emails to %v, want Ada and Bob", emails)
+	}
+	digest := emails["ada@example.com"]
+	if digest.subject != "2 updates from Reesource Tracker" ||
+		!strings.Contains(digest.body, "== You now own sample 0A-1B-2C ==") ||
+		!strings.Contains(digest.body, "== Sample 0A-1B-2C was marked broken ==") ||
+		!strings.Contains(digest.body, "http://tracker.test/app") {
+		t.Errorf("Ada's digest: got %q\n%s", digest.subject, digest.body)
+	}
+	// Bob turned off broken sample notifications
+	single := emails["bob@example.com"]
+	if single.subject != "Sample 0A-1B-2C has a new owner" || strings.Contains(single.body, "broken") {
+		t.Errorf("Bob's email: got %q\n%s", single.subject, single.body)
+	}
+
+	// Cy's hourly digest is sent once the oldest notification is an hour old
+	sendDue(ctx)
+	if emails := s.take(); len(emails) != 0 {
+		t.Fatalf("resent or sent the hourly digest early: %v", emails)
+	}
+	age(t, time.Hour+time.Minute)
+	sendDue(ctx)
+	if emails := s.take(); len(emails) != 1 || emails[0].to[0] != "cy@example.com" || emails[0].subject != "Sample 3D-4E-5F was marked broken" {
+		t.Errorf("hourly digest: got %+v", emails)
+	}
+}
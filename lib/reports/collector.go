@@ -0,0 +1,65 @@
+package reports
+
+import (
+	"context"
+	"reesource-tracker/lib/database"
+	"reesource-tracker/lib/metrics"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// COLLECT_TIMEOUT bounds the queries run on each scrape.
+const COLLECT_TIMEOUT = 5 * time.Second
+
+var (
+	samplesDesc   = inventoryDesc("samples", "Samples by state.", "state")
+	productsDesc  = inventoryDesc("products", "Products.")
+	locationsDesc = inventoryDesc("locations", "Locations.")
+	usersDesc     = inventoryDesc("users", "Users.")
+	loansDesc     = inventoryDesc("loans", "Samples currently on loan, by whether they are overdue.", "overdue")
+)
+
+func inventoryDesc(name string, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(metrics.NAMESPACE, "inventory", name), help, labels, nil)
+}
+
+// Collector exposes inventory gauges, read from the database when metrics are scraped.
+type Collector struct{}
+
+func (Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- samplesDesc
+	ch <- productsDesc
+	ch <- locationsDesc
+	ch <- usersDesc
+	ch <- loansDesc
+}
+
+func (Collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), COLLECT_TIMEOUT)
+	defer cancel()
+	if states, err := ByState(ctx); err == nil {
+		for _, row := range states {
+			ch <- prometheus.MustNewConstMetric(samplesDesc, prometheus.GaugeValue, float64(row.Count), row.State)
+		}
+	}
+	if products, err := database.Connection.GetProducts(ctx); err == nil {
+		ch <- prometheus.MustNewConstMetric(productsDesc, prometheus.GaugeValue, float64(len(products)))
+	}
+	if locations, err := database.Connection.GetLocations(ctx); err == nil {
+		ch <- prometheus.MustNewConstMetric(locationsDesc, prometheus.GaugeValue, float64(len(locations)))
+	}
+	if users, err := database.Connection.GetUsers(ctx); err == nil {
+		ch <- prometheus.MustNewConstMetric(usersDesc, prometheus.GaugeValue, float64(len(users)))
+	}
+	if loans, err := database.Connection.ListActiveLoans(ctx); err == nil {
+		overdue := 0
+		for _, loan := range loans {
+			if loan.DueAt.Before(time.Now()) {
+				overdue++
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(loansDesc, prometheus.GaugeValue, float64(len(loans)-overdue), "false")
+		ch <- prometheus.MustNewConstMetric(loansDesc, prometheus.GaugeValue, float64(overdue), "true")
+	}
+}
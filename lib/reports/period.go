@@ -0,0 +1,115 @@
+package reports
+
+import (
+	"fmt"
+	"time"
+)
+
+// Bucket sizes for reports over time
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// MAX_BUCKETS keeps a mistyped date range from producing an enormous report.
+const MAX_BUCKETS = 1000
+
+// Period is a date range split into day, week (starting Monday) or month buckets, in the
+// server's time zone. From is the start of the first bucket, To the end of the last.
+type Period struct {
+	Bucket string
+	From   time.Time
+	To     time.Time
+}
+
+// ParsePeriod reads a bucket size and optional inclusive from/to dates (YYYY-MM-DD). By
+// default the period ends today and covers 30 days, 12 weeks or 12 months.
+func ParsePeriod(bucket string, from string, to string) (Period, error) {
+	if bucket == "" {
+		bucket = BucketDay
+	}
+	p := Period{Bucket: bucket}
+	if bucket != BucketDay && bucket != BucketWeek && bucket != BucketMonth {
+		return p, fmt.Errorf("bucket must be %s, %s or %s", BucketDay, BucketWeek, BucketMonth)
+	}
+	end := time.Now()
+	if to != "" {
+		var err error
+		if end, err = time.ParseInLocation(time.DateOnly, to, time.Local); err != nil {
+			return p, fmt.Errorf("invalid to date %q", to)
+		}
+	}
+	p.To = p.next(p.start(end))
+	if from != "" {
+		start, err := time.ParseInLocation(time.DateOnly, from, time.Local)
+		if err != nil {
+			return p, fmt.Errorf("invalid from date %q", from)
+		}
+		p.From = p.start(start)
+	} else {
+		switch bucket {
+		case BucketDay:
+			p.From = p.To.AddDate(0, 0, -30)
+		case BucketWeek:
+			p.From = p.To.AddDate(0, 0, -7*12)
+		case BucketMonth:
+			p.From = p.To.AddDate(0, -12, 0)
+		}
+	}
+	if !p.From.Before(p.To) {
+		return p, fmt.Errorf("from must be before to")
+	}
+	if len(p.Labels()) > MAX_BUCKETS {
+		return p, fmt.Errorf("period covers more than %d %ss", MAX_BUCKETS, bucket)
+	}
+	return p, nil
+}
+
+// start returns the beginning of the bucket t falls in.
+func (p Period) start(t time.Time) time.Time {
+	t = t.In(time.Local)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	switch p.Bucket {
+	case BucketWeek:
+		// Weekday counts from Sunday, weeks start on Monday
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case BucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+	}
+	return day
+}
+
+// next returns the beginning of the bucket after the one starting at start.
+func (p Period) next(start time.Time) time.Time {
+	switch p.Bucket {
+	case BucketWeek:
+		return start.AddDate(0, 0, 7)
+	case BucketMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// Contains reports whether t is within the period.
+func (p Period) Contains(t time.Time) bool {
+	return !t.Before(p.From) && t.Before(p.To)
+}
+
+// Label names the bucket t falls in: the date for days and weeks (the Monday), or the month.
+func (p Period) Label(t time.Time) string {
+	start := p.start(t)
+	if p.Bucket == BucketMonth {
+		return start.Format("2006-01")
+	}
+	return start.Format(time.DateOnly)
+}
+
+// Labels lists every bucket in the period, in order.
+func (p Period) Labels() []string {
+	labels := []string{}
+	for t := p.From; t.Before(p.To) && len(labels) <= MAX_BUCKETS; t = p.next(t) {
+		labels = append(labels, p.Label(t))
+	}
+	return labels
+}
@@ -0,0 +1,80 @@
+package reports
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		name     string
+		bucket   string
+		from, to string
+		want     []string
+		count    int // checked instead of want for long periods
+		err      bool
+	}{
+		{name: "days", bucket: BucketDay, from: "2026-01-30", to: "2026-02-02", want: []string{"2026-01-30", "2026-01-31", "2026-02-01", "2026-02-02"}},
+		{name: "default bucket", from: "2026-01-01", to: "2026-01-01", want: []string{"2026-01-01"}},
+		{name: "weeks start on Monday", bucket: BucketWeek, from: "2026-01-07", to: "2026-01-19", want: []string{"2026-01-05", "2026-01-12", "2026-01-19"}},
+		{name: "Sunday is the end of a week", bucket: BucketWeek, from: "2026-01-11", to: "2026-01-11", want: []string{"2026-01-05"}},
+		{name: "months", bucket: BucketMonth, from: "2025-11-30", to: "2026-02-01", want: []string{"2025-11", "2025-12", "2026-01", "2026-02"}},
+		{name: "leap year", bucket: BucketDay, from: "2028-02-28", to: "2028-03-01", want: []string{"2028-02-28", "2028-02-29", "2028-03-01"}},
+		{name: "unknown bucket", bucket: "year", err: true},
+		{name: "bad from", from: "01/01/2026", to: "2026-01-02", err: true},
+		{name: "bad to", from: "2026-01-01", to: "tomorrow", err: true},
+		{name: "backwards", from: "2026-01-02", to: "2026-01-01", err: true},
+		{name: "too many buckets", bucket: BucketDay, from: "2000-01-01", to: "2026-01-01", err: true},
+		{name: "many months", bucket: BucketMonth, from: "2000-01-01", to: "2026-01-01", count: 26*12 + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePeriod(tt.bucket, tt.from, tt.to)
+			if tt.err {
+				if err == nil {
+					t.Errorf("got %v, want an error", p.Labels())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := p.Labels()
+			if tt.count != 0 {
+				if len(got) != tt.count {
+					t.Errorf("got %d buckets, want %d", len(got), tt.count)
+				}
+				return
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if first := p.Label(p.From); first != tt.want[0] || !p.Contains(p.From) || p.Contains(p.To) {
+				t.Errorf("period %s to %s doesn't start at %s and end before its To", p.From, p.To, tt.want[0])
+			}
+		})
+	}
+}
+
+// Without dates the period ends with the current bucket.
+func TestParsePeriodDefaults(t *testing.T) {
+	tests := []struct {
+		bucket string
+		want   int
+	}{
+		{bucket: BucketDay, want: 30},
+		{bucket: BucketWeek, want: 12},
+		{bucket: BucketMonth, want: 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bucket, func(t *testing.T) {
+			p, err := ParsePeriod(tt.bucket, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if labels := p.Labels(); len(labels) != tt.want {
+				t.Errorf("got %d buckets, want %d", len(labels), tt.want)
+			}
+		})
+	}
+}
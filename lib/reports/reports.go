@@ -0,0 +1,300 @@
+package reports
+
+import (
+	"context"
+	"encoding/json"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	sampleid "reesource-tracker/lib/sample_id"
+	samplestate "reesource-tracker/lib/sample_state"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MAX_TREE_DEPTH guards the parent walk against cycles in the location and product trees.
+const MAX_TREE_DEPTH = 32
+
+type StateCount struct {
+	State string `json:"state"`
+	Count int64  `json:"count"`
+}
+
+// TreeCount is the number of samples at a product or location. Count is the samples
+// directly at it, Total includes everything further down the tree.
+type TreeCount struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ParentID string `json:"parent_id"`
+	Path     string `json:"path"`
+	Count    int64  `json:"count"`
+	Total    int64  `json:"total"`
+}
+
+type OwnerCount struct {
+	OwnerID   string `json:"owner_id"`
+	OwnerName string `json:"owner_name"`
+	Count     int64  `json:"count"`
+}
+
+// ByState counts samples in each state. Every state is included, even with no samples.
+func ByState(ctx context.Context) ([]StateCount, error) {
+	rows, err := database.Connection.CountSamplesByState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int64{}
+	for _, row := range rows {
+		counts[row.State] = row.Count
+	}
+	result := []StateCount{}
+	for _, state := range samplestate.States {
+		result = append(result, StateCount{State: state, Count: counts[state]})
+	}
+	return result, nil
+}
+
+// ByProduct counts samples per product, rolled up the product tree.
+func ByProduct(ctx context.Context) ([]TreeCount, error) {
+	products, err := database.Connection.GetProducts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := database.Connection.CountSamplesByProduct(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []treeNode{}
+	for _, product := range products {
+		nodes = append(nodes, treeNode{
+			id:     id_helper.BlobToString(product.ID),
+			name:   product.Name,
+			parent: id_helper.BlobToString(product.ParentProductID),
+		})
+	}
+	counts := map[string]int64{}
+	for _, row := range rows {
+		counts[id_helper.BlobToString(row.ProductID)] += row.Count
+	}
+	return rollup(nodes, counts), nil
+}
+
+// ByLocation counts samples per location, rolled up the location tree.
+func ByLocation(ctx context.Context) ([]TreeCount, error) {
+	locations, err := database.Connection.GetLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := database.Connection.CountSamplesByLocation(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []treeNode{}
+	for _, location := range locations {
+		nodes = append(nodes, treeNode{
+			id:     id_helper.BlobToString(location.ID),
+			name:   location.Name,
+			parent: id_helper.BlobToString(location.ParentLocationID),
+		})
+	}
+	counts := map[string]int64{}
+	for _, row := range rows {
+		counts[id_helper.BlobToString(row.LocationID)] += row.Count
+	}
+	return rollup(nodes, counts), nil
+}
+
+// ByOwner counts samples per owner, most first. Samples without an owner have an empty owner_id.
+func ByOwner(ctx context.Context) ([]OwnerCount, error) {
+	rows, err := database.Connection.CountSamplesByOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := []OwnerCount{}
+	for _, row := range rows {
+		result = append(result, OwnerCount{
+			OwnerID:   id_helper.BlobToString(row.OwnerID),
+			OwnerName: row.OwnerName.String,
+			Count:     row.Count,
+		})
+	}
+	return result, nil
+}
+
+type treeNode struct {
+	id, name, parent string
+}
+
+// rollup adds each node's count to all of its ancestors. Samples without a product or
+// location are reported under an empty ID.
+func rollup(nodes []treeNode, counts map[string]int64) []TreeCount {
+	byID := map[string]treeNode{}
+	for _, node := range nodes {
+		byID[node.id] = node
+	}
+	result := []TreeCount{}
+	totals := map[string]int64{}
+	for id, count := range counts {
+		seen := map[string]bool{}
+		for depth := 0; id != "" && !seen[id] && depth < MAX_TREE_DEPTH; depth++ {
+			seen[id] = true
+			totals[id] += count
+			id = byID[id].parent
+		}
+	}
+	for _, node := range nodes {
+		result = append(result, TreeCount{
+			ID:       node.id,
+			Name:     node.name,
+			ParentID: node.parent,
+			Path:     path(byID, node.id),
+			Count:    counts[node.id],
+			Total:    totals[node.id],
+		})
+	}
+	if counts[""] > 0 {
+		result = append(result, TreeCount{Name: "(none)", Path: "(none)", Count: counts[""], Total: counts[""]})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
+
+// path joins the names from the root of the tree down to id.
+func path(byID map[string]treeNode, id string) string {
+	names := []string{}
+	seen := map[string]bool{}
+	for depth := 0; id != "" && !seen[id] && depth < MAX_TREE_DEPTH; depth++ {
+		seen[id] = true
+		node, ok := byID[id]
+		if !ok {
+			break
+		}
+		names = append([]string{node.name}, names...)
+		id = node.parent
+	}
+	return strings.Join(names, " / ")
+}
+
+// StateChange is a sample moving into a new state, taken from the activity log.
+type StateChange struct {
+	SampleID string
+	From     string
+	To       string
+	Time     time.Time
+}
+
+// stateChanges reads the state transitions recorded in the activity log, oldest first.
+func stateChanges(ctx context.Context) ([]StateChange, error) {
+	rows, err := database.Connection.ListSampleStateChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changes := []StateChange{}
+	for _, row := range rows {
+		var details struct {
+			State *struct {
+				From string `json:"from"`
+				To   string `json:"to"`
+			} `json:"state"`
+		}
+		if err := json.Unmarshal([]byte(row.Details.String), &details); err != nil || details.State == nil {
+			continue
+		}
+		changes = append(changes, StateChange{
+			SampleID: row.EntityID,
+			From:     details.State.From,
+			To:       details.State.To,
+			Time:     row.Time,
+		})
+	}
+	return changes, nil
+}
+
+// SeriesPoint is the number of samples registered in one bucket.
+type SeriesPoint struct {
+	Period string `json:"period"`
+	Count  int64  `json:"count"`
+}
+
+// StateSeriesPoint is the number of samples that moved into each state in one bucket.
+type StateSeriesPoint struct {
+	Period string           `json:"period"`
+	Total  int64            `json:"total"`
+	States map[string]int64 `json:"states"`
+}
+
+// Registrations counts samples registered in each bucket of the period. A sample is
+// registered at its time_registered or, for samples generated as unassigned labels, the
+// first time it left the unassigned state.
+func Registrations(ctx context.Context, period Period) ([]SeriesPoint, error) {
+	rows, err := database.Connection.ListSampleRegistrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := stateChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+	firstAssigned := map[string]time.Time{}
+	for _, change := range changes {
+		if change.To == "unassigned" || (change.From != "" && change.From != "unassigned") {
+			continue
+		}
+		if _, ok := firstAssigned[change.SampleID]; !ok {
+			firstAssigned[change.SampleID] = change.Time
+		}
+	}
+	counts := map[string]int64{}
+	for _, row := range rows {
+		registered := row.TimeRegistered.Time
+		if !row.TimeRegistered.Valid {
+			raw, _ := row.ID.([]byte)
+			display_id, err := sampleid.FormatSampleID(raw)
+			if err != nil {
+				continue
+			}
+			var ok bool
+			if registered, ok = firstAssigned[display_id]; !ok {
+				continue
+			}
+		}
+		if period.Contains(registered) {
+			counts[period.Label(registered)]++
+		}
+	}
+	result := []SeriesPoint{}
+	for _, label := range period.Labels() {
+		result = append(result, SeriesPoint{Period: label, Count: counts[label]})
+	}
+	return result, nil
+}
+
+// StateChanges counts the samples that moved into each state in each bucket of the period.
+func StateChanges(ctx context.Context, period Period) ([]StateSeriesPoint, error) {
+	changes, err := stateChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+	points := map[string]*StateSeriesPoint{}
+	result := []StateSeriesPoint{}
+	for _, label := range period.Labels() {
+		point := StateSeriesPoint{Period: label, States: map[string]int64{}}
+		for _, state := range samplestate.States {
+			point.States[state] = 0
+		}
+		result = append(result, point)
+	}
+	for i := range result {
+		points[result[i].Period] = &result[i]
+	}
+	for _, change := range changes {
+		if !period.Contains(change.Time) {
+			continue
+		}
+		if point, ok := points[period.Label(change.Time)]; ok {
+			point.States[change.To]++
+			point.Total++
+		}
+	}
+	return result, nil
+}
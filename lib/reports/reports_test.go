@@ -0,0 +1,199 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reesource-tracker/lib/database"
+	sampleid "reesource-tracker/lib/sample_id"
+	"reesource-tracker/lib/testenv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRollup(t *testing.T) {
+	tree := []treeNode{
+		{id: "lab", name: "Lab"},
+		{id: "shelf", name: "Shelf", parent: "lab"},
+		{id: "box", name: "Box", parent: "shelf"},
+		{id: "store", name: "Store"},
+	}
+	tests := []struct {
+		name   string
+		nodes  []treeNode
+		counts map[string]int64
+		want   map[string][2]int64 // path to count and total
+	}{
+		{
+			name:   "rolled up to the root",
+			nodes:  tree,
+			counts: map[string]int64{"box": 2, "shelf": 1, "store": 4},
+			want: map[string][2]int64{
+				"Lab": {0, 3}, "Lab / Shelf": {1, 3}, "Lab / Shelf / Box": {2, 2}, "Store": {4, 4},
+			},
+		},
+		{
+			name:   "samples without a location",
+			nodes:  tree[:1],
+			counts: map[string]int64{"": 5, "lab": 1},
+			want:   map[string][2]int64{"Lab": {1, 1}, "(none)": {5, 5}},
+		},
+		{
+			name:   "unknown parent",
+			nodes:  []treeNode{{id: "a", name: "A", parent: "gone"}},
+			counts: map[string]int64{"a": 1},
+			want:   map[string][2]int64{"A": {1, 1}},
+		},
+		{
+			name:   "cycle",
+			nodes:  []treeNode{{id: "a", name: "A", parent: "b"}, {id: "b", name: "B", parent: "a"}},
+			counts: map[string]int64{"a": 1},
+			want:   map[string][2]int64{"B / A": {1, 1}, "A / B": {0, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rollup(tt.nodes, tt.counts)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %d rows", got, len(tt.want))
+			}
+			for i, row := range got {
+				if i > 0 && got[i-1].Path > row.Path {
+					t.Errorf("rows not sorted by path: %q before %q", got[i-1].Path, row.Path)
+				}
+				if want, ok := tt.want[row.Path]; !ok || row.Count != want[0] || row.Total != want[1] {
+					t.Errorf("%s: got count %d total %d, want %v", row.Path, row.Count, row.Total, want)
+				}
+			}
+		})
+	}
+}
+
+// Registrations and state changes are counted from the activity log for generated samples,
+// and from time_registered for the rest.
+func TestSeries(t *testing.T) {
+	testenv.Engines(t, func(t *testing.T) {
+		ctx := context.Background()
+		day := func(d int) time.Time { return time.Date(2026, 1, d, 12, 0, 0, 0, time.Local) }
+		var generated [][]byte
+		err := database.Transaction(ctx, func(q *database.Queries) error {
+			var err error
+			if generated, err = q.RegisterNewSamples(ctx, 3); err != nil {
+				return err
+			}
+			_, raw, err := sampleid.GenerateNewSampleID()
+			if err != nil {
+				return err
+			}
+			_, err = q.UpdateOrCreateSample(ctx, database.UpdateOrCreateSampleParams{
+				ID:             raw[:],
+				State:          "available",
+				TimeRegistered: sql.NullTime{Time: day(2), Valid: true},
+			})
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		changes := []struct {
+			sample   []byte
+			from, to string
+			at       time.Time
+		}{
+			{generated[0], "unassigned", "in_use", day(5)},
+			{generated[1], "unassigned", "available", day(5)},
+			{generated[1], "available", "broken", day(20)},
+			{generated[1], "broken", "unassigned", day(21)},
+		}
+		for _, change := range changes {
+			display_id, _ := sampleid.FormatSampleID(change.sample)
+			id, _ := uuid.New().MarshalBinary()
+			err := database.Connection.RecordActivity(ctx, database.RecordActivityParams{
+				ID:         id,
+				EntityKind: "sample",
+				EntityID:   display_id,
+				Action:     "updated",
+				Details:    sql.NullString{String: fmt.Sprintf(`{"state":{"from":%q,"to":%q}}`, change.from, change.to), Valid: true},
+				Time:       change.at,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		tests := []struct {
+			bucket, from, to string
+			registrations    map[string]int64
+			moves            map[string]map[string]int64
+		}{
+			{
+				bucket: BucketDay, from: "2026-01-01", to: "2026-01-31",
+				registrations: map[string]int64{"2026-01-02": 1, "2026-01-05": 2},
+				moves: map[string]map[string]int64{
+					"2026-01-05": {"in_use": 1, "available": 1},
+					"2026-01-20": {"broken": 1},
+					"2026-01-21": {"unassigned": 1},
+				},
+			},
+			{
+				bucket: BucketWeek, from: "2026-01-01", to: "2026-01-31",
+				registrations: map[string]int64{"2025-12-29": 1, "2026-01-05": 2},
+				moves: map[string]map[string]int64{
+					"2026-01-05": {"in_use": 1, "available": 1},
+					"2026-01-19": {"broken": 1, "unassigned": 1},
+				},
+			},
+			{
+				bucket: BucketMonth, from: "2026-02-01", to: "2026-03-31",
+				registrations: map[string]int64{},
+				moves:         map[string]map[string]int64{},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.bucket+" from "+tt.from, func(t *testing.T) {
+				period, err := ParsePeriod(tt.bucket, tt.from, tt.to)
+				if err != nil {
+					t.Fatal(err)
+				}
+				registrations, err := Registrations(ctx, period)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, point := range registrations {
+					if point.Count != tt.registrations[point.Period] {
+						t.Errorf("registrations in %s: got %d, want %d", point.Period, point.Count, tt.registrations[point.Period])
+					}
+				}
+				moves, err := StateChanges(ctx, period)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, point := range moves {
+					var total int64
+					for state, count := range point.States {
+						total += count
+						if count != tt.moves[point.Period][state] {
+							t.Errorf("moves into %s in %s: got %d, want %d", state, point.Period, count, tt.moves[point.Period][state])
+						}
+					}
+					if point.Total != total {
+						t.Errorf("total in %s: got %d, want %d", point.Period, point.Total, total)
+					}
+				}
+			})
+		}
+
+		states, err := ByState(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]int64{"unassigned": 3, "available": 1}
+		for _, row := range states {
+			if row.Count != want[row.State] {
+				t.Errorf("samples %s: got %d, want %d", row.State, row.Count, want[row.State])
+			}
+		}
+	})
+}
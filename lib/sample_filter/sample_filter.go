@@ -0,0 +1,210 @@
+package samplefilter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	samplestate "reesource-tracker/lib/sample_state"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ANY_PREFIX before a product or location ID also selects everything below it, as in the
+// client's product and location filters.
+const ANY_PREFIX = "any-"
+
+// Values of Filter.Modded
+const (
+	ModdedAny      = "any"
+	ModdedActive   = "active"
+	ModdedNoActive = "noactive"
+	ModdedNever    = "never"
+)
+
+// Filter selects samples the same way as the filters on the client's sample list. The
+// zero value selects every sample.
+type Filter struct {
+	State []string `form:"state"`
+	// ActiveOnly hides unassigned and archived samples, unless State asks for them
+	ActiveOnly bool `form:"active_only"`
+	// Product and Location are a UUID, or ANY_PREFIX and a UUID to include the tree below it
+	Product  string `form:"product"`
+	Location string `form:"location"`
+	Owner    string `form:"owner"`
+	// Issue matches product issues containing it, ignoring case
+	Issue string `form:"issue"`
+	// Modded is one of the Modded constants
+	Modded string `form:"modded"`
+	// Mods is a comma separated list; samples match if any active mod contains one of them
+	Mods string `form:"mods"`
+}
+
+// Matcher is a checked Filter with the product and location trees resolved.
+type Matcher struct {
+	filter    Filter
+	states    map[string]bool
+	products  map[string]bool
+	locations map[string]bool
+	mods      []string
+}
+
+// Matcher checks the filter and prepares it for matching samples.
+func (f Filter) Matcher(ctx context.Context) (*Matcher, error) {
+	m := &Matcher{filter: f}
+	if len(f.State) > 0 {
+		m.states = map[string]bool{}
+		for _, state := range f.State {
+			if !slices.Contains(samplestate.States, state) {
+				return nil, fmt.Errorf("unknown state %q", state)
+			}
+			m.states[state] = true
+		}
+	}
+	if f.ActiveOnly && !m.states["unassigned"] && !m.states["archived"] {
+		if m.states == nil {
+			m.states = map[string]bool{}
+			for _, state := range samplestate.States {
+				m.states[state] = true
+			}
+		}
+		delete(m.states, "unassigned")
+		delete(m.states, "archived")
+	}
+	switch f.Modded {
+	case "", ModdedAny, ModdedActive, ModdedNoActive, ModdedNever:
+	default:
+		return nil, fmt.Errorf("modded must be %s, %s, %s or %s", ModdedAny, ModdedActive, ModdedNoActive, ModdedNever)
+	}
+	if f.Owner != "" {
+		if _, err := uuid.Parse(f.Owner); err != nil {
+			return nil, errors.New("owner must be a UUID")
+		}
+	}
+	for _, mod := range strings.Split(f.Mods, ",") {
+		if mod = strings.ToLower(strings.TrimSpace(mod)); mod != "" {
+			m.mods = append(m.mods, mod)
+		}
+	}
+
+	var err error
+	if f.Product != "" {
+		m.products, err = selectTree(f.Product, "product", func() ([]node, error) {
+			products, err := database.Connection.GetProducts(ctx)
+			nodes := []node{}
+			for _, product := range products {
+				nodes = append(nodes, node{id_helper.BlobToString(product.ID), id_helper.BlobToString(product.ParentProductID)})
+			}
+			return nodes, err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if f.Location != "" {
+		m.locations, err = selectTree(f.Location, "location", func() ([]node, error) {
+			locations, err := database.Connection.GetLocations(ctx)
+			nodes := []node{}
+			for _, location := range locations {
+				nodes = append(nodes, node{id_helper.BlobToString(location.ID), id_helper.BlobToString(location.ParentLocationID)})
+			}
+			return nodes, err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+type node struct {
+	id, parent string
+}
+
+// selectTree returns the IDs a product or location filter selects: the ID itself, and with
+// ANY_PREFIX everything below it.
+func selectTree(value string, name string, load func() ([]node, error)) (map[string]bool, error) {
+	withChildren := strings.HasPrefix(value, ANY_PREFIX)
+	id, err := uuid.Parse(strings.TrimPrefix(value, ANY_PREFIX))
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a UUID, optionally prefixed with %q", name, ANY_PREFIX)
+	}
+	selected := map[string]bool{id.String(): true}
+	if !withChildren {
+		return selected, nil
+	}
+	nodes, err := load()
+	if err != nil {
+		return nil, err
+	}
+	children := map[string][]string{}
+	for _, n := range nodes {
+		children[n.parent] = append(children[n.parent], n.id)
+	}
+	queue := []string{id.String()}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, child := range children[next] {
+			// The seen check also stops cycles in the tree
+			if !selected[child] {
+				selected[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+	return selected, nil
+}
+
+// Match reports whether a sample is selected by the filter.
+func (m *Matcher) Match(ctx context.Context, sample database.ListSamplesRow) (bool, error) {
+	f := m.filter
+	if m.states != nil && !m.states[sample.State] {
+		return false, nil
+	}
+	if m.products != nil && !m.products[id_helper.BlobToString(sample.ProductID)] {
+		return false, nil
+	}
+	if m.locations != nil && !m.locations[id_helper.BlobToString(sample.LocationID)] {
+		return false, nil
+	}
+	if f.Owner != "" && !strings.EqualFold(id_helper.BlobToString(sample.OwnerID), f.Owner) {
+		return false, nil
+	}
+	if f.Issue != "" && !strings.Contains(strings.ToLower(sample.ProductIssue.String), strings.ToLower(f.Issue)) {
+		return false, nil
+	}
+	mods := strings.ToLower(ModsSummary(sample))
+	if len(m.mods) > 0 && !slices.ContainsFunc(m.mods, func(mod string) bool { return strings.Contains(mods, mod) }) {
+		return false, nil
+	}
+	switch f.Modded {
+	case ModdedActive:
+		return mods != "", nil
+	case ModdedNoActive, ModdedNever:
+		if mods != "" {
+			return false, nil
+		}
+		// Only samples without active mods need their history checked
+		history, err := database.Connection.ListSampleMods(ctx, sample.ID)
+		if err != nil {
+			return false, err
+		}
+		return (len(history) > 0) == (f.Modded == ModdedNoActive), nil
+	}
+	return true, nil
+}
+
+// ModsSummary is the comma separated names of a sample's active mods.
+func ModsSummary(sample database.ListSamplesRow) string {
+	switch summary := sample.CurrentModsSummary.(type) {
+	case string:
+		return summary
+	case []byte:
+		return string(summary)
+	}
+	return ""
+}
@@ -0,0 +1,5 @@
+package samplestate
+
+// States are the sample states, in the order they are reported. They match the CHECK
+// constraint on samples.state.
+var States = []string{"in_use", "available", "broken", "archived", "unassigned"}
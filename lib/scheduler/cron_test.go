@@ -0,0 +1,165 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// bits returns the bitset matching the given values.
+func bits(values ...int) uint64 {
+	var b uint64
+	for _, v := range values {
+		b |= 1 << v
+	}
+	return b
+}
+
+func TestParseField(t *testing.T) {
+	tests := []struct {
+		field    string
+		min, max int
+		want     uint64
+		err      bool
+	}{
+		{field: "*", min: 0, max: 6, want: bits(0, 1, 2, 3, 4, 5, 6)},
+		{field: "5", min: 0, max: 59, want: bits(5)},
+		{field: "1,15", min: 1, max: 31, want: bits(1, 15)},
+		{field: "1-5", min: 0, max: 6, want: bits(1, 2, 3, 4, 5)},
+		{field: "*/15", min: 0, max: 59, want: bits(0, 15, 30, 45)},
+		{field: "0-30/10", min: 0, max: 59, want: bits(0, 10, 20, 30)},
+		{field: "5/20", min: 0, max: 59, want: bits(5, 25, 45)},
+		{field: "*/5", min: 1, max: 12, want: bits(1, 6, 11)},
+		{field: "1-3,10-12/2", min: 1, max: 12, want: bits(1, 2, 3, 10, 12)},
+		{field: "0-0", min: 0, max: 23, want: bits(0)},
+		{field: "60", min: 0, max: 59, err: true},
+		{field: "0", min: 1, max: 31, err: true},
+		{field: "5-1", min: 0, max: 59, err: true},
+		{field: "*/0", min: 0, max: 59, err: true},
+		{field: "*/x", min: 0, max: 59, err: true},
+		{field: "a", min: 0, max: 59, err: true},
+		{field: "1-x", min: 0, max: 59, err: true},
+		{field: "", min: 0, max: 59, err: true},
+		{field: "1,", min: 0, max: 59, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, err := parseField(tt.field, tt.min, tt.max)
+			if (err != nil) != tt.err || got != tt.want {
+				t.Errorf("parseField(%q, %d, %d): got %b, %v, want %b", tt.field, tt.min, tt.max, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		expr string
+		want Schedule
+		err  bool
+	}{
+		{expr: "@hourly", want: Schedule{minute: bits(0), hour: (1 << 24) - 1, dom: (1<<32 - 1) &^ 1, month: (1<<13 - 1) &^ 1, dow: (1 << 8) - 1}},
+		{expr: " 30 3 * * 1-5 ", want: Schedule{minute: bits(30), hour: bits(3), dom: (1<<32 - 1) &^ 1, month: (1<<13 - 1) &^ 1, dow: bits(1, 2, 3, 4, 5), dowRestricted: true}},
+		{expr: "0 0 1 1 7", want: Schedule{minute: bits(0), hour: bits(0), dom: bits(1), month: bits(1), dow: bits(0, 7), domRestricted: true, dowRestricted: true}},
+		{expr: "0 0 * * *", want: Schedule{minute: bits(0), hour: bits(0), dom: (1<<32 - 1) &^ 1, month: (1<<13 - 1) &^ 1, dow: (1 << 8) - 1}},
+		{expr: "0 0 * *", err: true},
+		{expr: "0 0 * * * *", err: true},
+		{expr: "@sometimes", err: true},
+		{expr: "0 24 * * *", err: true},
+		{expr: "0 0 32 * *", err: true},
+		{expr: "0 0 * 13 *", err: true},
+		{expr: "0 0 * * 8", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseSchedule(tt.expr)
+			if tt.err {
+				if err == nil {
+					t.Errorf("got %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2026, 1, 7, 10, 17, 42, 0, time.UTC)
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{name: "next minute", expr: "* * * * *", from: from, want: time.Date(2026, 1, 7, 10, 18, 0, 0, time.UTC)},
+		{name: "strictly after", expr: "18 10 * * *", from: time.Date(2026, 1, 7, 10, 18, 0, 0, time.UTC), want: time.Date(2026, 1, 8, 10, 18, 0, 0, time.UTC)},
+		{name: "step", expr: "*/15 * * * *", from: from, want: time.Date(2026, 1, 7, 10, 30, 0, 0, time.UTC)},
+		{name: "hour range", expr: "0 8-9 * * *", from: from, want: time.Date(2026, 1, 8, 8, 0, 0, 0, time.UTC)},
+		{name: "stepped range", expr: "0 9-17/4 * * *", from: from, want: time.Date(2026, 1, 7, 13, 0, 0, 0, time.UTC)},
+		{name: "day of week only", expr: "0 0 * * 5", from: from, want: time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC)},
+		{name: "day of month only", expr: "0 0 13 * *", from: from, want: time.Date(2026, 1, 13, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: the 13th or a Friday, whichever comes first
+		{name: "either day, weekday first", expr: "0 0 13 * 5", from: from, want: time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC)},
+		{name: "either day, date first", expr: "0 0 8 * 1", from: from, want: time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC)},
+		{name: "sunday as 7", expr: "0 0 * * 7", from: from, want: time.Date(2026, 1, 11, 0, 0, 0, 0, time.UTC)},
+		{name: "month", expr: "0 0 1 3 *", from: from, want: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "year end", expr: "@yearly", from: time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC), want: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "leap day", expr: "0 0 29 2 *", from: from, want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "never", expr: "0 0 31 2 *", from: from, want: time.Time{}},
+		{name: "short month skipped", expr: "0 0 31 * *", from: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), want: time.Date(2026, 5, 31, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseSchedule(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) for %q: got %s, want %s", tt.from, tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+// In New York the clocks go from 02:00 to 03:00 on 8 March 2026, and from 02:00 back to
+// 01:00 on 1 November 2026.
+func TestNextDaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC).In(loc)
+	}
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{name: "in the gap", expr: "30 2 * * *", from: utc(3, 8, 5, 0), want: utc(3, 8, 7, 30)}, // 03:30 EDT
+		{name: "after the gap", expr: "30 2 * * *", from: utc(3, 8, 7, 30), want: utc(3, 9, 6, 30)},
+		{name: "gap start", expr: "0 2 * * *", from: utc(3, 8, 5, 0), want: utc(3, 8, 7, 0)}, // 03:00 EDT
+		{name: "across the gap", expr: "*/30 * * * *", from: utc(3, 8, 6, 45), want: utc(3, 8, 7, 0)},
+		{name: "past the gap", expr: "*/30 * * * *", from: utc(3, 8, 7, 0), want: utc(3, 8, 7, 30)},
+		{name: "before the repeat", expr: "30 1 * * *", from: utc(11, 1, 4, 0), want: utc(11, 1, 5, 30)}, // 01:30 EDT
+		{name: "not repeated", expr: "30 1 * * *", from: utc(11, 1, 5, 30), want: utc(11, 2, 6, 30)},
+		{name: "during the repeat", expr: "30 1 * * *", from: utc(11, 1, 6, 10), want: utc(11, 2, 6, 30)},
+		{name: "hourly over the repeat", expr: "0 * * * *", from: utc(11, 1, 5, 0), want: utc(11, 1, 7, 0)}, // 02:00 EST
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseSchedule(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) for %q: got %s, want %s", tt.from, tt.expr, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reesource-tracker/lib/database"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Run statuses, stored in job_runs.status and scheduled_jobs.last_status
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+var (
+	ErrUnknownJob     = errors.New("unknown job")
+	ErrAlreadyRunning = errors.New("job is already running")
+)
+
+// Job is a task run in the background on a cron schedule.
+type Job struct {
+	Name        string
+	Description string
+	// Schedule is the default cron expression; admins can change it through the API
+	Schedule string
+	// Run does the work and returns a short summary for the run history
+	Run func(ctx context.Context) (string, error)
+}
+
+// Status is a job's configuration and the state of its latest run.
+type Status struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Schedule    string     `json:"schedule"`
+	Enabled     bool       `json:"enabled"`
+	Running     bool       `json:"running"`
+	LastRunAt   *time.Time `json:"last_run_at"`
+	LastStatus  string     `json:"last_status"`
+	NextRunAt   *time.Time `json:"next_run_at"`
+}
+
+var (
+	jobs = map[string]*Job{}
+	// mu guards running, runCtx and started, which Run sets while API handlers read them
+	mu      sync.Mutex
+	running = map[string]bool{}
+	// active counts the runs in progress, so Run can wait for them when it stops
+	active sync.WaitGroup
+	// runCtx is the context given to Run, also used for runs started through the API
+	runCtx  = context.Background()
+	started = time.Now()
+)
+
+// Register adds a job. It must be called before Run.
+func Register(job Job) {
+	if _, err := ParseSchedule(job.Schedule); err != nil {
+		panic(fmt.Sprintf("job %s: %s", job.Name, err))
+	}
+	jobs[job.Name] = &job
+}
+
+// Run records the registered jobs and runs them when they are due, until ctx is cancelled.
+// Jobs that were due while the server was down run once when it starts; jobs that have
+// never run wait for their next scheduled time. Cancelling ctx also cancels the jobs in
+// progress, and Run returns once they have stopped and their results are recorded.
+func Run(ctx context.Context) {
+	mu.Lock()
+	runCtx = ctx
+	started = time.Now()
+	mu.Unlock()
+	err := database.Connection.FailInterruptedJobRuns(ctx, sql.NullTime{Time: time.Now().UTC(), Valid: true})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to clean up interrupted job runs", "error", err)
+	}
+	for _, job := range jobs {
+		err := database.Connection.EnsureScheduledJob(ctx, database.EnsureScheduledJobParams{
+			Name:     job.Name,
+			Schedule: job.Schedule,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to register job", "job", job.Name, "error", err)
+		}
+	}
+	for {
+		runDue(ctx)
+		// Schedules have minute resolution, so check at the start of every minute
+		wait := time.Until(time.Now().Truncate(time.Minute).Add(time.Minute))
+		select {
+		case <-ctx.Done():
+			active.Wait()
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func runDue(ctx context.Context) {
+	rows, err := database.Connection.ListScheduledJobs(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load scheduled jobs", "error", err)
+		return
+	}
+	now := time.Now()
+	for _, row := range rows {
+		job, ok := jobs[row.Name]
+		if !ok || !row.Enabled {
+			continue
+		}
+		next := nextRun(row)
+		if !next.IsZero() && !next.After(now) {
+			start(job)
+		}
+	}
+}
+
+// nextRun is the first scheduled time after the job last ran, or after the scheduler
+// started if it never has.
+func nextRun(row database.ScheduledJob) time.Time {
+	schedule, err := ParseSchedule(row.Schedule)
+	if err != nil {
+		return time.Time{}
+	}
+	mu.Lock()
+	base := started
+	mu.Unlock()
+	if row.LastRunAt.Valid {
+		base = row.LastRunAt.Time
+	}
+	return schedule.Next(base.Local())
+}
+
+func start(job *Job) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	if running[job.Name] {
+		return false
+	}
+	running[job.Name] = true
+	active.Add(1)
+	go execute(runCtx, job)
+	return true
+}
+
+func isRunning(name string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return running[name]
+}
+
+func execute(ctx context.Context, job *Job) {
+	defer active.Done()
+	defer func() {
+		mu.Lock()
+		delete(running, job.Name)
+		mu.Unlock()
+	}()
+	startedAt := time.Now().UTC()
+	runID, err := database.Connection.StartJobRun(ctx, database.StartJobRunParams{
+		JobName:   job.Name,
+		StartedAt: startedAt,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record job run", "job", job.Name, "error", err)
+		return
+	}
+	// The last run is recorded as the job starts, so a job that brings the server down
+	// isn't retried in a loop
+	setLastRun(ctx, job.Name, startedAt, StatusRunning)
+
+	output, err := runJob(ctx, job)
+	// Record the result even if the job was cancelled because the server is stopping
+	ctx = context.WithoutCancel(ctx)
+	status := StatusSucceeded
+	if err != nil {
+		status = StatusFailed
+		output = strings.TrimSpace(output + "\n" + err.Error())
+	}
+	slog.LogAttrs(ctx, logLevel(status), "Job finished",
+		slog.String("job", job.Name),
+		slog.String("status", status),
+		slog.Float64("duration_ms", float64(time.Since(startedAt).Microseconds())/1000),
+		slog.String("output", output),
+	)
+	err = database.Connection.FinishJobRun(ctx, database.FinishJobRunParams{
+		FinishedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		Status:     status,
+		Output:     output,
+		ID:         runID,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record job result", "job", job.Name, "error", err)
+	}
+	setLastRun(ctx, job.Name, startedAt, status)
+}
+
+func logLevel(status string) slog.Level {
+	if status == StatusFailed {
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// runJob runs a job, turning a panic into an error so it is recorded like any other failure.
+func runJob(ctx context.Context, job *Job) (output string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+func setLastRun(ctx context.Context, name string, at time.Time, status string) {
+	err := database.Connection.SetJobLastRun(ctx, database.SetJobLastRunParams{
+		LastRunAt:  sql.NullTime{Time: at, Valid: true},
+		LastStatus: sql.NullString{String: status, Valid: true},
+		Name:       name,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record job status", "job", name, "error", err)
+	}
+}
+
+// RunNow starts a job in the background, outside its schedule.
+func RunNow(name string) error {
+	job, ok := jobs[name]
+	if !ok {
+		return ErrUnknownJob
+	}
+	if !start(job) {
+		return ErrAlreadyRunning
+	}
+	return nil
+}
+
+// Update changes a job's schedule and whether it runs on it.
+func Update(ctx context.Context, name string, schedule string, enabled bool) error {
+	if _, ok := jobs[name]; !ok {
+		return ErrUnknownJob
+	}
+	if _, err := ParseSchedule(schedule); err != nil {
+		return err
+	}
+	return database.Connection.UpdateScheduledJob(ctx, database.UpdateScheduledJobParams{
+		Schedule: strings.TrimSpace(schedule),
+		Enabled:  enabled,
+		Name:     name,
+	})
+}
+
+// List returns the status of every registered job, sorted by name.
+func List(ctx context.Context) ([]Status, error) {
+	rows, err := database.Connection.ListScheduledJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []Status{}
+	for _, row := range rows {
+		if _, ok := jobs[row.Name]; ok {
+			statuses = append(statuses, status(row))
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+// Get returns the status of one job.
+func Get(ctx context.Context, name string) (Status, error) {
+	if _, ok := jobs[name]; !ok {
+		return Status{}, ErrUnknownJob
+	}
+	row, err := database.Connection.GetScheduledJob(ctx, name)
+	if err != nil {
+		return Status{}, err
+	}
+	return status(row), nil
+}
+
+func status(row database.ScheduledJob) Status {
+	s := Status{
+		Name:        row.Name,
+		Description: jobs[row.Name].Description,
+		Schedule:    row.Schedule,
+		Enabled:     row.Enabled,
+		Running:     isRunning(row.Name),
+		LastStatus:  row.LastStatus.String,
+	}
+	if row.LastRunAt.Valid {
+		s.LastRunAt = &row.LastRunAt.Time
+	}
+	if next := nextRun(row); row.Enabled && !next.IsZero() {
+		s.NextRunAt = &next
+	}
+	return s
+}
@@ -0,0 +1,272 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reesource-tracker/lib/database"
+	id_helper "reesource-tracker/lib/id_helper"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// Headers sent with every delivery.
+const (
+	HEADER_EVENT     = "X-Reesource-Event"
+	HEADER_DELIVERY  = "X-Reesource-Delivery"
+	HEADER_SIGNATURE = "X-Reesource-Signature-256"
+)
+
+const (
+	// MAX_ATTEMPTS is how many times a delivery is tried before it is marked failed.
+	MAX_ATTEMPTS = 10
+	// RETRY_BASE is the delay before the first retry; each further retry doubles it.
+	RETRY_BASE = 30 * time.Second
+	// RETRY_MAX caps the delay between retries.
+	RETRY_MAX = 6 * time.Hour
+	// POLL_INTERVAL is how often the queue is checked for retries that have come due.
+	POLL_INTERVAL = 5 * time.Second
+	// BATCH_SIZE is how many due deliveries are loaded at a time.
+	BATCH_SIZE = 20
+	// REQUEST_TIMEOUT limits a single delivery attempt.
+	REQUEST_TIMEOUT = 10 * time.Second
+	// MAX_RESPONSE_BODY is how much of the receiver's response is kept in the log.
+	MAX_RESPONSE_BODY = 4096
+)
+
+// Payload is the JSON body POSTed to webhook URLs.
+type Payload struct {
+	DeliveryID string      `json:"delivery_id"`
+	EventID    uint64      `json:"event_id"`
+	Event      string      `json:"event"`
+	Data       interface{} `json:"data"`
+	Time       time.Time   `json:"time"`
+}
+
+var (
+	wake   = make(chan struct{}, 1)
+	client = &http.Client{Timeout: REQUEST_TIMEOUT}
+)
+
+// GenerateSecret returns a random signing secret for webhooks created without one.
+func GenerateSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// Sign returns the signature header value for a body: "sha256=" followed by the hex
+// HMAC-SHA256 of the body, keyed with the webhook's secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SplitEventTypes parses the stored event type filter. An empty filter matches every event.
+func SplitEventTypes(eventTypes string) []string {
+	if eventTypes == "" {
+		return []string{}
+	}
+	return strings.Split(eventTypes, ",")
+}
+
+// Matches reports whether a webhook with the given event type filter wants an event.
+func Matches(eventTypes string, eventType string) bool {
+	return eventTypes == "" || slices.Contains(SplitEventTypes(eventTypes), eventType)
+}
+
+// Store saves a delivery of the event through q for every active webhook that wants it, and
+// wakes Run to send them. The deliveries are in the database when it returns, so they
+// survive a crash; only sending them happens in the background. When q is a transaction,
+// the deliveries are committed or rolled back with it.
+func Store(ctx context.Context, q *database.Queries, eventID uint64, eventType string, data interface{}) error {
+	if q == nil {
+		return nil
+	}
+	hooks, err := q.ListActiveWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("loading webhooks: %w", err)
+	}
+	now := time.Now().UTC()
+	queued := false
+	for _, hook := range hooks {
+		if !Matches(hook.EventTypes, eventType) {
+			continue
+		}
+		delivery_uuid := uuid.New()
+		body, err := json.Marshal(Payload{
+			DeliveryID: delivery_uuid.String(),
+			EventID:    eventID,
+			Event:      eventType,
+			Data:       data,
+			Time:       now,
+		})
+		if err != nil {
+			return fmt.Errorf("encoding webhook payload: %w", err)
+		}
+		new_uid, _ := delivery_uuid.MarshalBinary()
+		err = q.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+			ID:            new_uid,
+			WebhookID:     hook.ID,
+			EventID:       int64(eventID),
+			EventType:     eventType,
+			Payload:       string(body),
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return fmt.Errorf("storing webhook delivery: %w", err)
+		}
+		queued = true
+	}
+	if queued {
+		// Deliveries stored in a transaction that is still open are picked up by the next poll
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Run sends stored deliveries until ctx is cancelled. Anything pending when the server
+// stops is sent after it restarts.
+func Run(ctx context.Context) {
+	ticker := time.NewTicker(POLL_INTERVAL)
+	defer ticker.Stop()
+	for {
+		for sendDue(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDue sends one batch of due deliveries and reports whether there may be more.
+func sendDue(ctx context.Context) bool {
+	if database.Connection == nil {
+		return false
+	}
+	due, err := database.Connection.ListDueWebhookDeliveries(ctx, database.ListDueWebhookDeliveriesParams{
+		NextAttemptAt: time.Now().UTC(),
+		Limit:         BATCH_SIZE,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load webhook deliveries", "error", err)
+		return false
+	}
+	for _, delivery := range due {
+		if ctx.Err() != nil {
+			return false
+		}
+		send(ctx, delivery)
+	}
+	return len(due) == BATCH_SIZE
+}
+
+// send makes one attempt at a delivery, logs it, and schedules a retry if it failed.
+func send(ctx context.Context, delivery database.ListDueWebhookDeliveriesRow) {
+	started := time.Now().UTC()
+	status, body, err := post(ctx, delivery)
+
+	attempt := database.RecordWebhookAttemptParams{
+		DeliveryID:  delivery.ID,
+		AttemptedAt: started,
+		DurationMs:  time.Since(started).Milliseconds(),
+	}
+	if status != 0 {
+		attempt.ResponseStatus = sql.NullInt64{Int64: int64(status), Valid: true}
+		attempt.ResponseBody = sql.NullString{String: body, Valid: true}
+	}
+	if err == nil && (status < 200 || status > 299) {
+		err = fmt.Errorf("receiver responded with %d", status)
+	}
+	if err != nil {
+		attempt.Error = sql.NullString{String: err.Error(), Valid: true}
+	}
+	if logErr := database.Connection.RecordWebhookAttempt(ctx, attempt); logErr != nil {
+		slog.ErrorContext(ctx, "Failed to log webhook attempt", "error", logErr)
+	}
+
+	attempts := delivery.Attempts + 1
+	update := database.UpdateWebhookDeliveryParams{
+		Status:        StatusDelivered,
+		Attempts:      attempts,
+		NextAttemptAt: started,
+		LastAttemptAt: sql.NullTime{Time: started, Valid: true},
+		ID:            delivery.ID,
+	}
+	if err != nil {
+		update.Status = StatusPending
+		update.NextAttemptAt = started.Add(Backoff(attempts))
+		if attempts >= MAX_ATTEMPTS {
+			update.Status = StatusFailed
+		}
+	}
+	if err := database.Connection.UpdateWebhookDelivery(ctx, update); err != nil {
+		slog.ErrorContext(ctx, "Failed to update webhook delivery", "error", err)
+	}
+}
+
+func post(ctx context.Context, delivery database.ListDueWebhookDeliveriesRow) (int, string, error) {
+	delivery_uuid, err := id_helper.UUIDFromBlob(delivery.ID)
+	if err != nil {
+		return 0, "", err
+	}
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "reesource-tracker-webhooks")
+	req.Header.Set(HEADER_EVENT, delivery.EventType)
+	req.Header.Set(HEADER_DELIVERY, delivery_uuid.String())
+	req.Header.Set(HEADER_SIGNATURE, Sign(delivery.Secret, body))
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer res.Body.Close()
+	response, _ := io.ReadAll(io.LimitReader(res.Body, MAX_RESPONSE_BODY))
+	return res.StatusCode, string(response), nil
+}
+
+// Backoff returns the delay before the next attempt, after the given number of attempts.
+func Backoff(attempts int64) time.Duration {
+	delay := RETRY_BASE
+	for i := int64(1); i < attempts; i++ {
+		delay *= 2
+		if delay >= RETRY_MAX {
+			return RETRY_MAX
+		}
+	}
+	return delay
+}
@@ -0,0 +1,225 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reesource-tracker/lib/config"
+	"reesource-tracker/lib/database"
+	"reesource-tracker/lib/testenv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const testSecret = "test-secret"
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// newReceiver starts a webhook receiver that answers 503 while fail is set, and creates an
+// active webhook for it wanting products_updated events. It returns the webhook's ID.
+func newReceiver(t *testing.T, fail *atomic.Bool) (chan received, []byte) {
+	t.Helper()
+	requests := make(chan received, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header.Clone(), body: body}
+		if fail.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(receiver.Close)
+	hookID, _ := uuid.New().MarshalBinary()
+	err := database.Connection.CreateWebhook(context.Background(), database.CreateWebhookParams{
+		ID:         hookID,
+		Url:        receiver.URL,
+		EventTypes: "products_updated",
+		Secret:     testSecret,
+		Active:     true,
+		CreatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return requests, hookID
+}
+
+func deliveries(t *testing.T, hookID []byte) []database.WebhookDelivery {
+	t.Helper()
+	rows, err := database.Connection.ListWebhookDeliveries(context.Background(), database.ListWebhookDeliveriesParams{WebhookID: hookID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func next(t *testing.T, requests chan received) received {
+	t.Helper()
+	select {
+	case req := <-requests:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a delivery")
+		return received{}
+	}
+}
+
+// checkSignature checks the signature header against an HMAC of the body computed here.
+func checkSignature(t *testing.T, req received) {
+	t.Helper()
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write(req.body)
+	if got, want := req.header.Get(HEADER_SIGNATURE), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("signature: got %q, want %q", got, want)
+	}
+}
+
+func TestDeliveryRetried(t *testing.T) {
+	testenv.Open(t, config.DRIVER_SQLITE)
+	ctx := context.Background()
+	var fail atomic.Bool
+	fail.Store(true)
+	requests, hookID := newReceiver(t, &fail)
+
+	runCtx, stop := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		Run(runCtx)
+		close(stopped)
+	}()
+	for id, eventType := range []string{"locations_updated", "products_updated"} {
+		if err := Store(ctx, database.Connection, uint64(id+1), eventType, map[string]string{"id": "abc"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := next(t, requests)
+	checkSignature(t, first)
+	var payload Payload
+	if err := json.Unmarshal(first.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.EventID != 2 || payload.Event != "products_updated" || payload.DeliveryID != first.header.Get(HEADER_DELIVERY) {
+		t.Errorf("payload: got %+v with headers %v", payload, first.header)
+	}
+	if event := first.header.Get(HEADER_EVENT); event != "products_updated" {
+		t.Errorf("%s: got %q", HEADER_EVENT, event)
+	}
+
+	// The failed attempt is recorded after the response, so wait for it before stopping
+	deadline := time.Now().Add(5 * time.Second)
+	for len(deliveries(t, hookID)) != 1 || deliveries(t, hookID)[0].Attempts != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("failed attempt not recorded: got %+v", deliveries(t, hookID))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+	<-stopped
+	delivery := deliveries(t, hookID)[0]
+	if delivery.Status != StatusPending || delivery.NextAttemptAt.Before(time.Now().Add(RETRY_BASE/2)) {
+		t.Fatalf("after a failure: got %s due at %s, want pending with a backoff", delivery.Status, delivery.NextAttemptAt)
+	}
+
+	// Bring the retry forward rather than waiting for the backoff
+	err := database.Connection.UpdateWebhookDelivery(ctx, database.UpdateWebhookDeliveryParams{
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		NextAttemptAt: time.Now().UTC(),
+		LastAttemptAt: delivery.LastAttemptAt,
+		ID:            delivery.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fail.Store(false)
+	sendDue(ctx)
+	retry := next(t, requests)
+	checkSignature(t, retry)
+	if string(retry.body) != string(first.body) || retry.header.Get(HEADER_DELIVERY) != first.header.Get(HEADER_DELIVERY) {
+		t.Errorf("retry differs from the first attempt: got %s, want %s", retry.body, first.body)
+	}
+	delivery = deliveries(t, hookID)[0]
+	if delivery.Status != StatusDelivered || delivery.Attempts != 2 {
+		t.Errorf("after the retry: got %s after %d attempts, want delivered after 2", delivery.Status, delivery.Attempts)
+	}
+	attempts, err := database.Connection.ListWebhookAttempts(ctx, delivery.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 2 || attempts[0].ResponseStatus.Int64 != http.StatusServiceUnavailable || attempts[1].ResponseStatus.Int64 != http.StatusNoContent {
+		t.Errorf("attempts: got %+v", attempts)
+	}
+}
+
+func TestStore(t *testing.T) {
+	testenv.Open(t, config.DRIVER_SQLITE)
+	ctx := context.Background()
+	var fail atomic.Bool
+	_, hookID := newReceiver(t, &fail)
+	allID, _ := uuid.New().MarshalBinary()
+	inactiveID, _ := uuid.New().MarshalBinary()
+	for _, hook := range []database.CreateWebhookParams{
+		{ID: allID, Url: "http://127.0.0.1:1/all", Active: true},
+		{ID: inactiveID, Url: "http://127.0.0.1:1/inactive", Active: false},
+	} {
+		hook.Secret, hook.CreatedAt = testSecret, time.Now().UTC()
+		if err := database.Connection.CreateWebhook(ctx, hook); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		eventType string
+		rollback  bool
+		want      map[string]int // new deliveries by webhook
+	}{
+		{name: "filtered in", eventType: "products_updated", want: map[string]int{"products": 1, "all": 1}},
+		{name: "filtered out", eventType: "samples_updated", want: map[string]int{"all": 1}},
+		{name: "rolled back", eventType: "products_updated", rollback: true, want: map[string]int{}},
+	}
+	hooks := map[string][]byte{"products": hookID, "all": allID, "inactive": inactiveID}
+	seen := map[string]int{}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errRollback := errors.New("rollback")
+			err := database.Transaction(ctx, func(q *database.Queries) error {
+				if err := Store(ctx, q, uint64(i+1), tt.eventType, nil); err != nil {
+					return err
+				}
+				if tt.rollback {
+					return errRollback
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, errRollback) {
+				t.Fatal(err)
+			}
+			// Stored deliveries are durable as soon as Store returns, before Run has seen them
+			for name, id := range hooks {
+				rows := deliveries(t, id)
+				if got := len(rows) - seen[name]; got != tt.want[name] {
+					t.Errorf("%s: got %d new deliveries, want %d", name, got, tt.want[name])
+				}
+				if len(rows) > seen[name] && (rows[0].Status != StatusPending || rows[0].EventID != int64(i+1)) {
+					t.Errorf("%s: got %+v, want a pending delivery of event %d", name, rows[0], i+1)
+				}
+				seen[name] = len(rows)
+			}
+		})
+	}
+}
@@ -2,14 +2,30 @@ package main
 
 import (
 	"context"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"reesource-tracker/api"
+	"reesource-tracker/api/openapi"
+	"reesource-tracker/api/sync"
+	"reesource-tracker/lib/auth"
+	"reesource-tracker/lib/config"
 	"reesource-tracker/lib/database"
-	"runtime"
+	"reesource-tracker/lib/jobs"
+	"reesource-tracker/lib/logging"
+	"reesource-tracker/lib/metrics"
+	"reesource-tracker/lib/mqtt"
+	"reesource-tracker/lib/notifications"
+	"reesource-tracker/lib/reports"
+	"reesource-tracker/lib/scheduler"
+	"reesource-tracker/lib/webhooks"
 	"strings"
+	"syscall"
 
 	_ "embed"
 
@@ -19,49 +35,166 @@ import (
 
 func main() {
 	godotenv.Load()
-	_, devmode := os.LookupEnv("DEV")
-	var r *gin.Engine
-	if devmode {
-		r = gin.Default() // includes Logger and Recovery
-	} else {
-		r = gin.New() // no Logger
-		r.Use(gin.Recovery())
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	if err := logging.Setup(); err != nil {
+		slog.Error("Invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
+	command := cfg.Command()
+	if command == "serve" {
+		serve(cfg)
+		return
 	}
-	if devmode {
-		println("Running frontend proxy")
+	run, ok := commands[command]
+	if !ok {
+		slog.Error("Unknown command", "command", command)
+		os.Exit(2)
+	}
+	os.Exit(run(cfg.Args[1:]))
+}
+
+// serve runs the HTTP server and background workers until it gets SIGINT or SIGTERM.
+func serve(cfg *config.Config) {
+	cfg.Log()
+	if !cfg.Dev {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	// Requests are logged by logging.Middleware instead of gin's Logger
+	r := gin.New()
+	r.Use(logging.Middleware(auth.LogUser), gin.Recovery(), metrics.Middleware())
+	if cfg.Dev {
+		slog.Info("Running frontend proxy")
 		r.Any("/app/*proxypath", proxy)
 	} else {
-		println("Serving frontend static files")
-		r.LoadHTMLGlob("./client/*.html")
+		slog.Info("Serving frontend static files")
+		r.LoadHTMLGlob(filepath.Join(cfg.ClientPath, "*.html"))
 		r.GET("/app/*path", func(c *gin.Context) {
 			path := c.Param("path")
 			// Check if the first segment is "assets"
 			segments := strings.SplitN(path, "/", 3)
 			if len(segments) > 1 && segments[1] == "assets" {
-				c.File("./client" + path)
+				c.File(filepath.Join(cfg.ClientPath, path))
 				return
 			}
 			c.HTML(http.StatusOK, "index.html", gin.H{})
 		})
 
 	}
-	database.Connect(context.Background())
+	if err := database.Connect(context.Background()); err != nil {
+		slog.Error("Failed to open the database", "error", err)
+		os.Exit(1)
+	}
+	if role := cfg.AnonymousRole; role != "" && role != "none" && !auth.IsRole(role) {
+		slog.Error("Unknown anonymous role", "anonymous_role", role)
+		os.Exit(1)
+	}
+	if auth.AnonymousRole() == auth.RoleAdmin {
+		slog.Warn("Requests without credentials have the admin role", "anonymous_role", auth.RoleAdmin)
+	}
+	if err := auth.SetupOIDC(context.Background()); err != nil {
+		slog.Warn("Single sign-on disabled", "error", err)
+	}
+	if err := mqtt.Setup(sync.PublishSampleStates); err != nil {
+		slog.Warn("MQTT disabled", "error", err)
+	}
+	if err := notifications.Setup(); err != nil {
+		slog.Warn("Email notifications disabled", "error", err)
+	}
+	jobs.Register()
+	// Workers are stopped after the HTTP server has drained, since requests still queue
+	// webhooks and notifications for them
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	workersDone := startWorkers(workerCtx, webhooks.Run, notifications.Run, scheduler.Run)
 	api.Routes(r)
+	// Requests to routes missing from the OpenAPI document aren't validated
+	for _, route := range openapi.Missing(r.Routes()) {
+		slog.Error("Route missing from the OpenAPI document", "route", route)
+	}
+	metrics.Registry.MustRegister(reports.Collector{})
+	if !metrics.Listen() {
+		r.GET("/metrics", auth.Middleware(), auth.Require(auth.PermMetricsRead), gin.WrapH(metrics.Handler()))
+	}
 	r.GET("/", func(c *gin.Context) {
 		c.Redirect(http.StatusPermanentRedirect, "/app")
 	})
 
-	if runtime.GOOS == "windows" && devmode {
-		r.Run("localhost:80")
-	} else {
-		r.Run("0.0.0.0:80")
+	server := &http.Server{Addr: cfg.Address(), Handler: r}
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Listening", "address", cfg.Address())
+		serverErr <- server.ListenAndServe()
+	}()
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	exitCode := 0
+	select {
+	case <-signals.Done():
+		slog.Info("Shutting down", "timeout", cfg.ShutdownTimeout.String())
+	case err := <-serverErr:
+		slog.Error("Server failed", "error", err)
+		exitCode = 1
+	}
+	// A second signal kills the server straight away
+	stopSignals()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	sync.Shutdown()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("Requests did not finish in time, closing their connections", "error", err)
+		server.Close()
 	}
+	stopWorkers()
+	select {
+	case <-workersDone:
+	case <-ctx.Done():
+		slog.Warn("Background jobs did not finish in time")
+	}
+	mqtt.Close()
+	if err := database.Close(context.Background()); err != nil {
+		slog.Error("Failed to close the database", "error", err)
+		exitCode = 1
+	}
+	slog.Info("Stopped")
+	os.Exit(exitCode)
+}
+
+// startWorkers runs background workers until ctx is cancelled. The returned channel is
+// closed once all of them have returned.
+func startWorkers(ctx context.Context, workers ...func(context.Context)) <-chan struct{} {
+	finished := make(chan struct{}, len(workers))
+	for _, run := range workers {
+		go func() {
+			run(ctx)
+			finished <- struct{}{}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		for range workers {
+			<-finished
+		}
+		close(done)
+	}()
+	return done
 }
 
 func proxy(c *gin.Context) {
-	remote, err := url.Parse("http://" + c.Request.Host + ":5173/")
+	target := config.Current.DevProxy
+	if target == "" {
+		host, _, err := net.SplitHostPort(c.Request.Host)
+		if err != nil {
+			host = c.Request.Host
+		}
+		target = "http://" + net.JoinHostPort(host, "5173") + "/"
+	}
+	remote, err := url.Parse(target)
 	if err != nil {
-		println("Could not resolve Proxy URL")
+		slog.Error("Could not resolve proxy URL", "error", err)
 	}
 	proxy := httputil.NewSingleHostReverseProxy(remote)
 	proxy.ServeHTTP(c.Writer, c.Request)